@@ -0,0 +1,408 @@
+// Package backup copies the SQLite database with the online backup API, so the
+// server keeps serving while a consistent copy is taken, and restores copies
+// only after checking them.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mattn/go-sqlite3"
+)
+
+// The supported compressions.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// extensions maps each compression to the suffix of its backup files.
+var extensions = map[string]string{
+	CompressionNone: ".db",
+	CompressionGzip: ".db.gz",
+	CompressionZstd: ".db.zst",
+}
+
+const (
+	backupPrefix = "swift_codes-"
+	// pagesPerStep is how much the backup copies before letting writers in.
+	pagesPerStep = 256
+)
+
+// ErrInvalidBackup is returned when a backup fails validation on restore.
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// TooSoonError is returned by CreateBackup when the newest backup is younger
+// than Manager.MinInterval.
+type TooSoonError struct {
+	Wait time.Duration
+}
+
+func (err *TooSoonError) Error() string {
+	return fmt.Sprintf("the last backup was taken too recently; try again in %s", err.Wait.Round(time.Second))
+}
+
+// RetryAfter is how long to wait before the next backup is allowed.
+func (err *TooSoonError) RetryAfter() time.Duration {
+	return err.Wait
+}
+
+// Environment variables the server reads to enable the backup endpoint.
+const (
+	DirEnv         = "SWIFT_BACKUP_DIR"
+	CompressionEnv = "SWIFT_BACKUP_COMPRESSION"
+	KeepEnv        = "SWIFT_BACKUP_KEEP"
+	MinIntervalEnv = "SWIFT_BACKUP_MIN_INTERVAL"
+)
+
+// CheckCompression returns an error unless name is a supported compression.
+func CheckCompression(name string) error {
+	if _, ok := extensions[name]; !ok {
+		return fmt.Errorf("unknown compression %q: use none, gzip or zstd", name)
+	}
+	return nil
+}
+
+// Manager writes backups of a SQLite database into Dir.
+type Manager struct {
+	DB  *db.DB
+	Dir string
+	// Compression is one of the Compression constants; CompressionNone when empty.
+	Compression string
+	// Keep is how many backups to retain, the newest first; all when zero.
+	Keep int
+	// MinInterval is how old the newest backup in Dir must be before another
+	// is taken, so a burst of requests cannot rotate every good backup out
+	// of Keep. There is no limit when zero.
+	MinInterval time.Duration
+
+	mu sync.Mutex
+}
+
+// CreateBackup takes a consistent copy of the database, compresses it and
+// prunes backups beyond Keep. One backup runs at a time. It returns a
+// *TooSoonError when the newest backup is younger than MinInterval.
+func (manager *Manager) CreateBackup(ctx context.Context) (models.Backup, error) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if manager.MinInterval > 0 {
+		existing, err := List(manager.Dir)
+		if err != nil {
+			return models.Backup{}, err
+		}
+		if len(existing) > 0 {
+			if age := time.Since(existing[0].CreatedAt); age < manager.MinInterval {
+				return models.Backup{}, &TooSoonError{Wait: manager.MinInterval - age}
+			}
+		}
+	}
+
+	compression := manager.Compression
+	if compression == "" {
+		compression = CompressionNone
+	}
+	if err := CheckCompression(compression); err != nil {
+		return models.Backup{}, err
+	}
+	extension := extensions[compression]
+	if err := os.MkdirAll(manager.Dir, 0o755); err != nil {
+		return models.Backup{}, err
+	}
+
+	createdAt := time.Now().UTC()
+	name := backupPrefix + createdAt.Format("20060102T150405.000000000Z") + extension
+	copyPath := filepath.Join(manager.Dir, name+".partial")
+	defer os.Remove(copyPath)
+	if err := onlineCopy(ctx, manager.DB, copyPath); err != nil {
+		return models.Backup{}, err
+	}
+
+	path := filepath.Join(manager.Dir, name)
+	if compression == CompressionNone {
+		if err := os.Rename(copyPath, path); err != nil {
+			return models.Backup{}, err
+		}
+	} else if err := compressFile(copyPath, path, compression); err != nil {
+		return models.Backup{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return models.Backup{}, err
+	}
+	created := models.Backup{Name: name, Compression: compression, Size: info.Size(), CreatedAt: createdAt}
+	return created, manager.prune()
+}
+
+// ListBackups returns the backups in Dir, newest first.
+func (manager *Manager) ListBackups() ([]models.Backup, error) {
+	return List(manager.Dir)
+}
+
+func (manager *Manager) prune() error {
+	if manager.Keep <= 0 {
+		return nil
+	}
+	backups, err := List(manager.Dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, old := range backups[min(manager.Keep, len(backups)):] {
+		errs = append(errs, os.Remove(filepath.Join(manager.Dir, old.Name)))
+	}
+	return errors.Join(errs...)
+}
+
+// List returns the backups in dir, newest first. A missing dir has none.
+func List(dir string) ([]models.Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []models.Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		compression, createdAt, ok := parseName(name)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, models.Backup{Name: name, Compression: compression, Size: info.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// parseName recognizes the names CreateBackup gives its files.
+func parseName(name string) (compression string, createdAt time.Time, ok bool) {
+	if !strings.HasPrefix(name, backupPrefix) {
+		return "", time.Time{}, false
+	}
+	for candidate, extension := range extensions {
+		stamp, found := strings.CutSuffix(strings.TrimPrefix(name, backupPrefix), extension)
+		if !found {
+			continue
+		}
+		createdAt, err := time.Parse("20060102T150405.000000000Z", stamp)
+		if err == nil {
+			return candidate, createdAt, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// CompressionOf reports the compression of a backup file from its name,
+// treating names it does not recognize as uncompressed.
+func CompressionOf(path string) string {
+	switch {
+	case strings.HasSuffix(path, extensions[CompressionGzip]):
+		return CompressionGzip
+	case strings.HasSuffix(path, extensions[CompressionZstd]):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// Restore checks the backup at source and copies it into target, which may
+// be in use: the backup API replaces its pages in one step, and other
+// connections see the restored data afterwards. Nothing is written to target
+// unless the backup passes SQLite's integrity check and was written by a build
+// with no migrations this one lacks; older backups are migrated first.
+//
+// The change log is restored too, so its sequence goes back to where it was
+// when the backup was taken. Servers keep their position in the log in
+// memory, for the read cache, the snapshot publisher and the webhook
+// dispatcher, and would skip changes numbered below it; every server using
+// target must be restarted after a restore.
+func Restore(ctx context.Context, source string, target *db.DB) error {
+	if target.Dialect != db.SQLite {
+		return fmt.Errorf("restores need SQLite, not %s; use the database's own tools", target.Dialect.Name)
+	}
+	candidate, err := os.CreateTemp(filepath.Dir(source), filepath.Base(source)+".restore-*")
+	if err != nil {
+		return err
+	}
+	candidatePath := candidate.Name()
+	candidate.Close()
+	defer os.Remove(candidatePath)
+	if err := decompressFile(source, candidatePath, CompressionOf(source)); err != nil {
+		return err
+	}
+
+	restored, err := validate(ctx, candidatePath)
+	if err != nil {
+		return err
+	}
+	defer restored.Close()
+	return copyDatabase(ctx, target.DB, restored.DB)
+}
+
+// validate opens a candidate and runs the checks Restore promises. It keeps
+// a rollback journal so no -wal file is left beside the temporary copy.
+func validate(ctx context.Context, path string) (*db.DB, error) {
+	candidate, err := db.Settings{JournalMode: "DELETE"}.Open(db.SQLite, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	unknown, err := db.UnknownMigrations(candidate)
+	if err == nil && len(unknown) > 0 {
+		err = fmt.Errorf("written by a newer version, with migrations %s", strings.Join(unknown, ", "))
+	}
+	var result string
+	if err == nil {
+		err = candidate.QueryRowContext(ctx, `PRAGMA integrity_check;`).Scan(&result)
+	}
+	if err == nil && result != "ok" {
+		err = fmt.Errorf("integrity check: %s", result)
+	}
+	if err != nil {
+		candidate.Close()
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return candidate, nil
+}
+
+// onlineCopy writes a consistent copy of database to a new file at path.
+func onlineCopy(ctx context.Context, database *db.DB, path string) error {
+	if database.Dialect != db.SQLite {
+		return fmt.Errorf("backups need SQLite, not %s; use the database's own tools", database.Dialect.Name)
+	}
+	destination, err := sql.Open(db.SQLite.DriverName, path)
+	if err != nil {
+		return err
+	}
+	defer destination.Close()
+	return copyDatabase(ctx, destination, database.DB)
+}
+
+// copyDatabase runs the SQLite backup API from source into destination a few
+// pages at a time, so writers are not held off for the whole copy. SQLite
+// restarts the copy if another connection writes to source in between.
+func copyDatabase(ctx context.Context, destination, source *sql.DB) error {
+	destinationConn, err := destination.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destinationConn.Close()
+	sourceConn, err := source.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sourceConn.Close()
+
+	return destinationConn.Raw(func(destinationDriverConn any) error {
+		return sourceConn.Raw(func(sourceDriverConn any) error {
+			destinationSQLite, ok := destinationDriverConn.(*sqlite3.SQLiteConn)
+			sourceSQLite, sourceOK := sourceDriverConn.(*sqlite3.SQLiteConn)
+			if !ok || !sourceOK {
+				return errors.New("backups need SQLite connections")
+			}
+			backup, err := destinationSQLite.Backup("main", sourceSQLite, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := backup.Step(pagesPerStep)
+				if err != nil {
+					backup.Close()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+				select {
+				case <-ctx.Done():
+					backup.Close()
+					return ctx.Err()
+				case <-time.After(time.Millisecond):
+				}
+			}
+		})
+	})
+}
+
+func compressFile(sourcePath, destinationPath, compression string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	var compressor io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		compressor = gzip.NewWriter(destination)
+	case CompressionZstd:
+		compressor, err = zstd.NewWriter(destination)
+	default:
+		err = fmt.Errorf("unknown compression %q", compression)
+	}
+	if err == nil {
+		_, err = io.Copy(compressor, source)
+		err = errors.Join(err, compressor.Close())
+	}
+	err = errors.Join(err, destination.Close())
+	if err != nil {
+		os.Remove(destinationPath)
+	}
+	return err
+}
+
+func decompressFile(sourcePath, destinationPath, compression string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	var reader io.Reader = source
+	switch compression {
+	case CompressionGzip:
+		gzipReader, err := gzip.NewReader(source)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case CompressionZstd:
+		zstdReader, err := zstd.NewReader(source)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	}
+	destination, err := os.Create(destinationPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destination, reader); err != nil {
+		destination.Close()
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	return destination.Close()
+}
@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/db/dbtest"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+func seededDatabase(t *testing.T) (*db.DB, *service.SwiftRepository) {
+	t.Helper()
+	database := dbtest.OpenSQLite(t)
+	repo := &service.SwiftRepository{DB: database}
+	createCode(t, repo, "ZZBANKZZXXX")
+	return database, repo
+}
+
+func createCode(t *testing.T, repo *service.SwiftRepository, code string) {
+	t.Helper()
+	err := repo.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: code, CodeType: "BIC11",
+		Name: "ZELAND NATIONAL BANK", CountryName: "ZELAND", IsHeadquarter: true})
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", code, err)
+	}
+}
+
+func TestBackupAndRestoreRoundTrip(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ctx := context.Background()
+			database, repo := seededDatabase(t)
+			manager := &Manager{DB: database, Dir: t.TempDir(), Compression: compression}
+			created, err := manager.CreateBackup(ctx)
+			if err != nil {
+				t.Fatalf("CreateBackup failed: %v", err)
+			}
+			if created.Compression != compression || CompressionOf(created.Name) != compression || created.Size == 0 {
+				t.Errorf("Unexpected backup %+v", created)
+			}
+
+			// change the live database, then restore into it while it is open
+			if err := repo.DeleteSwiftCode("ZZBANKZZXXX"); err != nil {
+				t.Fatal(err)
+			}
+			createCode(t, repo, "YYBANKYYXXX")
+			if err := Restore(ctx, filepath.Join(manager.Dir, created.Name), database); err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			if _, _, err := repo.GetSwiftCode("ZZBANKZZXXX"); err != nil {
+				t.Errorf("Expected the backed up code to be back, got %v", err)
+			}
+			if _, _, err := repo.GetSwiftCode("YYBANKYYXXX"); !errors.Is(err, service.ErrNotFound) {
+				t.Errorf("Expected the later code to be gone, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBackupsArePrunedToKeep(t *testing.T) {
+	database, _ := seededDatabase(t)
+	manager := &Manager{DB: database, Dir: t.TempDir(), Compression: CompressionGzip, Keep: 2}
+	var created []models.Backup
+	for i := 0; i < 3; i++ {
+		backup, err := manager.CreateBackup(context.Background())
+		if err != nil {
+			t.Fatalf("CreateBackup %d failed: %v", i+1, err)
+		}
+		created = append(created, backup)
+	}
+	os.WriteFile(filepath.Join(manager.Dir, "notes.txt"), []byte("not a backup"), 0o644)
+
+	backups, err := manager.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 2 || backups[0].Name != created[2].Name || backups[1].Name != created[1].Name {
+		t.Errorf("Expected the two newest backups, newest first, got %+v", backups)
+	}
+}
+
+func TestBackupsAreRateLimited(t *testing.T) {
+	database, _ := seededDatabase(t)
+	manager := &Manager{DB: database, Dir: t.TempDir(), Keep: 2, MinInterval: time.Hour}
+	first, err := manager.CreateBackup(context.Background())
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	_, err = manager.CreateBackup(context.Background())
+	var tooSoon *TooSoonError
+	if !errors.As(err, &tooSoon) || tooSoon.RetryAfter() <= 0 || tooSoon.RetryAfter() > time.Hour {
+		t.Fatalf("Expected a TooSoonError for the second backup, got %v", err)
+	}
+	if backups, _ := manager.ListBackups(); len(backups) != 1 || backups[0].Name != first.Name {
+		t.Errorf("Expected only the first backup to be kept, got %+v", backups)
+	}
+}
+
+func TestRestoreRejectsInvalidBackups(t *testing.T) {
+	ctx := context.Background()
+	database, repo := seededDatabase(t)
+	dir := t.TempDir()
+
+	garbage := filepath.Join(dir, "swift_codes-garbage.db.gz")
+	os.WriteFile(garbage, []byte("not gzip at all"), 0o644)
+	if err := Restore(ctx, garbage, database); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Expected ErrInvalidBackup for a corrupt archive, got %v", err)
+	}
+
+	notDatabase := filepath.Join(dir, "swift_codes-text.db")
+	os.WriteFile(notDatabase, []byte("this is not a database file, just some text padding it out"), 0o644)
+	if err := Restore(ctx, notDatabase, database); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Expected ErrInvalidBackup for a file that is not a database, got %v", err)
+	}
+
+	// a backup taken by a newer version records a migration this build lacks
+	manager := &Manager{DB: database, Dir: dir}
+	created, err := manager.CreateBackup(ctx)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	newer, err := sql.Open("sqlite3", filepath.Join(dir, created.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newer.Exec(`INSERT INTO schema_migrations (name) VALUES ('from the future');`)
+	newer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(ctx, filepath.Join(dir, created.Name), database); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("Expected ErrInvalidBackup for a newer schema, got %v", err)
+	}
+
+	if _, _, err := repo.GetSwiftCode("ZZBANKZZXXX"); err != nil {
+		t.Errorf("Expected the live database to be untouched, got %v", err)
+	}
+}
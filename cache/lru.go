@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// lruEntry is one cached value with its expiry time.
+type lruEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// lru is a size-bounded least-recently-used map with per-entry expiry. It is
+// not safe for concurrent use; Store guards it with a mutex.
+type lru struct {
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+func newLRU(capacity int) *lru {
+	return &lru{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the value for key when it is present and not expired at now.
+func (cache *lru) get(key string, now time.Time) (interface{}, bool) {
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if !now.Before(entry.expiresAt) {
+		cache.removeElement(element)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// add stores value under key and returns how many entries were evicted to
+// make room for it.
+func (cache *lru) add(key string, value interface{}, expiresAt time.Time) int {
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		cache.order.MoveToFront(element)
+		return 0
+	}
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+
+	evicted := 0
+	for cache.order.Len() > cache.capacity {
+		cache.removeElement(cache.order.Back())
+		evicted++
+	}
+	return evicted
+}
+
+// remove drops key and reports whether it was present.
+func (cache *lru) remove(key string) bool {
+	element, ok := cache.entries[key]
+	if ok {
+		cache.removeElement(element)
+	}
+	return ok
+}
+
+func (cache *lru) removeElement(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*lruEntry).key)
+}
+
+func (cache *lru) purge() {
+	cache.order.Init()
+	cache.entries = make(map[string]*list.Element)
+}
+
+func (cache *lru) len() int {
+	return cache.order.Len()
+}
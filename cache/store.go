@@ -0,0 +1,378 @@
+// Package cache puts an in-memory read cache in front of a
+// handler.SwiftDataStore.
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"swift-codes-project/changefeed"
+	handler "swift-codes-project/handlers"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Defaults used when the corresponding Store field is not set.
+const (
+	DefaultCapacity     = 10000
+	DefaultTTL          = 5 * time.Minute
+	DefaultWatchBackoff = time.Second
+)
+
+// maxWatchBackoff caps the wait between Watch retries.
+const maxWatchBackoff = time.Minute
+
+// Stats are the cache counters since the Store was created.
+type Stats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+	// WatchErrors counts failed reads of the change log by Watch.
+	WatchErrors uint64 `json:"watchErrors"`
+	// WatchError is the last of them while Watch has not yet recovered;
+	// until it has, writes from other processes are only picked up by TTL.
+	WatchError string `json:"watchError,omitempty"`
+}
+
+// swiftCodeResult is the cached result of GetSwiftCode. Not found results
+// are cached too, so repeated lookups of unknown codes stay cheap.
+type swiftCodeResult struct {
+	row          models.SwiftCode
+	branches     []models.SwiftCode
+	err          error
+	lastModified time.Time
+}
+
+type countryResult struct {
+	rows         []models.SwiftCode
+	lastModified time.Time
+}
+
+// Store caches GetSwiftCode and GetCountrySwiftCodes results of Next. Other
+// reads go straight to Next. Writes made through the Store invalidate the
+// affected entries before returning; writes made elsewhere, such as imports,
+// are picked up by Watch.
+type Store struct {
+	Next handler.SwiftDataStore
+	// Freshness, when set, is asked for Last-Modified as results are loaded,
+	// and the answer is kept with them; the Store then serves it as a
+	// handler.FreshnessStore without another query.
+	Freshness handler.FreshnessStore
+	// Capacity is the maximum number of cached results.
+	Capacity int
+	// TTL bounds how long a result is served without going back to Next.
+	TTL time.Duration
+	// WatchBackoff is how long Watch first waits after a failed read of the
+	// change log. The wait doubles on every further failure, up to a minute.
+	WatchBackoff time.Duration
+	Now          func() time.Time
+
+	initOnce sync.Once
+	mu       sync.Mutex
+	entries  *lru
+	// generation is bumped by every invalidation, so a load that started
+	// before it does not store its now stale result.
+	generation uint64
+	loads      singleflight.Group
+	watchError string
+
+	hits, misses, evictions, invalidations, watchErrors atomic.Uint64
+}
+
+var (
+	_ handler.SwiftDataStore = (*Store)(nil)
+	_ handler.FreshnessStore = (*Store)(nil)
+)
+
+func (store *Store) init() {
+	store.initOnce.Do(func() {
+		capacity := store.Capacity
+		if capacity <= 0 {
+			capacity = DefaultCapacity
+		}
+		store.entries = newLRU(capacity)
+	})
+}
+
+func (store *Store) now() time.Time {
+	if store.Now != nil {
+		return store.Now()
+	}
+	return time.Now()
+}
+
+func (store *Store) ttl() time.Duration {
+	if store.TTL > 0 {
+		return store.TTL
+	}
+	return DefaultTTL
+}
+
+func codeKey(code string) string    { return "code:" + code }
+func countryKey(iso2 string) string { return "country:" + iso2 }
+
+// cached returns the value under key, loading it through load on a miss.
+// Concurrent misses for the same key share a single load, unless an
+// invalidation came between them: a miss after it starts a load of its own
+// rather than waiting for a result that may predate it.
+func (store *Store) cached(key string, load func() (interface{}, error)) (interface{}, error) {
+	store.init()
+	store.mu.Lock()
+	value, ok := store.entries.get(key, store.now())
+	generation := store.generation
+	store.mu.Unlock()
+	if ok {
+		store.hits.Add(1)
+		return value, nil
+	}
+	store.misses.Add(1)
+
+	value, err, _ := store.loads.Do(key+"@"+strconv.FormatUint(generation, 10), func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		store.mu.Lock()
+		if store.generation == generation {
+			store.evictions.Add(uint64(store.entries.add(key, value, store.now().Add(store.ttl()))))
+		}
+		store.mu.Unlock()
+		return value, nil
+	})
+	return value, err
+}
+
+func (store *Store) GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error) {
+	result, err := store.swiftCode(requestedCode)
+	if err != nil {
+		return models.SwiftCode{}, nil, err
+	}
+	return result.row, append([]models.SwiftCode(nil), result.branches...), result.err
+}
+
+func (store *Store) GetCountrySwiftCodes(requestedISO2 string) ([]models.SwiftCode, error) {
+	result, err := store.country(requestedISO2)
+	return append([]models.SwiftCode(nil), result.rows...), err
+}
+
+// SwiftCodeLastModified returns the time loaded with the cached GetSwiftCode
+// result, loading both on a miss.
+func (store *Store) SwiftCodeLastModified(code string) (time.Time, error) {
+	result, err := store.swiftCode(code)
+	return result.lastModified, err
+}
+
+// CountryLastModified returns the time loaded with the cached
+// GetCountrySwiftCodes result, loading both on a miss.
+func (store *Store) CountryLastModified(requestedISO2 string) (time.Time, error) {
+	result, err := store.country(requestedISO2)
+	return result.lastModified, err
+}
+
+func (store *Store) swiftCode(requestedCode string) (swiftCodeResult, error) {
+	value, err := store.cached(codeKey(requestedCode), func() (interface{}, error) {
+		row, branches, err := store.Next.GetSwiftCode(requestedCode)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return nil, err
+		}
+		lastModified := store.lastModified(func(freshness handler.FreshnessStore) (time.Time, error) {
+			return freshness.SwiftCodeLastModified(requestedCode)
+		})
+		return swiftCodeResult{row: row, branches: branches, err: err, lastModified: lastModified}, nil
+	})
+	if err != nil {
+		return swiftCodeResult{}, err
+	}
+	return value.(swiftCodeResult), nil
+}
+
+func (store *Store) country(requestedISO2 string) (countryResult, error) {
+	value, err := store.cached(countryKey(requestedISO2), func() (interface{}, error) {
+		rows, err := store.Next.GetCountrySwiftCodes(requestedISO2)
+		if err != nil {
+			return nil, err
+		}
+		lastModified := store.lastModified(func(freshness handler.FreshnessStore) (time.Time, error) {
+			return freshness.CountryLastModified(requestedISO2)
+		})
+		return countryResult{rows: rows, lastModified: lastModified}, nil
+	})
+	if err != nil {
+		return countryResult{}, err
+	}
+	return value.(countryResult), nil
+}
+
+// lastModified asks Freshness, if any, via lookup. It runs after the rows
+// were read, so a change landing in between makes the time later than the
+// rows, never earlier. Failures only cost the Last-Modified header.
+func (store *Store) lastModified(lookup func(handler.FreshnessStore) (time.Time, error)) time.Time {
+	if store.Freshness == nil {
+		return time.Time{}
+	}
+	lastModified, err := lookup(store.Freshness)
+	if err != nil {
+		return time.Time{}
+	}
+	return lastModified
+}
+
+// GetSwiftCodesByCode goes straight to Next: one query for the whole batch
+// is cheaper than checking each code against the cache first.
+func (store *Store) GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error) {
+	return store.Next.GetSwiftCodesByCode(codes)
+}
+
+func (store *Store) SearchSwiftCodes(query, requestedISO2 string, limit int) ([]models.SwiftCode, error) {
+	return store.Next.SearchSwiftCodes(query, requestedISO2, limit)
+}
+
+func (store *Store) ExportSwiftCodes(requestedISO2 string, visit func(models.SwiftCode) error) error {
+	return store.Next.ExportSwiftCodes(requestedISO2, visit)
+}
+
+func (store *Store) CreateSwiftCode(newEntry models.SwiftCode) error {
+	err := store.Next.CreateSwiftCode(newEntry)
+	store.InvalidateCode(newEntry)
+	return err
+}
+
+func (store *Store) UpdateSwiftCode(changedEntry models.SwiftCode) error {
+	err := store.Next.UpdateSwiftCode(changedEntry)
+	store.InvalidateCode(changedEntry)
+	return err
+}
+
+// DeleteSwiftCode invalidates the code, its head office and its country. The
+// row is gone by then, so the country is taken from the code itself
+// (characters 5 and 6 of a BIC); Watch covers rows stored under another one.
+func (store *Store) DeleteSwiftCode(codeToDelete string) error {
+	err := store.Next.DeleteSwiftCode(codeToDelete)
+	deleted := models.SwiftCode{SwiftCode: codeToDelete}
+	if len(codeToDelete) >= 6 {
+		deleted.CountryISO2 = codeToDelete[4:6]
+	}
+	store.InvalidateCode(deleted)
+	return err
+}
+
+// InvalidateCode drops every cached result that may include sc: the code
+// itself, its head office's branch list and its country list.
+func (store *Store) InvalidateCode(sc models.SwiftCode) {
+	keys := []string{codeKey(sc.SwiftCode), countryKey(sc.CountryISO2)}
+	if sc.HqSwiftCode != "" {
+		keys = append(keys, codeKey(sc.HqSwiftCode))
+	} else if len(sc.SwiftCode) >= 8 && !strings.HasSuffix(sc.SwiftCode, "XXX") {
+		keys = append(keys, codeKey(sc.SwiftCode[:8]+"XXX"))
+	}
+
+	store.init()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.generation++
+	for _, key := range keys {
+		if store.entries.remove(key) {
+			store.invalidations.Add(1)
+		}
+	}
+}
+
+// Purge drops every cached result, for example after a bulk import.
+func (store *Store) Purge() {
+	store.init()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.generation++
+	store.invalidations.Add(uint64(store.entries.len()))
+	store.entries.purge()
+}
+
+// Stats returns the current counters.
+func (store *Store) Stats() Stats {
+	store.init()
+	store.mu.Lock()
+	entries := store.entries.len()
+	watchError := store.watchError
+	store.mu.Unlock()
+	return Stats{
+		Hits:          store.hits.Load(),
+		Misses:        store.misses.Load(),
+		Evictions:     store.evictions.Load(),
+		Invalidations: store.invalidations.Load(),
+		Entries:       entries,
+		WatchErrors:   store.watchErrors.Load(),
+		WatchError:    watchError,
+	}
+}
+
+// Watch invalidates the entries touched by every change logged after since,
+// until ctx is done. The change log is written by database triggers, so this
+// also covers imports and writes from other processes. When the log cannot
+// be read, Watch reports it in Stats and resumes after the last change it
+// saw, waiting longer after every failure in a row.
+func (store *Store) Watch(ctx context.Context, feed *changefeed.Feed, since int64) {
+	backoff := store.WatchBackoff
+	if backoff <= 0 {
+		backoff = DefaultWatchBackoff
+	}
+	delay := backoff
+	watched := &changefeed.Feed{
+		Store: readReporter{ChangeStore: feed.Store, succeeded: func() {
+			delay = backoff
+			store.setWatchError(nil)
+		}},
+		PollInterval: feed.PollInterval,
+	}
+	for ctx.Err() == nil {
+		err := watched.Follow(ctx, since, func(change models.SwiftCodeChange) error {
+			store.InvalidateCode(change.Code)
+			since = change.Sequence
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		store.watchErrors.Add(1)
+		store.setWatchError(err)
+		log.Printf("Cache invalidation failed, retrying in %s: %v", delay, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxWatchBackoff)
+	}
+}
+
+// readReporter calls succeeded after every successful read of the change log.
+type readReporter struct {
+	changefeed.ChangeStore
+	succeeded func()
+}
+
+func (reporter readReporter) ListChanges(since int64, limit int) ([]models.SwiftCodeChange, error) {
+	changes, err := reporter.ChangeStore.ListChanges(since, limit)
+	if err == nil {
+		reporter.succeeded()
+	}
+	return changes, err
+}
+
+func (store *Store) setWatchError(err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err == nil {
+		store.watchError = ""
+	} else {
+		store.watchError = err.Error()
+	}
+}
@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"swift-codes-project/changefeed"
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// countingStore answers every lookup with a fixed head office and counts the
+// calls that reach it. release, when set, blocks lookups until closed.
+type countingStore struct {
+	service.SwiftRepository
+	codeCalls      atomic.Int32
+	countryCalls   atomic.Int32
+	freshnessCalls atomic.Int32
+	release        chan struct{}
+}
+
+func (stub *countingStore) GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error) {
+	stub.codeCalls.Add(1)
+	if stub.release != nil {
+		<-stub.release
+	}
+	if requestedCode == "ZZMISSINXXX" {
+		return models.SwiftCode{}, nil, service.ErrNotFound
+	}
+	return models.SwiftCode{SwiftCode: requestedCode, CountryISO2: "ZZ", IsHeadquarter: true}, nil, nil
+}
+
+func (stub *countingStore) GetCountrySwiftCodes(requestedISO2 string) ([]models.SwiftCode, error) {
+	stub.countryCalls.Add(1)
+	return []models.SwiftCode{{SwiftCode: "ZZBANKZZXXX", CountryISO2: requestedISO2}}, nil
+}
+
+func (stub *countingStore) SwiftCodeLastModified(code string) (time.Time, error) {
+	stub.freshnessCalls.Add(1)
+	return time.Unix(1700000000, 0), nil
+}
+
+func (stub *countingStore) CountryLastModified(requestedISO2 string) (time.Time, error) {
+	stub.freshnessCalls.Add(1)
+	return time.Unix(1700000000, 0), nil
+}
+
+func (stub *countingStore) CreateSwiftCode(newEntry models.SwiftCode) error { return nil }
+
+func (stub *countingStore) DeleteSwiftCode(codeToDelete string) error { return nil }
+
+// TestStoreCachesLookups expects repeated lookups, including not found ones,
+// to be served from the cache.
+func TestStoreCachesLookups(t *testing.T) {
+	next := &countingStore{}
+	store := &Store{Next: next}
+
+	for i := 0; i < 3; i++ {
+		store.GetSwiftCode("ZZBANKZZXXX")
+		store.GetCountrySwiftCodes("ZZ")
+		if _, _, err := store.GetSwiftCode("ZZMISSINXXX"); err != service.ErrNotFound {
+			t.Fatalf("Expected ErrNotFound from the cache, got: %v", err)
+		}
+	}
+	if next.codeCalls.Load() != 2 || next.countryCalls.Load() != 1 {
+		t.Errorf("Expected 2 code and 1 country lookup, got %d and %d", next.codeCalls.Load(), next.countryCalls.Load())
+	}
+	if stats := store.Stats(); stats.Hits != 6 || stats.Misses != 3 || stats.Entries != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestStoreKeepsLastModifiedWithResults expects Last-Modified to be read
+// once per load and then answered from the cached entry.
+func TestStoreKeepsLastModifiedWithResults(t *testing.T) {
+	next := &countingStore{}
+	store := &Store{Next: next, Freshness: next}
+
+	for i := 0; i < 3; i++ {
+		store.GetSwiftCode("ZZBANKZZXXX")
+		if lastModified, _ := store.SwiftCodeLastModified("ZZBANKZZXXX"); !lastModified.Equal(time.Unix(1700000000, 0)) {
+			t.Fatalf("Expected the loaded time, got %v", lastModified)
+		}
+		store.GetCountrySwiftCodes("ZZ")
+		store.CountryLastModified("ZZ")
+	}
+	if next.freshnessCalls.Load() != 2 {
+		t.Errorf("Expected one freshness query per cached result, got %d", next.freshnessCalls.Load())
+	}
+
+	store.CreateSwiftCode(models.SwiftCode{SwiftCode: "ZZBANKZZ002", CountryISO2: "ZZ"})
+	store.SwiftCodeLastModified("ZZBANKZZXXX")
+	if next.freshnessCalls.Load() != 3 || next.codeCalls.Load() != 2 {
+		t.Errorf("Expected the invalidated entry to be reloaded with its time, got %d freshness and %d code lookups",
+			next.freshnessCalls.Load(), next.codeCalls.Load())
+	}
+}
+
+// TestStoreExpiresAndEvicts checks TTL expiry and the LRU bound.
+func TestStoreExpiresAndEvicts(t *testing.T) {
+	now := time.Unix(0, 0)
+	next := &countingStore{}
+	store := &Store{Next: next, Capacity: 2, TTL: time.Minute, Now: func() time.Time { return now }}
+
+	store.GetSwiftCode("AAAAAAAAXXX")
+	now = now.Add(2 * time.Minute)
+	store.GetSwiftCode("AAAAAAAAXXX")
+	if next.codeCalls.Load() != 2 {
+		t.Errorf("Expected the expired entry to be reloaded, got %d lookups", next.codeCalls.Load())
+	}
+
+	store.GetSwiftCode("BBBBBBBBXXX")
+	store.GetSwiftCode("AAAAAAAAXXX") // AAAAAAAA is now the most recently used
+	store.GetSwiftCode("CCCCCCCCXXX") // evicts BBBBBBBB
+	next.codeCalls.Store(0)
+	store.GetSwiftCode("AAAAAAAAXXX")
+	store.GetSwiftCode("BBBBBBBBXXX")
+	if next.codeCalls.Load() != 1 {
+		t.Errorf("Expected only the least recently used entry to be evicted, got %d lookups", next.codeCalls.Load())
+	}
+	if stats := store.Stats(); stats.Evictions < 1 || stats.Entries != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestStoreSharesConcurrentMisses expects concurrent misses for one code to
+// reach the underlying store once.
+func TestStoreSharesConcurrentMisses(t *testing.T) {
+	next := &countingStore{release: make(chan struct{})}
+	store := &Store{Next: next}
+
+	var lookups sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		lookups.Add(1)
+		go func() {
+			defer lookups.Done()
+			store.GetSwiftCode("ZZBANKZZXXX")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(next.release)
+	lookups.Wait()
+
+	if next.codeCalls.Load() != 1 {
+		t.Errorf("Expected one shared lookup, got %d", next.codeCalls.Load())
+	}
+}
+
+// TestStoreInvalidatesOnWrite expects a new branch to evict its head office
+// and country, and a delete to evict the deleted code.
+func TestStoreInvalidatesOnWrite(t *testing.T) {
+	next := &countingStore{}
+	store := &Store{Next: next}
+
+	store.GetSwiftCode("ZZBANKZZXXX")
+	store.GetSwiftCode("ZZBANKZZ001")
+	store.GetCountrySwiftCodes("ZZ")
+	store.CreateSwiftCode(models.SwiftCode{SwiftCode: "ZZBANKZZ002", CountryISO2: "ZZ"})
+	if stats := store.Stats(); stats.Invalidations != 2 || stats.Entries != 1 {
+		t.Fatalf("Expected the head office and country to be invalidated, got %+v", stats)
+	}
+
+	store.DeleteSwiftCode("ZZBANKZZ001")
+	if stats := store.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected the deleted code to be invalidated, got %+v", stats)
+	}
+}
+
+// TestStoreWatchInvalidatesExternalWrites changes a row behind the cache's
+// back, as an import would, and waits for the change log to evict it.
+func TestStoreWatchInvalidatesExternalWrites(t *testing.T) {
+	testDatabase, initError := db.InitDB("file:" + t.Name() + "?mode=memory&cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+	repository := &service.SwiftRepository{DB: testDatabase}
+	repository.CreateSwiftCode(models.SwiftCode{
+		SwiftCode: "ZZBANKZZXXX", CountryISO2: "ZZ", Name: "OLD NAME", CountryName: "ZELAND", IsHeadquarter: true,
+	})
+
+	store := &Store{Next: repository}
+	latestChange, _ := repository.LatestChangeSequence()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, &changefeed.Feed{Store: repository, PollInterval: 5 * time.Millisecond}, latestChange)
+
+	store.GetSwiftCode("ZZBANKZZXXX")
+	testDatabase.Exec(`UPDATE swift_codes SET name = 'NEW NAME' WHERE swift_code = 'ZZBANKZZXXX';`)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if row, _, _ := store.GetSwiftCode("ZZBANKZZXXX"); row.Name == "NEW NAME" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected the cached row to be invalidated by the change log")
+}
+
+// TestStoreLoadsAgainAfterInvalidation expects a miss that arrives after an
+// invalidation not to share a load that started before it.
+func TestStoreLoadsAgainAfterInvalidation(t *testing.T) {
+	next := &countingStore{release: make(chan struct{})}
+	store := &Store{Next: next}
+
+	var lookups sync.WaitGroup
+	lookups.Add(2)
+	go func() {
+		defer lookups.Done()
+		store.GetSwiftCode("ZZBANKZZXXX")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	store.InvalidateCode(models.SwiftCode{SwiftCode: "ZZBANKZZXXX"})
+	go func() {
+		defer lookups.Done()
+		store.GetSwiftCode("ZZBANKZZXXX")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(next.release)
+	lookups.Wait()
+
+	if next.codeCalls.Load() != 2 {
+		t.Errorf("Expected a second load after the invalidation, got %d loads", next.codeCalls.Load())
+	}
+}
+
+// flakyChanges fails the first failures reads of the change log, then
+// returns change once.
+type flakyChanges struct {
+	mu       sync.Mutex
+	failures int
+	change   models.SwiftCodeChange
+	served   bool
+}
+
+func (stub *flakyChanges) ListChanges(since int64, limit int) ([]models.SwiftCodeChange, error) {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.failures > 0 {
+		stub.failures--
+		return nil, errors.New("database is locked")
+	}
+	if stub.served || since >= stub.change.Sequence {
+		return nil, nil
+	}
+	stub.served = true
+	return []models.SwiftCodeChange{stub.change}, nil
+}
+
+// TestStoreWatchSurvivesFeedErrors expects Watch to report failed reads of
+// the change log in Stats and carry on invalidating once they succeed.
+func TestStoreWatchSurvivesFeedErrors(t *testing.T) {
+	next := &countingStore{}
+	store := &Store{Next: next, WatchBackoff: 30 * time.Millisecond}
+	store.GetSwiftCode("ZZBANKZZXXX")
+
+	changes := &flakyChanges{failures: 2, change: models.SwiftCodeChange{
+		Sequence: 8, Code: models.SwiftCode{SwiftCode: "ZZBANKZZXXX", CountryISO2: "ZZ"},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, &changefeed.Feed{Store: changes, PollInterval: 5 * time.Millisecond}, 7)
+
+	time.Sleep(10 * time.Millisecond)
+	if stats := store.Stats(); stats.WatchErrors == 0 || stats.WatchError == "" {
+		t.Errorf("Expected the failed read in Stats, got %+v", stats)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if stats := store.Stats(); stats.Entries == 0 && stats.WatchError == "" {
+			if stats.WatchErrors != 2 {
+				t.Errorf("Expected 2 failed reads, got %+v", stats)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected Watch to recover and invalidate, got %+v", store.Stats())
+}
@@ -0,0 +1,105 @@
+package changefeed
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"swift-codes-project/models"
+)
+
+// DefaultPollInterval is how often the change log is checked for new entries
+// when Feed.PollInterval is not set.
+const DefaultPollInterval = 250 * time.Millisecond
+
+// ChangeStore reads the change log.
+type ChangeStore interface {
+	ListChanges(since int64, limit int) ([]models.SwiftCodeChange, error)
+}
+
+// Feed waits for change log entries. The log is filled by database triggers,
+// so writes from other processes (such as swiftctl imports) are seen too;
+// that is why the feed polls rather than relying on in-process notifications.
+type Feed struct {
+	Store        ChangeStore
+	PollInterval time.Duration
+}
+
+func (feed *Feed) pollInterval() time.Duration {
+	if feed.PollInterval > 0 {
+		return feed.PollInterval
+	}
+	return DefaultPollInterval
+}
+
+// Wait returns up to limit changes after since. If there are none yet it keeps
+// polling until some arrive or ctx is done, in which case it returns an empty
+// slice and no error.
+func (feed *Feed) Wait(ctx context.Context, since int64, limit int) ([]models.SwiftCodeChange, error) {
+	ticker := time.NewTicker(feed.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		changes, err := feed.Store.ListChanges(since, limit)
+		if err != nil || len(changes) > 0 {
+			return changes, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Follow passes every change after since to handle, in order, until ctx is
+// done or handle returns an error. It returns nil when ctx ends.
+func (feed *Feed) Follow(ctx context.Context, since int64, handle func(models.SwiftCodeChange) error) error {
+	const batchSize = 100
+	for ctx.Err() == nil {
+		changes, err := feed.Wait(ctx, since, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if err := handle(change); err != nil {
+				return err
+			}
+			since = change.Sequence
+		}
+	}
+	return nil
+}
+
+// RetentionEnv names the environment variable setting how long change log
+// entries are kept, as a Go duration such as 720h. 0 keeps them forever.
+const RetentionEnv = "SWIFT_CHANGE_RETENTION"
+
+// DefaultRetention is how long change log entries are kept when RetentionEnv
+// is not set.
+const DefaultRetention = 90 * 24 * time.Hour
+
+// Pruner deletes old change log entries.
+type Pruner interface {
+	PruneChanges(cutoff time.Time) (int64, error)
+}
+
+// Prune deletes entries older than retention from store now and then every
+// interval, until ctx is done. Readers whose cursor falls behind the oldest
+// kept entry resume from there.
+func Prune(ctx context.Context, store Pruner, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if removed, err := store.PruneChanges(time.Now().Add(-retention)); err != nil {
+			log.Printf("Failed to prune change log: %v", err)
+		} else if removed > 0 {
+			log.Printf("Pruned %d change log entries older than %s", removed, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
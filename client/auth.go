@@ -0,0 +1,36 @@
+package client
+
+import "net/http"
+
+// Authenticator adds credentials to every outgoing request, including retries.
+type Authenticator interface {
+	Authenticate(request *http.Request)
+}
+
+// BearerToken sends an "Authorization: Bearer" header.
+type BearerToken string
+
+func (token BearerToken) Authenticate(request *http.Request) {
+	request.Header.Set("Authorization", "Bearer "+string(token))
+}
+
+// APIKey sends Key in Header, or in X-API-Key when Header is empty.
+type APIKey struct {
+	Header string
+	Key    string
+}
+
+func (apiKey APIKey) Authenticate(request *http.Request) {
+	header := apiKey.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	request.Header.Set(header, apiKey.Key)
+}
+
+// AuthenticatorFunc adapts a function to Authenticator.
+type AuthenticatorFunc func(request *http.Request)
+
+func (authenticate AuthenticatorFunc) Authenticate(request *http.Request) {
+	authenticate(request)
+}
@@ -0,0 +1,282 @@
+// Package client is a typed Go client for the SWIFT Codes REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SwiftCode is a head office or branch as returned by the API. Branches is
+// only filled in by Get for head offices.
+type SwiftCode struct {
+	Address       string      `json:"address"`
+	BankName      string      `json:"bankName"`
+	CountryISO2   string      `json:"countryISO2"`
+	CountryName   string      `json:"countryName"`
+	IsHeadquarter bool        `json:"isHeadquarter"`
+	SwiftCode     string      `json:"swiftCode"`
+	Branches      []SwiftCode `json:"branches,omitempty"`
+}
+
+// Country is the result of ListByCountry.
+type Country struct {
+	CountryISO2 string      `json:"countryISO2"`
+	CountryName string      `json:"countryName"`
+	SwiftCodes  []SwiftCode `json:"swiftCodes"`
+}
+
+// NewSwiftCode is the body of Create.
+type NewSwiftCode struct {
+	Address       string `json:"address"`
+	BankName      string `json:"bankName"`
+	CountryISO2   string `json:"countryISO2"`
+	CountryName   string `json:"countryName"`
+	IsHeadquarter bool   `json:"isHeadquarter"`
+	SwiftCode     string `json:"swiftCode"`
+	CodeType      string `json:"codeType,omitempty"`
+	TownName      string `json:"townName,omitempty"`
+	TimeZone      string `json:"timeZone,omitempty"`
+}
+
+// SearchOptions narrows Search. Zero values use the server defaults.
+type SearchOptions struct {
+	CountryISO2 string
+	Limit       int
+}
+
+// BatchGetResult lists the codes found, in request order, and those that do
+// not exist.
+type BatchGetResult struct {
+	SwiftCodes []SwiftCode `json:"swiftCodes"`
+	Missing    []string    `json:"missing"`
+}
+
+// Client calls the API at BaseURL. The zero value of every other field is a
+// usable default. Requests that fail with a network error, 429 or 5xx are
+// retried with exponential backoff. Create and Delete are only retried on
+// 429, which the server sends before doing anything: after a server or
+// network error the first attempt may have gone through, and repeating it
+// would report a conflict, or a missing code, for a write that succeeded.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Auth       Authenticator
+	// MaxRetries is the number of retries after the first attempt; negative
+	// disables retries.
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// New returns a client for the API at baseURL, such as "http://localhost:8080".
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+func (client *Client) httpClient() *http.Client {
+	if client.HTTPClient != nil {
+		return client.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (client *Client) maxRetries() int {
+	if client.MaxRetries == 0 {
+		return 3
+	}
+	return max(client.MaxRetries, 0)
+}
+
+// backoff returns the delay before retry number attempt (1-based).
+func (client *Client) backoff(attempt int) time.Duration {
+	base, limit := client.BaseBackoff, client.MaxBackoff
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if limit <= 0 {
+		limit = 5 * time.Second
+	}
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// retryAfter reads a Retry-After header given in seconds.
+func retryAfter(response *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// do sends the request, retrying as described on Client, and decodes a 2xx
+// JSON body into result when it is not nil.
+func (client *Client) do(ctx context.Context, method, path string, query url.Values, body, result interface{}, retryServerErrors bool) error {
+	endpoint := strings.TrimSuffix(client.BaseURL, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var encodedBody []byte
+	if body != nil {
+		var err error
+		if encodedBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(encodedBody))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Accept", "application/json")
+		if body != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		if client.Auth != nil {
+			client.Auth.Authenticate(request)
+		}
+
+		delay := client.backoff(attempt + 1)
+		response, err := client.httpClient().Do(request)
+		if err == nil {
+			responseBody, readErr := io.ReadAll(response.Body)
+			response.Body.Close()
+			switch {
+			case readErr != nil:
+				err = readErr
+			case response.StatusCode >= 200 && response.StatusCode < 300:
+				if result == nil {
+					return nil
+				}
+				return json.Unmarshal(responseBody, result)
+			default:
+				apiError := newAPIError(response.StatusCode, responseBody)
+				retryable := response.StatusCode == http.StatusTooManyRequests ||
+					(retryServerErrors && apiError.Temporary())
+				if !retryable || attempt >= client.maxRetries() {
+					return apiError
+				}
+				if serverDelay, ok := retryAfter(response); ok {
+					delay = serverDelay
+				}
+			}
+		}
+		if err != nil && (ctx.Err() != nil || attempt >= client.maxRetries() || !retryServerErrors) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Get returns one code; head offices include their branches.
+func (client *Client) Get(ctx context.Context, swiftCode string) (SwiftCode, error) {
+	var result SwiftCode
+	err := client.do(ctx, http.MethodGet, "/v1/swift-codes/"+url.PathEscape(swiftCode), nil, nil, &result, true)
+	return result, err
+}
+
+// ListByCountry returns every code of an ISO-2 country.
+func (client *Client) ListByCountry(ctx context.Context, countryISO2 string) (Country, error) {
+	var result Country
+	err := client.do(ctx, http.MethodGet, "/v1/swift-codes/country/"+url.PathEscape(countryISO2), nil, nil, &result, true)
+	return result, err
+}
+
+// Search matches swift code prefixes and bank or town name substrings.
+func (client *Client) Search(ctx context.Context, query string, options SearchOptions) ([]SwiftCode, error) {
+	values := url.Values{"q": {query}}
+	if options.CountryISO2 != "" {
+		values.Set("country", options.CountryISO2)
+	}
+	if options.Limit > 0 {
+		values.Set("limit", strconv.Itoa(options.Limit))
+	}
+	var result struct {
+		SwiftCodes []SwiftCode `json:"swiftCodes"`
+	}
+	err := client.do(ctx, http.MethodGet, "/v1/swift-codes/search", values, nil, &result, true)
+	return result.SwiftCodes, err
+}
+
+// Create adds a new code. It fails with ErrAlreadyExists when the code is taken.
+func (client *Client) Create(ctx context.Context, newCode NewSwiftCode) error {
+	return client.do(ctx, http.MethodPost, "/v1/swift-codes", nil, newCode, nil, false)
+}
+
+// Delete removes a code. It fails with ErrNotFound when there is no such code.
+func (client *Client) Delete(ctx context.Context, swiftCode string) error {
+	return client.do(ctx, http.MethodDelete, "/v1/swift-codes/"+url.PathEscape(swiftCode), nil, nil, nil, false)
+}
+
+// BatchGet looks up several codes in one request.
+func (client *Client) BatchGet(ctx context.Context, swiftCodes []string) (BatchGetResult, error) {
+	var result BatchGetResult
+	body := map[string][]string{"swiftCodes": swiftCodes}
+	err := client.do(ctx, http.MethodPost, "/v1/swift-codes/batch-get", nil, body, &result, true)
+	return result, err
+}
+
+// BatchError collects the per-code failures of BatchCreate and BatchDelete.
+type BatchError struct {
+	Errors map[string]error
+}
+
+func (batchError *BatchError) Error() string {
+	return strconv.Itoa(len(batchError.Errors)) + " of the batch operations failed"
+}
+
+// BatchCreate creates every code, continuing past failures. It returns a
+// *BatchError keyed by swift code when any of them failed, or the context's
+// error when it is cancelled.
+func (client *Client) BatchCreate(ctx context.Context, newCodes []NewSwiftCode) error {
+	failures := map[string]error{}
+	for _, newCode := range newCodes {
+		if err := client.Create(ctx, newCode); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures[newCode.SwiftCode] = err
+		}
+	}
+	return batchResult(failures)
+}
+
+// BatchDelete deletes every code, continuing past failures, and reports them
+// like BatchCreate.
+func (client *Client) BatchDelete(ctx context.Context, swiftCodes []string) error {
+	failures := map[string]error{}
+	for _, swiftCode := range swiftCodes {
+		if err := client.Delete(ctx, swiftCode); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures[swiftCode] = err
+		}
+	}
+	return batchResult(failures)
+}
+
+func batchResult(failures map[string]error) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return &BatchError{Errors: failures}
+}
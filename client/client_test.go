@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"swift-codes-project/db"
+	handler "swift-codes-project/handlers"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// newTestServer runs the real router on a fresh in-memory database holding
+// one head office with a branch. wrap, when set, sits in front of the router.
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	testDatabase, initError := db.InitDB("file:" + t.Name() + "?mode=memory&cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	t.Cleanup(func() { testDatabase.Close() })
+
+	repository := &service.SwiftRepository{DB: testDatabase}
+	for _, entry := range []models.SwiftCode{
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", Name: "ZELAND BANK", TownName: "CAPITAL", CountryName: "ZELAND", IsHeadquarter: true},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ001", Name: "ZELAND BANK", TownName: "PORT", CountryName: "ZELAND", HqSwiftCode: "ZZBANKZZXXX"},
+	} {
+		if insertError := repository.CreateSwiftCode(entry); insertError != nil {
+			t.Fatalf("Failed to insert %s: %v", entry.SwiftCode, insertError)
+		}
+	}
+
+	var router http.Handler = handler.NewRouter(&handler.SwiftHTTPHandler{DataStore: repository})
+	if wrap != nil {
+		router = wrap(router)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestClientAgainstRouter exercises every call against the real handlers.
+func TestClientAgainstRouter(t *testing.T) {
+	server := newTestServer(t, nil)
+	apiClient := New(server.URL)
+	ctx := context.Background()
+
+	headOffice, getError := apiClient.Get(ctx, "zzbankzzxxx")
+	if getError != nil {
+		t.Fatalf("Expected no error getting head office, got: %v", getError)
+	}
+	if !headOffice.IsHeadquarter || len(headOffice.Branches) != 1 || headOffice.Branches[0].SwiftCode != "ZZBANKZZ001" {
+		t.Errorf("Unexpected head office: %+v", headOffice)
+	}
+
+	country, listError := apiClient.ListByCountry(ctx, "ZZ")
+	if listError != nil || country.CountryName != "ZELAND" || len(country.SwiftCodes) != 2 {
+		t.Errorf("Unexpected country result %+v, error %v", country, listError)
+	}
+
+	matches, searchError := apiClient.Search(ctx, "port", SearchOptions{CountryISO2: "ZZ", Limit: 5})
+	if searchError != nil || len(matches) != 1 || matches[0].SwiftCode != "ZZBANKZZ001" {
+		t.Errorf("Unexpected search result %+v, error %v", matches, searchError)
+	}
+
+	newCode := NewSwiftCode{
+		Address: "1 NEW ROAD", BankName: "NEW BANK", CountryISO2: "ZZ",
+		CountryName: "ZELAND", IsHeadquarter: true, SwiftCode: "ZZNEWBNKXXX",
+	}
+	if createError := apiClient.Create(ctx, newCode); createError != nil {
+		t.Fatalf("Expected no error creating code, got: %v", createError)
+	}
+	if createError := apiClient.Create(ctx, newCode); !errors.Is(createError, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists creating the code twice, got: %v", createError)
+	}
+	created, _ := apiClient.Get(ctx, "ZZNEWBNKXXX")
+	if created.BankName != "NEW BANK" {
+		t.Errorf("Expected created bank name 'NEW BANK', got '%s'", created.BankName)
+	}
+
+	batch, batchError := apiClient.BatchGet(ctx, []string{"ZZNEWBNKXXX", "ZZMISSINGXX"})
+	if batchError != nil || len(batch.SwiftCodes) != 1 || len(batch.Missing) != 1 || batch.Missing[0] != "ZZMISSINGXX" {
+		t.Errorf("Unexpected batch result %+v, error %v", batch, batchError)
+	}
+
+	if deleteError := apiClient.Delete(ctx, "ZZNEWBNKXXX"); deleteError != nil {
+		t.Fatalf("Expected no error deleting code, got: %v", deleteError)
+	}
+	_, getError = apiClient.Get(ctx, "ZZNEWBNKXXX")
+	var apiError *APIError
+	if !errors.Is(getError, ErrNotFound) || !errors.As(getError, &apiError) || apiError.Message != "not found" {
+		t.Errorf("Expected a not found APIError after delete, got: %v", getError)
+	}
+
+	batchDeleteError := apiClient.BatchDelete(ctx, []string{"ZZBANKZZ001", "ZZMISSINGXX"})
+	var batchFailures *BatchError
+	if !errors.As(batchDeleteError, &batchFailures) || len(batchFailures.Errors) != 1 ||
+		!errors.Is(batchFailures.Errors["ZZMISSINGXX"], ErrNotFound) {
+		t.Errorf("Expected only ZZMISSINGXX to fail, got: %v", batchDeleteError)
+	}
+}
+
+// TestClientRetriesAndAuthenticates fails the first two attempts of every
+// request and expects the client to retry with its credentials each time.
+func TestClientRetriesAndAuthenticates(t *testing.T) {
+	var attempts, unauthenticated atomic.Int32
+	server := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+			if incomingRequest.Header.Get("Authorization") != "Bearer secret-token" {
+				unauthenticated.Add(1)
+			}
+			switch attempts.Add(1) {
+			case 1:
+				responseWriter.Header().Set("Retry-After", "0")
+				http.Error(responseWriter, `{"error":"slow down"}`, http.StatusTooManyRequests)
+			case 2:
+				http.Error(responseWriter, `{"error":"db failure"}`, http.StatusServiceUnavailable)
+			default:
+				next.ServeHTTP(responseWriter, incomingRequest)
+			}
+		})
+	})
+	apiClient := &Client{BaseURL: server.URL, Auth: BearerToken("secret-token"), BaseBackoff: time.Millisecond}
+
+	headOffice, getError := apiClient.Get(context.Background(), "ZZBANKZZXXX")
+	if getError != nil {
+		t.Fatalf("Expected the third attempt to succeed, got: %v", getError)
+	}
+	if headOffice.SwiftCode != "ZZBANKZZXXX" || attempts.Load() != 3 {
+		t.Errorf("Expected ZZBANKZZXXX after 3 attempts, got %q after %d", headOffice.SwiftCode, attempts.Load())
+	}
+	if unauthenticated.Load() != 0 {
+		t.Errorf("Expected every attempt to carry the bearer token, %d did not", unauthenticated.Load())
+	}
+}
+
+// TestClientGivesUpAfterMaxRetries expects the last server error to be
+// returned once retries run out, and Create and Delete not to be retried on
+// a 5xx.
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		http.Error(responseWriter, `{"error":"db failure"}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	apiClient := &Client{BaseURL: server.URL, MaxRetries: 2, BaseBackoff: time.Millisecond}
+
+	_, getError := apiClient.Get(context.Background(), "ZZBANKZZXXX")
+	var apiError *APIError
+	if !errors.As(getError, &apiError) || apiError.StatusCode != http.StatusInternalServerError || !apiError.Temporary() {
+		t.Fatalf("Expected a 500 APIError, got: %v", getError)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts.Load())
+	}
+
+	attempts.Store(0)
+	apiClient.Create(context.Background(), NewSwiftCode{SwiftCode: "ZZBANKZZXXX"})
+	if attempts.Load() != 1 {
+		t.Errorf("Expected Create to be attempted once, got %d", attempts.Load())
+	}
+
+	attempts.Store(0)
+	apiClient.Delete(context.Background(), "ZZBANKZZXXX")
+	if attempts.Load() != 1 {
+		t.Errorf("Expected Delete to be attempted once, got %d", attempts.Load())
+	}
+}
+
+// TestClientStopsRetryingWhenContextEnds expects a cancelled context to end
+// the backoff wait.
+func TestClientStopsRetryingWhenContextEnds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, _ *http.Request) {
+		http.Error(responseWriter, `{"error":"db failure"}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	apiClient := &Client{BaseURL: server.URL, MaxRetries: 10, BaseBackoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, getError := apiClient.Get(ctx, "ZZBANKZZXXX")
+	if !errors.Is(getError, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", getError)
+	}
+}
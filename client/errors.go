@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Errors matched by APIError.Is, so callers can write
+// errors.Is(err, client.ErrNotFound) without inspecting status codes.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrBadRequest    = errors.New("bad request")
+)
+
+// APIError is returned for every non-2xx response. Message is the error field
+// of the server's {"error": "..."} envelope, or the status text when the body
+// is not an envelope.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (apiError *APIError) Error() string {
+	return "swift codes api: " + strconv.Itoa(apiError.StatusCode) + " " + apiError.Message
+}
+
+// Is maps status codes onto the package's sentinel errors.
+func (apiError *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return apiError.StatusCode == http.StatusNotFound
+	case ErrAlreadyExists:
+		return apiError.StatusCode == http.StatusConflict
+	case ErrBadRequest:
+		return apiError.StatusCode == http.StatusBadRequest
+	}
+	return false
+}
+
+// Temporary reports whether the request may succeed if retried.
+func (apiError *APIError) Temporary() bool {
+	return apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode >= 500
+}
+
+// newAPIError decodes the error envelope from body.
+func newAPIError(statusCode int, body []byte) *APIError {
+	var envelope struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) != nil || envelope.Error == "" {
+		envelope.Error = http.StatusText(statusCode)
+	}
+	return &APIError{StatusCode: statusCode, Message: envelope.Error}
+}
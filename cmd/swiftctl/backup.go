@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"swift-codes-project/backup"
+)
+
+// runBackup copies the database into a backup directory; the server may keep
+// running meanwhile.
+func runBackup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	openDatabase := databaseFlags(flags, "SQLite database DSN")
+	dir := flags.String("dir", envOr(backup.DirEnv, "backups"), "backup directory")
+	compression := flags.String("compress", envOr(backup.CompressionEnv, backup.CompressionGzip), "compression: none, gzip or zstd")
+	keep := flags.Int("keep", 7, "number of backups to retain; 0 keeps all")
+	flags.Parse(args)
+
+	if err := backup.CheckCompression(*compression); err != nil {
+		return err
+	}
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	manager := &backup.Manager{DB: database, Dir: *dir, Compression: *compression, Keep: *keep}
+	created, err := manager.CreateBackup(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d bytes)\n", filepath.Join(*dir, created.Name), created.Size)
+	return nil
+}
+
+// runRestore validates a backup and copies it into the database.
+func runRestore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	openDatabase := databaseFlags(flags, "SQLite database DSN to restore into")
+	dir := flags.String("dir", envOr(backup.DirEnv, "backups"), "backup directory, used when -from is not given")
+	from := flags.String("from", "", "backup file to restore (default the newest in -dir)")
+	flags.Parse(args)
+
+	source := *from
+	if source == "" {
+		backups, err := backup.List(*dir)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			return errors.New("no backups in " + *dir)
+		}
+		source = filepath.Join(*dir, backups[0].Name)
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	if err := backup.Restore(context.Background(), source, database); err != nil {
+		return err
+	}
+	fmt.Printf("restored %s; restart every server using the database now: the change log went back to the backup's sequence\n", source)
+	return nil
+}
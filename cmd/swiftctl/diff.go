@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"swift-codes-project/db"
+	"swift-codes-project/diff"
+	"swift-codes-project/models"
+	"swift-codes-project/parser"
+	"swift-codes-project/service"
+)
+
+// runDiff compares two vendor files, or one candidate file against the live
+// table when only one file is given.
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	openDatabase := databaseFlags(flags, "database DSN, used when only one file is given")
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Parse(args)
+
+	var oldCodes, newCodes []models.SwiftCode
+	var err error
+	switch flags.NArg() {
+	case 1:
+		if oldCodes, err = readLiveCodes(openDatabase); err != nil {
+			return err
+		}
+		if newCodes, err = readCodeFile(flags.Arg(0)); err != nil {
+			return err
+		}
+	case 2:
+		if oldCodes, err = readCodeFile(flags.Arg(0)); err != nil {
+			return err
+		}
+		if newCodes, err = readCodeFile(flags.Arg(1)); err != nil {
+			return err
+		}
+	default:
+		return errors.New("usage: swiftctl diff [flags] [old.xlsx] new.xlsx")
+	}
+
+	result := diff.Compare(oldCodes, newCodes)
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	return result.WriteText(os.Stdout)
+}
+
+// readCodeFile reads an xlsx or csv file without touching the database.
+func readCodeFile(filePath string) ([]models.SwiftCode, error) {
+	var codes []models.SwiftCode
+	var err error
+	if strings.EqualFold(filepath.Ext(filePath), ".csv") {
+		codes, _, err = parser.ReadCSV(filePath, parser.ImportOptions{})
+	} else {
+		codes, _, err = parser.ReadExcel(filePath, parser.ImportOptions{})
+	}
+	return codes, err
+}
+
+func readLiveCodes(openDatabase func() (*db.DB, error)) ([]models.SwiftCode, error) {
+	database, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+
+	var codes []models.SwiftCode
+	repo := &service.SwiftRepository{DB: database}
+	err = repo.ExportSwiftCodes("", func(sc models.SwiftCode) error {
+		codes = append(codes, sc)
+		return nil
+	})
+	return codes, err
+}
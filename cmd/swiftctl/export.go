@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"swift-codes-project/exporter"
+	"swift-codes-project/service"
+)
+
+// runExport writes every stored code, optionally for one country, to a file or stdout.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	openDatabase := databaseFlags(flags, "database DSN")
+	format := flags.String("format", exporter.FormatCSV, "output format: csv, jsonl, xlsx or snapshot")
+	country := flags.String("country", "", "only export this ISO-2 country code")
+	outputPath := flags.String("o", "", "output file (default stdout)")
+	flags.Parse(args)
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	var destination io.Writer = os.Stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		destination = file
+	}
+
+	rowWriter, err := exporter.NewWriter(*format, destination)
+	if err != nil {
+		return err
+	}
+	repo := &service.SwiftRepository{DB: database}
+	if err := repo.ExportSwiftCodes(strings.ToUpper(*country), rowWriter.WriteRow); err != nil {
+		return err
+	}
+	return rowWriter.Close()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+	"swift-codes-project/parser"
+	"swift-codes-project/service"
+)
+
+// runImport loads a vendor file into the live table and records it as a new
+// dataset version.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	openDatabase := databaseFlags(flags, "database DSN")
+	filePath := flags.String("file", "", "xlsx or csv file to import")
+	sheetName := flags.String("sheet", "", "sheet to import (default first sheet)")
+	versionName := flags.String("name", "", "dataset version name (default file name and import time)")
+	effective := flags.String("effective", "", "date the data takes effect, 2006-01-02 (default now)")
+	replace := flags.Bool("replace", true, "replace all existing rows instead of adding to them")
+	checkCountries := flags.Bool("check-countries", true, "skip rows whose country is not in the ISO 3166-1 countries table")
+	flags.Parse(args)
+
+	if *filePath == "" {
+		return errors.New("-file is required")
+	}
+	now := time.Now()
+	effectiveFrom := now
+	if *effective != "" {
+		parsed, err := time.Parse(time.DateOnly, *effective)
+		if err != nil {
+			return fmt.Errorf("invalid -effective: %v", err)
+		}
+		effectiveFrom = parsed
+	}
+	if *versionName == "" {
+		*versionName = service.DatasetVersionName(*filePath, now)
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	// the version is recorded in the import's own transaction, so a failed
+	// import leaves neither rows nor a version behind
+	var version models.DatasetVersion
+	options := parser.ImportOptions{
+		SheetName:      *sheetName,
+		Replace:        *replace,
+		CheckCountries: *checkCountries,
+		Finish: func(tx *db.Tx) (err error) {
+			version, err = service.RecordDatasetVersionTx(tx, *versionName, *filePath, effectiveFrom)
+			return err
+		},
+	}
+	var report parser.ImportReport
+	if strings.EqualFold(filepath.Ext(*filePath), ".csv") {
+		report, err = parser.ImportCSV(database, *filePath, options)
+	} else {
+		report, err = parser.ImportExcel(database, *filePath, options)
+	}
+	if err != nil {
+		return err
+	}
+	for _, skipped := range report.SkippedRows {
+		fmt.Printf("skipped row %d: %s\n", skipped.RowNumber, skipped.Reason)
+	}
+	fmt.Printf("imported %d rows as version %d (%s)\n", report.Imported, version.Version, version.Name)
+	return nil
+}
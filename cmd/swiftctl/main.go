@@ -0,0 +1,78 @@
+// Command swiftctl performs administrative tasks against the SWIFT codes database.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"swift-codes-project/db"
+)
+
+const defaultDSN = "file:swift_codes.db?_fk=1"
+
+// databaseFlags registers -driver and -db, defaulting to the server's
+// environment variables, which also tune the connection, and returns a function that opens the database once
+// flags have been parsed.
+func databaseFlags(flags *flag.FlagSet, dsnUsage string) func() (*db.DB, error) {
+	driver := flags.String("driver", envOr(db.DriverEnv, db.SQLite.Name), "database driver: sqlite or postgres")
+	dsn := flags.String("db", envOr(db.DSNEnv, defaultDSN), dsnUsage)
+	return func() (*db.DB, error) {
+		dialect, err := db.DialectByName(*driver)
+		if err != nil {
+			return nil, err
+		}
+		settings, err := db.SettingsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return settings.Open(dialect, *dsn)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// commands maps each subcommand to the function that runs it.
+var commands = map[string]func(args []string) error{
+	"backup":   runBackup,
+	"diff":     runDiff,
+	"export":   runExport,
+	"import":   runImport,
+	"keygen":   runKeygen,
+	"restore":  runRestore,
+	"snapshot": runSnapshot,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: swiftctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  backup    copy the database while the server keeps serving")
+	fmt.Fprintln(os.Stderr, "  diff      compare two files, or a file against the database")
+	fmt.Fprintln(os.Stderr, "  export    write the directory as csv, jsonl or xlsx")
+	fmt.Fprintln(os.Stderr, "  import    load an xlsx or csv file as a new dataset version")
+	fmt.Fprintln(os.Stderr, "  keygen    create the key pair snapshots are signed with")
+	fmt.Fprintln(os.Stderr, "  restore   check a backup and copy it into the database")
+	fmt.Fprintln(os.Stderr, "  snapshot  publish a signed snapshot for read-only replicas")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "swiftctl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"swift-codes-project/dbsnapshot"
+)
+
+// runKeygen creates the key pair snapshots are signed and verified with.
+func runKeygen(args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	prefix := flags.String("o", "snapshot", "write the keys to <o>.key and <o>.pub")
+	flags.Parse(args)
+	if err := dbsnapshot.GenerateKeyFiles(*prefix+".key", *prefix+".pub"); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s.key (keep it secret) and %s.pub\n", *prefix, *prefix)
+	return nil
+}
+
+// runSnapshot publishes one signed snapshot of a SQLite database.
+func runSnapshot(args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	openDatabase := databaseFlags(flags, "SQLite database DSN")
+	dir := flags.String("dir", envOr(dbsnapshot.DirEnv, "snapshots"), "directory to publish into")
+	keyPath := flags.String("key", envOr(dbsnapshot.KeyEnv, "snapshot.key"), "private key file from swiftctl keygen")
+	flags.Parse(args)
+
+	key, err := dbsnapshot.ReadPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	publisher := &dbsnapshot.Publisher{DB: database, Dir: *dir, Key: key}
+	manifest, err := publisher.Publish(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("published %s: %d rows, dataset version %d, sha256 %s\n",
+		manifest.File, manifest.RowCount, manifest.DatasetVersion, manifest.SHA256)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package countries holds the ISO 3166-1 list the countries table is seeded
+// from, and the rules swift codes are checked against it with.
+package countries
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"swift-codes-project/models"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// iso3166 lists every assigned alpha-2 code with its alpha-3 and numeric
+// codes, its short name and its official name. It was generated from the
+// github.com/pariz/gountries data, with the current names for CZ, MK, SZ
+// and TR.
+//
+//go:embed iso3166.csv
+var iso3166 []byte
+
+// Errors returned by Check.
+var (
+	ErrUnknownCountry = errors.New("unknown country")
+	ErrNameMismatch   = errors.New("country name does not match")
+)
+
+var (
+	parseOnce sync.Once
+	all       []models.Country
+	byISO2    map[string]models.Country
+)
+
+func parse() {
+	records, err := csv.NewReader(bytes.NewReader(iso3166)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("countries: embedded iso3166.csv: %v", err))
+	}
+	byISO2 = make(map[string]models.Country, len(records))
+	for _, record := range records[1:] {
+		country := models.Country{ISO2: record[0], ISO3: record[1], Numeric: record[2], Name: record[3], OfficialName: record[4]}
+		all = append(all, country)
+		byISO2[country.ISO2] = country
+	}
+}
+
+// All returns every country, ordered by alpha-2 code.
+func All() []models.Country {
+	parseOnce.Do(parse)
+	return append([]models.Country(nil), all...)
+}
+
+// Lookup returns the country with the alpha-2 code iso2, in upper case.
+func Lookup(iso2 string) (models.Country, bool) {
+	parseOnce.Do(parse)
+	country, ok := byISO2[iso2]
+	return country, ok
+}
+
+// Check validates a swift code's country against the embedded list; see
+// CheckName.
+func Check(iso2, countryName string) error {
+	country, ok := Lookup(iso2)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownCountry, iso2)
+	}
+	return CheckName(country, countryName)
+}
+
+// CheckName returns ErrNameMismatch unless countryName is the country's short
+// or official name. Case, accents and spacing are ignored, so the upper-cased
+// names in vendor files, such as CURACAO, match.
+func CheckName(country models.Country, countryName string) error {
+	folded := fold(countryName)
+	if folded == "" || (folded != fold(country.Name) && folded != fold(country.OfficialName)) {
+		return fmt.Errorf("%w: %q is not %s (%s)", ErrNameMismatch, countryName, country.ISO2, country.Name)
+	}
+	return nil
+}
+
+// fold upper-cases name, drops its accents and collapses its whitespace.
+func fold(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		stripped = name
+	}
+	return strings.Join(strings.Fields(strings.ToUpper(stripped)), " ")
+}
@@ -0,0 +1,51 @@
+package countries
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEmbeddedListIsComplete(t *testing.T) {
+	all := All()
+	if len(all) != 249 {
+		t.Fatalf("Expected the 249 ISO 3166-1 countries, got %d", len(all))
+	}
+	seen := make(map[string]bool)
+	for i, country := range all {
+		if len(country.ISO2) != 2 || len(country.ISO3) != 3 || len(country.Numeric) != 3 || country.Name == "" || country.OfficialName == "" {
+			t.Errorf("Incomplete country %+v", country)
+		}
+		if seen[country.ISO2] || seen[country.ISO3] || seen[country.Numeric] {
+			t.Errorf("Duplicate code in %+v", country)
+		}
+		seen[country.ISO2], seen[country.ISO3], seen[country.Numeric] = true, true, true
+		if i > 0 && all[i-1].ISO2 >= country.ISO2 {
+			t.Errorf("Expected countries ordered by ISO2, found %s after %s", country.ISO2, all[i-1].ISO2)
+		}
+	}
+	if poland, ok := Lookup("PL"); !ok || poland.ISO3 != "POL" || poland.Numeric != "616" {
+		t.Errorf("Unexpected entry for PL: %+v", poland)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, valid := range [][2]string{
+		{"PL", "POLAND"},
+		{"PL", "Republic of  Poland"},
+		{"CW", "CURACAO"},
+		{"AX", "aland islands"},
+		{"US", "UNITED STATES OF AMERICA"},
+	} {
+		if err := Check(valid[0], valid[1]); err != nil {
+			t.Errorf("%s %q: expected a match, got %v", valid[0], valid[1], err)
+		}
+	}
+	if err := Check("ZZ", "ZELAND"); !errors.Is(err, ErrUnknownCountry) {
+		t.Errorf("Expected ErrUnknownCountry for ZZ, got %v", err)
+	}
+	for _, mismatch := range []string{"GERMANY", "POLSKA", ""} {
+		if err := Check("PL", mismatch); !errors.Is(err, ErrNameMismatch) {
+			t.Errorf("%q: expected ErrNameMismatch, got %v", mismatch, err)
+		}
+	}
+}
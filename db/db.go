@@ -1,40 +1,181 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-
-	_ "github.com/mattn/go-sqlite3"
+	"sync"
 )
 
-//Init DB initilaizes the SQLite DB connection
+// DB is a connection pool for one Dialect. Queries are written with ?
+// placeholders, as for SQLite, and rebound for the dialect on the way out, so
+// repositories need no per-engine copies of their SQL.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+
+	// statements maps query text to what PrepareCached prepared for it.
+	statements sync.Map
+}
+
+// Tx is a transaction begun on a DB; it rebinds queries the same way.
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+// Open connects to dsn with DefaultSettings and migrates the schema.
+func Open(dialect Dialect, dsn string) (*DB, error) {
+	return DefaultSettings.Open(dialect, dsn)
+}
 
-func InitDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dsn)
+// OpenReadOnly is Settings.OpenReadOnly with DefaultSettings.
+func OpenReadOnly(dialect Dialect, dsn string) (*DB, error) {
+	return DefaultSettings.OpenReadOnly(dialect, dsn)
+}
+
+func (settings Settings) open(dialect Dialect, dsn string, prepare func(*DB) error) (*DB, error) {
+	connections, err := sql.Open(dialect.DriverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("error openind db connection %w", err)
 	}
-	if err := db.Ping(); err != nil {
+	database := &DB{DB: connections, Dialect: dialect}
+	settings.applyPool(database)
+	if err := database.Ping(); err != nil {
+		connections.Close()
 		return nil, fmt.Errorf("error pinging db %w", err)
 	}
+	if err := prepare(database); err != nil {
+		connections.Close()
+		return nil, err
+	}
+	return database, nil
+}
+
+//Init DB initilaizes the SQLite DB connection
+
+func InitDB(dsn string) (*DB, error) {
+	return Open(SQLite, dsn)
+}
+
+// PrepareCached prepares queries once. Later Exec, Query and QueryRow calls
+// with exactly the same text run the prepared statement instead of having
+// the driver parse the SQL again.
+func (database *DB) PrepareCached(queries ...string) error {
+	for _, query := range queries {
+		if _, cached := database.statements.Load(query); cached {
+			continue
+		}
+		statement, err := database.DB.Prepare(database.Dialect.Rebind(query))
+		if err != nil {
+			return fmt.Errorf("error preparing statement %w", err)
+		}
+		if _, raced := database.statements.LoadOrStore(query, statement); raced {
+			statement.Close()
+		}
+	}
+	return nil
+}
+
+func (database *DB) cached(query string) (*sql.Stmt, bool) {
+	statement, ok := database.statements.Load(query)
+	if !ok {
+		return nil, false
+	}
+	return statement.(*sql.Stmt), true
+}
+
+// Close closes the prepared statements and then the pool.
+func (database *DB) Close() error {
+	database.statements.Range(func(query, statement any) bool {
+		statement.(*sql.Stmt).Close()
+		database.statements.Delete(query)
+		return true
+	})
+	return database.DB.Close()
+}
+
+func (database *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return database.ExecContext(context.Background(), query, args...)
+}
+
+func (database *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if statement, ok := database.cached(query); ok {
+		return statement.ExecContext(ctx, args...)
+	}
+	return database.DB.ExecContext(ctx, database.Dialect.Rebind(query), args...)
+}
+
+func (database *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return database.QueryContext(context.Background(), query, args...)
+}
 
-	//create the table
-	createTableQuery := `
-	CREATE TABLE IF NOT EXISTS swift_codes (
-		country_iso2 TEXT,
-		swift_code   TEXT PRIMARY KEY,
-		code_type    TEXT,
-		name         TEXT,
-		address      TEXT,
-		town_name    TEXT,
-		country_name TEXT,
-		time_zone    TEXT,
-		is_headquarter BOOLEAN,
-    	hq_swift_code  TEXT
-	);`
-	_, err = db.Exec(createTableQuery)
+func (database *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if statement, ok := database.cached(query); ok {
+		return statement.QueryContext(ctx, args...)
+	}
+	return database.DB.QueryContext(ctx, database.Dialect.Rebind(query), args...)
+}
+
+func (database *DB) QueryRow(query string, args ...any) *sql.Row {
+	return database.QueryRowContext(context.Background(), query, args...)
+}
+
+func (database *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if statement, ok := database.cached(query); ok {
+		return statement.QueryRowContext(ctx, args...)
+	}
+	return database.DB.QueryRowContext(ctx, database.Dialect.Rebind(query), args...)
+}
+
+func (database *DB) Prepare(query string) (*sql.Stmt, error) {
+	return database.PrepareContext(context.Background(), query)
+}
+
+func (database *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return database.DB.PrepareContext(ctx, database.Dialect.Rebind(query))
+}
+
+func (database *DB) Begin() (*Tx, error) {
+	return database.BeginTx(context.Background(), nil)
+}
+
+func (database *DB) BeginTx(ctx context.Context, options *sql.TxOptions) (*Tx, error) {
+	tx, err := database.DB.BeginTx(ctx, options)
 	if err != nil {
-		return nil, fmt.Errorf("error creating swift_codes table %w", err)
+		return nil, err
 	}
-	return db, nil
+	return &Tx{Tx: tx, Dialect: database.Dialect}, nil
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.PrepareContext(context.Background(), query)
+}
+
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.Tx.PrepareContext(ctx, tx.Dialect.Rebind(query))
 }
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebindNumbersPlaceholdersOutsideLiterals(t *testing.T) {
+	query := `SELECT * FROM swift_codes WHERE name LIKE ? ESCAPE '\' AND address <> 'why?' AND swift_code = ?;`
+	if rebound := SQLite.Rebind(query); rebound != query {
+		t.Errorf("Expected SQLite to keep ? placeholders, got %s", rebound)
+	}
+	expected := `SELECT * FROM swift_codes WHERE name LIKE $1 ESCAPE '\' AND address <> 'why?' AND swift_code = $2;`
+	if rebound := Postgres.Rebind(query); rebound != expected {
+		t.Errorf("Unexpected PostgreSQL query:\n%s", rebound)
+	}
+}
+
+func TestDialectByName(t *testing.T) {
+	for name, expected := range map[string]Dialect{"sqlite": SQLite, "sqlite3": SQLite, "Postgres": Postgres, "pgx": Postgres} {
+		if dialect, err := DialectByName(name); err != nil || dialect != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", name, expected.Name, dialect.Name, err)
+		}
+	}
+	if _, err := DialectByName("oracle"); err == nil {
+		t.Errorf("Expected an error for an unknown driver")
+	}
+}
+
+// TestMigrationsRunOnce reopens a file database and checks every migration
+// is recorded exactly once.
+func TestMigrationsRunOnce(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "swift_codes.db") + "?_fk=1"
+	for i := 0; i < 2; i++ {
+		database, err := InitDB(dsn)
+		if err != nil {
+			t.Fatalf("Open %d failed: %v", i+1, err)
+		}
+		var applied int
+		database.QueryRow(`SELECT COUNT(*) FROM schema_migrations;`).Scan(&applied)
+		database.Close()
+		if applied != len(migrations) {
+			t.Fatalf("Open %d: expected %d recorded migrations, got %d", i+1, len(migrations), applied)
+		}
+	}
+}
+
+// TestNormalizeTimeZonesMigration replays the migration over rows written
+// before it, keeping the zones it cannot map and leaving the change log as
+// the inserts left it.
+func TestNormalizeTimeZonesMigration(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "swift_codes.db") + "?_fk=1"
+	database, err := InitDB(dsn)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for code, zone := range map[string]string{"PLBANKPLXXX": "europe/warsaw", "MTBANKMTXXX": "UTC+01:00", "ZZBANKZZXXX": "Europe/Zeland"} {
+		if _, err := database.Exec(`INSERT INTO swift_codes (country_iso2, swift_code, name, country_name, time_zone, is_headquarter) VALUES (?, ?, '', '', ?, 1);`, code[4:6], code, zone); err != nil {
+			t.Fatalf("Insert %s failed: %v", code, err)
+		}
+	}
+	database.Exec(`DELETE FROM schema_migrations WHERE name = 'normalize time zones';`)
+	database.Close()
+
+	database, err = InitDB(dsn)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer database.Close()
+	for code, expected := range map[string]string{"PLBANKPLXXX": "Europe/Warsaw", "MTBANKMTXXX": "Etc/GMT-1", "ZZBANKZZXXX": "Europe/Zeland"} {
+		var zone string
+		database.QueryRow(`SELECT time_zone FROM swift_codes WHERE swift_code = ?;`, code).Scan(&zone)
+		if zone != expected {
+			t.Errorf("%s: expected %s, got %s", code, expected, zone)
+		}
+	}
+	var updates int
+	database.QueryRow(`SELECT COUNT(*) FROM swift_code_changes WHERE change_type = 'updated';`).Scan(&updates)
+	if updates != 0 {
+		t.Errorf("Expected the migration to leave no updates in the change log, got %d", updates)
+	}
+}
+
+func TestReadOnlyDSN(t *testing.T) {
+	testCases := []struct {
+		dialect       Dialect
+		dsn, expected string
+	}{
+		{SQLite, "swift_codes.db", "file:swift_codes.db?mode=ro"},
+		{SQLite, "file:swift_codes.db?cache=shared&_fk=1", "file:swift_codes.db?cache=shared&_fk=1&mode=ro"},
+		{Postgres, "postgres://swift@db/swift_codes", "postgres://swift@db/swift_codes?default_transaction_read_only=on"},
+		{Postgres, "postgres://swift@db/swift_codes?sslmode=disable", "postgres://swift@db/swift_codes?sslmode=disable&default_transaction_read_only=on"},
+		{Postgres, "host=db dbname=swift_codes", "host=db dbname=swift_codes default_transaction_read_only=on"},
+	}
+	for _, testCase := range testCases {
+		if got := testCase.dialect.ReadOnlyDSN(testCase.dsn); got != testCase.expected {
+			t.Errorf("%s %q: expected %q, got %q", testCase.dialect.Name, testCase.dsn, testCase.expected, got)
+		}
+	}
+}
+
+func TestOpenReadOnlyRefusesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swift_codes.db")
+	if _, err := OpenReadOnly(SQLite, path); err == nil {
+		t.Fatalf("Expected an error opening a database that was never migrated")
+	}
+
+	writer, err := InitDB("file:" + path + "?_fk=1")
+	if err != nil {
+		t.Fatalf("Failed to create the database: %v", err)
+	}
+	writer.Close()
+
+	reader, err := OpenReadOnly(SQLite, path)
+	if err != nil {
+		t.Fatalf("Expected the migrated database to open read-only, got %v", err)
+	}
+	defer reader.Close()
+	var count int
+	if err := reader.QueryRow(`SELECT COUNT(*) FROM swift_codes;`).Scan(&count); err != nil {
+		t.Errorf("Expected reads to work, got %v", err)
+	}
+	if _, err := reader.Exec(`DELETE FROM swift_codes;`); err == nil {
+		t.Errorf("Expected a write to fail")
+	}
+}
+
+func TestSettingsConfigureSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swift_codes.db")
+	settings := Settings{BusyTimeout: 250 * time.Millisecond, Synchronous: "FULL", MaxIdleConns: 3}
+	database, err := settings.Open(SQLite, "file:"+path+"?_fk=1")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer database.Close()
+
+	var journalMode string
+	var busyTimeout, synchronous int
+	database.QueryRow(`PRAGMA journal_mode;`).Scan(&journalMode)
+	database.QueryRow(`PRAGMA busy_timeout;`).Scan(&busyTimeout)
+	database.QueryRow(`PRAGMA synchronous;`).Scan(&synchronous)
+	if journalMode != "wal" || busyTimeout != 250 || synchronous != 2 {
+		t.Errorf("Expected wal, 250ms and FULL (2), got %s, %dms and %d", journalMode, busyTimeout, synchronous)
+	}
+
+	// SQLite takes one writer at a time, so the writable pool holds one
+	// connection while a read-only pool on the same file is not capped
+	if open := database.Stats().MaxOpenConnections; open != 1 {
+		t.Errorf("Expected the writer to hold 1 connection, got %d", open)
+	}
+	reader, err := settings.OpenReadOnly(SQLite, "file:"+path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	defer reader.Close()
+	if open := reader.Stats().MaxOpenConnections; open != 0 {
+		t.Errorf("Expected an uncapped reader, got %d connections", open)
+	}
+
+	// parameters already in the DSN win
+	if dsn := settings.dsn(SQLite, "file:x.db?_journal_mode=DELETE", true); !strings.Contains(dsn, "_journal_mode=DELETE") ||
+		strings.Contains(dsn, "_journal_mode=WAL") {
+		t.Errorf("Expected the DSN's journal mode to be kept, got %s", dsn)
+	}
+	if dsn := settings.dsn(Postgres, "postgres://swift@db/swift_codes", true); dsn != "postgres://swift@db/swift_codes" {
+		t.Errorf("Expected PostgreSQL DSNs to be left alone, got %s", dsn)
+	}
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	t.Setenv(JournalModeEnv, "DELETE")
+	t.Setenv(BusyTimeoutEnv, "2s")
+	t.Setenv(MaxOpenConnsEnv, "4")
+	settings, err := SettingsFromEnv()
+	if err != nil {
+		t.Fatalf("SettingsFromEnv failed: %v", err)
+	}
+	expected := DefaultSettings
+	expected.JournalMode, expected.BusyTimeout, expected.MaxOpenConns = "DELETE", 2*time.Second, 4
+	if settings != expected {
+		t.Errorf("Expected %+v, got %+v", expected, settings)
+	}
+
+	t.Setenv(MaxIdleConnsEnv, "many")
+	if _, err := SettingsFromEnv(); err == nil {
+		t.Errorf("Expected an error for a malformed %s", MaxIdleConnsEnv)
+	}
+}
+
+func TestPrepareCachedReusesStatements(t *testing.T) {
+	database, err := InitDB("file:" + filepath.Join(t.TempDir(), "swift_codes.db") + "?_fk=1")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer database.Close()
+
+	const countSQL = `SELECT COUNT(*) FROM swift_codes WHERE country_iso2 = ?;`
+	if err := database.PrepareCached(countSQL, countSQL); err != nil {
+		t.Fatalf("PrepareCached failed: %v", err)
+	}
+	first, ok := database.cached(countSQL)
+	if !ok {
+		t.Fatalf("Expected the statement to be cached")
+	}
+	database.PrepareCached(countSQL)
+	if again, _ := database.cached(countSQL); again != first {
+		t.Errorf("Expected the statement to be prepared only once")
+	}
+	var count int
+	if err := database.QueryRow(countSQL, "PL").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected the cached statement to run, got %d %v", count, err)
+	}
+	if err := database.PrepareCached(`SELECT nothing FROM nowhere;`); err == nil {
+		t.Errorf("Expected an error preparing invalid SQL")
+	}
+}
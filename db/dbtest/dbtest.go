@@ -0,0 +1,149 @@
+// Package dbtest opens throwaway databases for tests, one per supported
+// dialect, so suites can run unchanged against SQLite and PostgreSQL.
+package dbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"swift-codes-project/db"
+)
+
+// PostgresDSNEnv names the environment variable pointing tests at a
+// PostgreSQL server, e.g. postgres://postgres@localhost:5432/postgres. Every
+// database opened there lives in a schema of its own that is dropped when
+// the test ends. PostgreSQL backends are skipped when it is unset and Main
+// could not start a server either.
+const PostgresDSNEnv = "SWIFT_TEST_POSTGRES_DSN"
+
+// Main runs a package's tests; call it from TestMain. When PostgresDSNEnv is
+// unset and initdb and pg_ctl are on the PATH, it first starts a throwaway
+// PostgreSQL cluster in a temporary directory, listening on a Unix socket
+// only, and points PostgresDSNEnv at it. The cluster is removed afterwards.
+func Main(m *testing.M) int {
+	if os.Getenv(PostgresDSNEnv) == "" {
+		stop, err := startPostgres()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dbtest: PostgreSQL tests will be skipped: %v\n", err)
+		} else {
+			defer stop()
+		}
+	}
+	return m.Run()
+}
+
+// startPostgres initializes and starts a cluster owned by the postgres role
+// with trust authentication, and returns a function that stops and deletes it.
+func startPostgres() (stop func(), err error) {
+	for _, tool := range []string{"initdb", "pg_ctl"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, fmt.Errorf("%s is not on the PATH", tool)
+		}
+	}
+	dir, err := os.MkdirTemp("", "swift-pg")
+	if err != nil {
+		return nil, err
+	}
+	dataDir := filepath.Join(dir, "data")
+	run := func(name string, args ...string) error {
+		output, err := exec.Command(name, args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %v: %s", name, err, output)
+		}
+		return nil
+	}
+	if err := run("initdb", "-D", dataDir, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync"); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	serverOptions := "-k " + dir + " -c listen_addresses='' -F"
+	if err := run("pg_ctl", "-D", dataDir, "-l", filepath.Join(dir, "server.log"), "-o", serverOptions, "-w", "start"); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	os.Setenv(PostgresDSNEnv, "host="+dir+" user=postgres dbname=postgres sslmode=disable")
+	return func() {
+		run("pg_ctl", "-D", dataDir, "-m", "immediate", "-w", "stop")
+		os.Unsetenv(PostgresDSNEnv)
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// Backend opens a fresh, empty database for one test.
+type Backend struct {
+	Name string
+	Open func(t testing.TB) *db.DB
+}
+
+// Backends returns SQLite, plus PostgreSQL when PostgresDSNEnv is set.
+func Backends() []Backend {
+	backends := []Backend{{Name: db.SQLite.Name, Open: OpenSQLite}}
+	if os.Getenv(PostgresDSNEnv) != "" {
+		backends = append(backends, Backend{Name: db.Postgres.Name, Open: OpenPostgres})
+	}
+	return backends
+}
+
+var databaseCounter atomic.Int64
+
+// OpenSQLite opens a private in-memory SQLite database, closed at cleanup.
+func OpenSQLite(t testing.TB) *db.DB {
+	t.Helper()
+	name := fmt.Sprintf("dbtest%d", databaseCounter.Add(1))
+	database, err := db.InitDB("file:" + name + "?mode=memory&cache=shared&_fk=1")
+	if err != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// OpenPostgres creates a schema on the server named by PostgresDSNEnv and
+// opens a database whose search_path points at it.
+func OpenPostgres(t testing.TB) *db.DB {
+	t.Helper()
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+	admin, err := sql.Open(db.Postgres.DriverName, dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	schema := fmt.Sprintf("swift_test_%d_%d", os.Getpid(), databaseCounter.Add(1))
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema + `;`); err != nil {
+		admin.Close()
+		t.Fatalf("Failed to create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE;`)
+		admin.Close()
+	})
+
+	database, err := db.Open(db.Postgres, withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("Failed to initialize PostgreSQL database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// withSearchPath adds search_path to a URL or keyword/value connection string.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if parsed, err := url.Parse(dsn); err == nil {
+			query := parsed.Query()
+			query.Set("search_path", schema)
+			parsed.RawQuery = query.Encode()
+			return parsed.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Dialect describes how a database engine differs from the SQL the
+// repositories are written in, which uses ? placeholders and otherwise sticks
+// to syntax both engines share.
+type Dialect struct {
+	// Name identifies the dialect in configuration.
+	Name string
+	// DriverName is the database/sql driver connections are opened with.
+	DriverName string
+	// numberedPlaceholders rewrites ? to $1, $2, ... as PostgreSQL expects.
+	numberedPlaceholders bool
+}
+
+// The supported dialects.
+var (
+	SQLite   = Dialect{Name: "sqlite", DriverName: "sqlite3"}
+	Postgres = Dialect{Name: "postgres", DriverName: "pgx", numberedPlaceholders: true}
+)
+
+// DialectByName looks a dialect up by its configuration name.
+func DialectByName(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case SQLite.Name, "sqlite3":
+		return SQLite, nil
+	case Postgres.Name, "postgresql", "pgx":
+		return Postgres, nil
+	}
+	return Dialect{}, fmt.Errorf("unknown database driver %q", name)
+}
+
+// Rebind rewrites the ? placeholders of query into the dialect's syntax.
+// Question marks inside string literals are left alone.
+func (dialect Dialect) Rebind(query string) string {
+	if !dialect.numberedPlaceholders || !strings.Contains(query, "?") {
+		return query
+	}
+	var rebound strings.Builder
+	rebound.Grow(len(query) + 16)
+	inLiteral := false
+	position := 0
+	for _, char := range query {
+		switch {
+		case char == '\'':
+			inLiteral = !inLiteral
+		case char == '?' && !inLiteral:
+			position++
+			rebound.WriteByte('$')
+			rebound.WriteString(strconv.Itoa(position))
+			continue
+		}
+		rebound.WriteRune(char)
+	}
+	return rebound.String()
+}
+
+// ReadOnlyDSN returns dsn changed so that connections refuse writes. SQLite
+// paths are turned into file: URIs, which is what makes mode=ro count.
+func (dialect Dialect) ReadOnlyDSN(dsn string) string {
+	if dialect.numberedPlaceholders {
+		// pgx passes unknown parameters to the server as session settings
+		if strings.Contains(dsn, "://") {
+			return withQueryParameter(dsn, "default_transaction_read_only=on")
+		}
+		return strings.TrimSpace(dsn + " default_transaction_read_only=on")
+	}
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	return withQueryParameter(dsn, "mode=ro")
+}
+
+func withQueryParameter(dsn, parameter string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + parameter
+	}
+	return dsn + "?" + parameter
+}
+
+// IsUniqueViolation reports whether err is a primary key or unique constraint
+// failure, whichever driver returned it.
+func IsUniqueViolation(err error) bool {
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.ExtendedCode == sqlite3.ErrConstraintPrimaryKey ||
+			sqliteError.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	var postgresError *pgconn.PgError
+	return errors.As(err, &postgresError) && postgresError.Code == "23505"
+}
+
+// Environment variables the server reads to choose its database. swiftctl
+// uses them as the defaults of its -driver and -db flags.
+const (
+	DriverEnv = "SWIFT_DB_DRIVER"
+	DSNEnv    = "SWIFT_DB_DSN"
+	// ReadDSNEnv points repository reads at a separate pool, such as a replica.
+	ReadDSNEnv = "SWIFT_DB_READ_DSN"
+	// ReadOnlyEnv, when true, opens SWIFT_DB_DSN read-only and refuses writes.
+	ReadOnlyEnv = "SWIFT_READ_ONLY"
+)
@@ -0,0 +1,654 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"swift-codes-project/countries"
+	"swift-codes-project/timezones"
+)
+
+// migration is one schema step, written once per dialect. Steps are applied
+// in order and recorded in schema_migrations, so each runs once per database.
+// The first steps use IF NOT EXISTS throughout because databases created
+// before schema_migrations existed already have their tables.
+type migration struct {
+	name     string
+	sqlite   string
+	postgres string
+	// seed, when set, fills the new tables or rewrites rows in the same
+	// transaction. A migration may consist of a seed alone.
+	seed func(tx *sql.Tx, dialect Dialect) error
+}
+
+func (step migration) sql(dialect Dialect) string {
+	if dialect == Postgres {
+		return step.postgres
+	}
+	return step.sqlite
+}
+
+var migrations = []migration{
+	{
+		name: "create swift_codes",
+		sqlite: `
+	CREATE TABLE IF NOT EXISTS swift_codes (
+		country_iso2 TEXT,
+		swift_code   TEXT PRIMARY KEY,
+		code_type    TEXT,
+		name         TEXT,
+		address      TEXT,
+		town_name    TEXT,
+		country_name TEXT,
+		time_zone    TEXT,
+		is_headquarter BOOLEAN,
+		hq_swift_code  TEXT
+	);`,
+		postgres: `
+	CREATE TABLE IF NOT EXISTS swift_codes (
+		country_iso2   TEXT,
+		swift_code     TEXT PRIMARY KEY,
+		code_type      TEXT,
+		name           TEXT,
+		address        TEXT,
+		town_name      TEXT,
+		country_name   TEXT,
+		time_zone      TEXT,
+		is_headquarter BOOLEAN,
+		hq_swift_code  TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_swift_codes_country ON swift_codes (country_iso2);
+	CREATE INDEX IF NOT EXISTS idx_swift_codes_hq ON swift_codes (hq_swift_code);`,
+	},
+	{
+		// every import is recorded as a dataset version; swift_code_history keeps
+		// each row with the range of versions [valid_from, valid_to) it was part of
+		name: "create dataset history",
+		sqlite: `
+	CREATE TABLE IF NOT EXISTS dataset_versions (
+		version        INTEGER PRIMARY KEY AUTOINCREMENT,
+		name           TEXT NOT NULL UNIQUE,
+		source         TEXT,
+		effective_from TIMESTAMP NOT NULL,
+		imported_at    TIMESTAMP NOT NULL,
+		row_count      INTEGER NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS swift_code_history (
+		country_iso2   TEXT,
+		swift_code     TEXT NOT NULL,
+		code_type      TEXT,
+		name           TEXT,
+		address        TEXT,
+		town_name      TEXT,
+		country_name   TEXT,
+		time_zone      TEXT,
+		is_headquarter BOOLEAN,
+		hq_swift_code  TEXT,
+		valid_from     INTEGER NOT NULL REFERENCES dataset_versions(version),
+		valid_to       INTEGER REFERENCES dataset_versions(version)
+	);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_history_code ON swift_code_history (swift_code, valid_from);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_history_country ON swift_code_history (country_iso2, valid_from);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_history_hq ON swift_code_history (hq_swift_code, valid_from);`,
+		postgres: `
+	CREATE TABLE IF NOT EXISTS dataset_versions (
+		version        BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		name           TEXT NOT NULL UNIQUE,
+		source         TEXT,
+		effective_from TIMESTAMPTZ NOT NULL,
+		imported_at    TIMESTAMPTZ NOT NULL,
+		row_count      INTEGER NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS swift_code_history (
+		country_iso2   TEXT,
+		swift_code     TEXT NOT NULL,
+		code_type      TEXT,
+		name           TEXT,
+		address        TEXT,
+		town_name      TEXT,
+		country_name   TEXT,
+		time_zone      TEXT,
+		is_headquarter BOOLEAN,
+		hq_swift_code  TEXT,
+		valid_from     BIGINT NOT NULL REFERENCES dataset_versions(version),
+		valid_to       BIGINT REFERENCES dataset_versions(version)
+	);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_history_code ON swift_code_history (swift_code, valid_from);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_history_country ON swift_code_history (country_iso2, valid_from);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_history_hq ON swift_code_history (hq_swift_code, valid_from);`,
+	},
+	{
+		// swift_code_changes is the change-data-capture log. Triggers record every
+		// write to swift_codes, whichever code path made it, with a monotonically
+		// increasing sequence number. Deletes keep the row as it was before.
+		name: "create change log",
+		sqlite: `
+	CREATE TABLE IF NOT EXISTS swift_code_changes (
+		sequence       INTEGER PRIMARY KEY AUTOINCREMENT,
+		change_type    TEXT NOT NULL,
+		changed_at     TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+		country_iso2   TEXT,
+		swift_code     TEXT NOT NULL,
+		code_type      TEXT,
+		name           TEXT,
+		address        TEXT,
+		town_name      TEXT,
+		country_name   TEXT,
+		time_zone      TEXT,
+		is_headquarter BOOLEAN,
+		hq_swift_code  TEXT
+	);
+	CREATE TRIGGER IF NOT EXISTS swift_codes_created AFTER INSERT ON swift_codes BEGIN
+		INSERT INTO swift_code_changes (
+			change_type, country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone, is_headquarter, hq_swift_code
+		) VALUES (
+			'created', NEW.country_iso2, NEW.swift_code, NEW.code_type, NEW.name, NEW.address,
+			NEW.town_name, NEW.country_name, NEW.time_zone, NEW.is_headquarter, NEW.hq_swift_code
+		);
+	END;
+	CREATE TRIGGER IF NOT EXISTS swift_codes_updated AFTER UPDATE ON swift_codes BEGIN
+		INSERT INTO swift_code_changes (
+			change_type, country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone, is_headquarter, hq_swift_code
+		) VALUES (
+			'updated', NEW.country_iso2, NEW.swift_code, NEW.code_type, NEW.name, NEW.address,
+			NEW.town_name, NEW.country_name, NEW.time_zone, NEW.is_headquarter, NEW.hq_swift_code
+		);
+	END;
+	CREATE TRIGGER IF NOT EXISTS swift_codes_deleted AFTER DELETE ON swift_codes BEGIN
+		INSERT INTO swift_code_changes (
+			change_type, country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone, is_headquarter, hq_swift_code
+		) VALUES (
+			'deleted', OLD.country_iso2, OLD.swift_code, OLD.code_type, OLD.name, OLD.address,
+			OLD.town_name, OLD.country_name, OLD.time_zone, OLD.is_headquarter, OLD.hq_swift_code
+		);
+	END;
+	CREATE INDEX IF NOT EXISTS idx_swift_code_changes_code ON swift_code_changes (swift_code, changed_at);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_changes_hq ON swift_code_changes (hq_swift_code, changed_at);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_changes_country ON swift_code_changes (country_iso2, changed_at);`,
+		// clock_timestamp rather than now(), which is frozen for the whole
+		// transaction, so rows of one import batch keep distinct times
+		postgres: `
+	CREATE TABLE IF NOT EXISTS swift_code_changes (
+		sequence       BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		change_type    TEXT NOT NULL,
+		changed_at     TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+		country_iso2   TEXT,
+		swift_code     TEXT NOT NULL,
+		code_type      TEXT,
+		name           TEXT,
+		address        TEXT,
+		town_name      TEXT,
+		country_name   TEXT,
+		time_zone      TEXT,
+		is_headquarter BOOLEAN,
+		hq_swift_code  TEXT
+	);
+	CREATE OR REPLACE FUNCTION record_swift_code_change() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			INSERT INTO swift_code_changes (
+				change_type, country_iso2, swift_code, code_type, name, address,
+				town_name, country_name, time_zone, is_headquarter, hq_swift_code
+			) VALUES (
+				'deleted', OLD.country_iso2, OLD.swift_code, OLD.code_type, OLD.name, OLD.address,
+				OLD.town_name, OLD.country_name, OLD.time_zone, OLD.is_headquarter, OLD.hq_swift_code
+			);
+			RETURN OLD;
+		END IF;
+		INSERT INTO swift_code_changes (
+			change_type, country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone, is_headquarter, hq_swift_code
+		) VALUES (
+			CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END,
+			NEW.country_iso2, NEW.swift_code, NEW.code_type, NEW.name, NEW.address,
+			NEW.town_name, NEW.country_name, NEW.time_zone, NEW.is_headquarter, NEW.hq_swift_code
+		);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS swift_codes_changed ON swift_codes;
+	CREATE TRIGGER swift_codes_changed AFTER INSERT OR UPDATE OR DELETE ON swift_codes
+		FOR EACH ROW EXECUTE FUNCTION record_swift_code_change();
+	CREATE INDEX IF NOT EXISTS idx_swift_code_changes_code ON swift_code_changes (swift_code, changed_at);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_changes_hq ON swift_code_changes (hq_swift_code, changed_at);
+	CREATE INDEX IF NOT EXISTS idx_swift_code_changes_country ON swift_code_changes (country_iso2, changed_at);`,
+	},
+	{
+		// webhook_deliveries is an outbox: events are written here before any
+		// attempt is made, so they survive restarts and are delivered at least once.
+		// webhook_cursors remembers how far each event source has been read.
+		name: "create webhooks",
+		sqlite: `
+	CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		url          TEXT NOT NULL,
+		secret       TEXT NOT NULL,
+		event_types  TEXT NOT NULL,
+		country_iso2 TEXT NOT NULL DEFAULT '',
+		created_at   TIMESTAMP NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id               INTEGER PRIMARY KEY AUTOINCREMENT,
+		subscription_id  INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event_type       TEXT NOT NULL,
+		payload          TEXT NOT NULL,
+		status           TEXT NOT NULL,
+		attempts         INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at  TIMESTAMP NOT NULL,
+		last_status_code INTEGER NOT NULL DEFAULT 0,
+		last_error       TEXT NOT NULL DEFAULT '',
+		created_at       TIMESTAMP NOT NULL,
+		delivered_at     TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	CREATE TABLE IF NOT EXISTS webhook_cursors (
+		source   TEXT PRIMARY KEY,
+		position INTEGER NOT NULL
+	);`,
+		postgres: `
+	CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		url          TEXT NOT NULL,
+		secret       TEXT NOT NULL,
+		event_types  TEXT NOT NULL,
+		country_iso2 TEXT NOT NULL DEFAULT '',
+		created_at   TIMESTAMPTZ NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id               BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		subscription_id  BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event_type       TEXT NOT NULL,
+		payload          TEXT NOT NULL,
+		status           TEXT NOT NULL,
+		attempts         INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at  TIMESTAMPTZ NOT NULL,
+		last_status_code INTEGER NOT NULL DEFAULT 0,
+		last_error       TEXT NOT NULL DEFAULT '',
+		created_at       TIMESTAMPTZ NOT NULL,
+		delivered_at     TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	CREATE TABLE IF NOT EXISTS webhook_cursors (
+		source   TEXT PRIMARY KEY,
+		position BIGINT NOT NULL
+	);`,
+	},
+	{
+		// ISO 3166-1 reference data that swift codes are validated against,
+		// and the per-country index SQLite lacked for counting codes by country
+		name: "create countries",
+		sqlite: `
+	CREATE TABLE countries (
+		iso2          TEXT PRIMARY KEY,
+		iso3          TEXT NOT NULL UNIQUE,
+		numeric_code  TEXT NOT NULL,
+		name          TEXT NOT NULL,
+		official_name TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_swift_codes_country ON swift_codes (country_iso2);`,
+		postgres: `
+	CREATE TABLE countries (
+		iso2          TEXT PRIMARY KEY,
+		iso3          TEXT NOT NULL UNIQUE,
+		numeric_code  TEXT NOT NULL,
+		name          TEXT NOT NULL,
+		official_name TEXT NOT NULL
+	);`,
+		seed: seedCountries,
+	},
+	{
+		// institutions holds each bank once, keyed by the first four letters
+		// of its codes. Triggers add banks as their codes are written, whichever
+		// code path writes them; a head office's name replaces a branch's.
+		name: "create institutions",
+		sqlite: `
+	CREATE TABLE institutions (
+		bank_code TEXT PRIMARY KEY,
+		name      TEXT NOT NULL
+	);
+	INSERT INTO institutions (bank_code, name)
+	SELECT upper(substr(swift_code, 1, 4)),
+	       COALESCE(MAX(CASE WHEN is_headquarter THEN name END), MAX(name), '')
+	  FROM swift_codes
+	 GROUP BY upper(substr(swift_code, 1, 4));
+	CREATE TRIGGER swift_codes_institution_created AFTER INSERT ON swift_codes BEGIN
+		INSERT INTO institutions (bank_code, name)
+		VALUES (upper(substr(NEW.swift_code, 1, 4)), COALESCE(NEW.name, ''))
+		ON CONFLICT (bank_code) DO UPDATE SET name = excluded.name WHERE NEW.is_headquarter;
+	END;
+	CREATE TRIGGER swift_codes_institution_updated AFTER UPDATE OF swift_code, name, is_headquarter ON swift_codes BEGIN
+		INSERT INTO institutions (bank_code, name)
+		VALUES (upper(substr(NEW.swift_code, 1, 4)), COALESCE(NEW.name, ''))
+		ON CONFLICT (bank_code) DO UPDATE SET name = excluded.name WHERE NEW.is_headquarter;
+	END;
+	CREATE INDEX idx_swift_codes_bank ON swift_codes (upper(substr(swift_code, 1, 4)));`,
+		postgres: `
+	CREATE TABLE institutions (
+		bank_code TEXT PRIMARY KEY,
+		name      TEXT NOT NULL
+	);
+	INSERT INTO institutions (bank_code, name)
+	SELECT upper(substr(swift_code, 1, 4)),
+	       COALESCE(MAX(CASE WHEN is_headquarter THEN name END), MAX(name), '')
+	  FROM swift_codes
+	 GROUP BY upper(substr(swift_code, 1, 4));
+	CREATE OR REPLACE FUNCTION record_institution() RETURNS trigger AS $$
+	BEGIN
+		INSERT INTO institutions (bank_code, name)
+		VALUES (upper(substr(NEW.swift_code, 1, 4)), COALESCE(NEW.name, ''))
+		ON CONFLICT (bank_code) DO UPDATE SET name = excluded.name WHERE NEW.is_headquarter;
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+	CREATE TRIGGER swift_codes_institution AFTER INSERT OR UPDATE OF swift_code, name, is_headquarter ON swift_codes
+		FOR EACH ROW EXECUTE FUNCTION record_institution();
+	CREATE INDEX idx_swift_codes_bank ON swift_codes (upper(substr(swift_code, 1, 4)));`,
+	},
+	{
+		// time zones used to be stored as the vendor file spelled them
+		name: "normalize time zones",
+		seed: normalizeTimeZones,
+	},
+	{
+		// Readers page through the change log with sequence > cursor, which
+		// needs sequences to become visible in order. An IDENTITY value is
+		// taken when a row is written, not when it commits, so a transaction
+		// that writes later but commits sooner would let readers skip past
+		// rows still in flight. Writers of the log now queue on a transaction
+		// advisory lock, held until commit. SQLite only ever has one writer.
+		name: "serialize change log writers",
+		postgres: `
+	CREATE OR REPLACE FUNCTION record_swift_code_change() RETURNS trigger AS $$
+	BEGIN
+		PERFORM pg_advisory_xact_lock(` + changeLogLock + `);
+		IF TG_OP = 'DELETE' THEN
+			INSERT INTO swift_code_changes (
+				change_type, country_iso2, swift_code, code_type, name, address,
+				town_name, country_name, time_zone, is_headquarter, hq_swift_code
+			) VALUES (
+				'deleted', OLD.country_iso2, OLD.swift_code, OLD.code_type, OLD.name, OLD.address,
+				OLD.town_name, OLD.country_name, OLD.time_zone, OLD.is_headquarter, OLD.hq_swift_code
+			);
+			RETURN OLD;
+		END IF;
+		INSERT INTO swift_code_changes (
+			change_type, country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone, is_headquarter, hq_swift_code
+		) VALUES (
+			CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END,
+			NEW.country_iso2, NEW.swift_code, NEW.code_type, NEW.name, NEW.address,
+			NEW.town_name, NEW.country_name, NEW.time_zone, NEW.is_headquarter, NEW.hq_swift_code
+		);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;`,
+	},
+	{
+		// An institution goes once its last code is deleted or moved to
+		// another bank code, and a deleted head office hands the bank's name
+		// to a remaining head office, or else a branch.
+		name: "forget institutions without codes",
+		sqlite: `
+	DELETE FROM institutions
+	 WHERE NOT EXISTS (SELECT 1 FROM swift_codes WHERE upper(substr(swift_code, 1, 4)) = institutions.bank_code);
+	CREATE TRIGGER swift_codes_institution_deleted AFTER DELETE ON swift_codes BEGIN
+		` + forgetInstitutionSQLite + `
+	END;
+	CREATE TRIGGER swift_codes_institution_moved AFTER UPDATE OF swift_code ON swift_codes
+		WHEN upper(substr(OLD.swift_code, 1, 4)) <> upper(substr(NEW.swift_code, 1, 4)) BEGIN
+		` + forgetInstitutionSQLite + `
+	END;`,
+		postgres: `
+	DELETE FROM institutions
+	 WHERE NOT EXISTS (SELECT 1 FROM swift_codes WHERE upper(substr(swift_code, 1, 4)) = institutions.bank_code);
+	CREATE OR REPLACE FUNCTION forget_institution() RETURNS trigger AS $$
+	BEGIN
+		DELETE FROM institutions
+		 WHERE bank_code = upper(substr(OLD.swift_code, 1, 4))
+		   AND NOT EXISTS (SELECT 1 FROM swift_codes WHERE upper(substr(swift_code, 1, 4)) = upper(substr(OLD.swift_code, 1, 4)));
+		IF OLD.is_headquarter THEN
+			UPDATE institutions SET name = (` + institutionNameSQL + `)
+			 WHERE bank_code = upper(substr(OLD.swift_code, 1, 4));
+		END IF;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+	CREATE TRIGGER swift_codes_institution_deleted AFTER DELETE ON swift_codes
+		FOR EACH ROW EXECUTE FUNCTION forget_institution();
+	CREATE TRIGGER swift_codes_institution_moved AFTER UPDATE OF swift_code ON swift_codes
+		FOR EACH ROW WHEN (upper(substr(OLD.swift_code, 1, 4)) IS DISTINCT FROM upper(substr(NEW.swift_code, 1, 4)))
+		EXECUTE FUNCTION forget_institution();`,
+	},
+	{
+		// a delivery waits for the earlier deliveries of its subscription
+		name: "index webhook deliveries by subscription",
+		sqlite: `
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, status, id);`,
+		postgres: `
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, status, id);`,
+	},
+}
+
+// institutionNameSQL names an institution after its codes: a head office's
+// name when it has one, otherwise a branch's.
+const institutionNameSQL = `
+	SELECT COALESCE(MAX(CASE WHEN is_headquarter THEN name END), MAX(name), '')
+	  FROM swift_codes WHERE upper(substr(swift_code, 1, 4)) = institutions.bank_code`
+
+// forgetInstitutionSQLite is the body of the SQLite triggers that clean up
+// after OLD leaves its institution.
+const forgetInstitutionSQLite = `DELETE FROM institutions
+		 WHERE bank_code = upper(substr(OLD.swift_code, 1, 4))
+		   AND NOT EXISTS (SELECT 1 FROM swift_codes WHERE upper(substr(swift_code, 1, 4)) = upper(substr(OLD.swift_code, 1, 4)));
+		UPDATE institutions SET name = (` + institutionNameSQL + `)
+		 WHERE OLD.is_headquarter AND bank_code = upper(substr(OLD.swift_code, 1, 4));`
+
+// PostgreSQL advisory lock keys. migrationLock is held by a connection
+// applying migrations; changeLogLock by a transaction writing the change log.
+const (
+	migrationLock = "727310001"
+	changeLogLock = "727310002"
+)
+
+// seedCountries loads the embedded ISO 3166-1 list into countries.
+func seedCountries(tx *sql.Tx, dialect Dialect) error {
+	stmt, err := tx.Prepare(dialect.Rebind(`
+	INSERT INTO countries (iso2, iso3, numeric_code, name, official_name)
+	VALUES (?, ?, ?, ?, ?);`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, country := range countries.All() {
+		if _, err := stmt.Exec(country.ISO2, country.ISO3, country.Numeric, country.Name, country.OfficialName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeTimeZones rewrites stored time zones to their IANA names. Zones
+// that cannot be mapped are left for an operator to fix; only new writes
+// reject them. The rewrite is a spelling fix rather than a change to the
+// directory, so the change log entries its UPDATEs trigger are removed again;
+// left in, every webhook subscriber would be sent an event per code.
+func normalizeTimeZones(tx *sql.Tx, dialect Dialect) error {
+	if dialect == Postgres {
+		// keeps other writers' entries out of the range deleted below
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(` + changeLogLock + `);`); err != nil {
+			return err
+		}
+	}
+	var logged int64
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(sequence), 0) FROM swift_code_changes;`).Scan(&logged); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT DISTINCT time_zone FROM swift_codes WHERE time_zone IS NOT NULL;`)
+	if err != nil {
+		return err
+	}
+	renamed := make(map[string]string)
+	for rows.Next() {
+		var stored string
+		if err := rows.Scan(&stored); err != nil {
+			rows.Close()
+			return err
+		}
+		if normalized, err := timezones.Normalize(stored); err == nil && normalized != stored {
+			renamed[stored] = normalized
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for stored, normalized := range renamed {
+		if _, err := tx.Exec(dialect.Rebind(`UPDATE swift_codes SET time_zone = ? WHERE time_zone = ?;`), normalized, stored); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(dialect.Rebind(`DELETE FROM swift_code_changes WHERE sequence > ?;`), logged)
+	return err
+}
+
+// migrate applies every migration the database has not recorded yet, each in
+// its own transaction. On PostgreSQL every transaction first takes
+// migrationLock, so servers started together against one database wait for
+// each other instead of applying the same step twice.
+func migrate(database *sql.DB, dialect Dialect) error {
+	if err := createSchemaMigrations(database, dialect); err != nil {
+		return fmt.Errorf("error creating schema_migrations table %w", err)
+	}
+
+	applied, err := appliedMigrations(database)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range migrations {
+		if applied[step.name] {
+			continue
+		}
+		if err := applyMigration(database, dialect, step); err != nil {
+			return fmt.Errorf("error applying migration %q %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+func createSchemaMigrations(database *sql.DB, dialect Dialect) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := lockMigrations(tx, dialect); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		name       TEXT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockMigrations makes tx wait until no other connection is migrating. SQLite
+// needs no lock, as it runs one write transaction at a time.
+func lockMigrations(tx *sql.Tx, dialect Dialect) error {
+	if dialect != Postgres {
+		return nil
+	}
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(` + migrationLock + `);`)
+	return err
+}
+
+// checkMigrated fails unless every migration has been applied, for
+// connections that may not apply them themselves.
+func checkMigrated(database *sql.DB) error {
+	applied, err := appliedMigrations(database)
+	if err != nil {
+		return err
+	}
+	for _, step := range migrations {
+		if !applied[step.name] {
+			return fmt.Errorf("migration %q has not been applied; open the database read-write once to migrate it", step.name)
+		}
+	}
+	return nil
+}
+
+// UnknownMigrations lists the migrations recorded in database that this build
+// does not have, as in a database last opened by a newer version.
+func UnknownMigrations(database *DB) ([]string, error) {
+	applied, err := appliedMigrations(database.DB)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range migrations {
+		delete(applied, step.name)
+	}
+	var unknown []string
+	for name := range applied {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func appliedMigrations(database *sql.DB) (map[string]bool, error) {
+	applied := make(map[string]bool)
+	rows, err := database.Query(`SELECT name FROM schema_migrations;`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		applied[name] = true
+	}
+	return applied, rows.Err()
+}
+
+func applyMigration(database *sql.DB, dialect Dialect, step migration) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := lockMigrations(tx, dialect); err != nil {
+		return err
+	}
+	// another server may have applied the step while this one waited
+	var alreadyApplied int
+	if err := tx.QueryRow(dialect.Rebind(`SELECT COUNT(*) FROM schema_migrations WHERE name = ?;`), step.name).Scan(&alreadyApplied); err != nil {
+		return err
+	}
+	if alreadyApplied > 0 {
+		return nil
+	}
+	if query := step.sql(dialect); query != "" {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	if step.seed != nil {
+		if err := step.seed(tx, dialect); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(dialect.Rebind(`INSERT INTO schema_migrations (name) VALUES (?);`), step.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
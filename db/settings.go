@@ -0,0 +1,169 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings tunes connections and the pool. Zero fields take the value
+// DefaultSettings gives them; the SQLite pragmas are ignored by PostgreSQL.
+type Settings struct {
+	// JournalMode is SQLite's journal_mode. WAL lets readers carry on while
+	// a writer commits.
+	JournalMode string
+	// BusyTimeout is how long SQLite waits for a lock before failing.
+	BusyTimeout time.Duration
+	// Synchronous is SQLite's synchronous level; NORMAL is durable in WAL
+	// mode except against power loss.
+	Synchronous string
+	// MaxOpenConns caps the pool; negative means no cap. Writable SQLite
+	// pools always hold one connection.
+	MaxOpenConns int
+	// MaxIdleConns is how many connections, and the statements prepared on
+	// them, are kept between requests.
+	MaxIdleConns int
+	// ConnMaxLifetime recycles connections; negative means never.
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultSettings is what Open and OpenReadOnly use.
+var DefaultSettings = Settings{
+	JournalMode:     "WAL",
+	BusyTimeout:     5 * time.Second,
+	Synchronous:     "NORMAL",
+	MaxOpenConns:    -1,
+	MaxIdleConns:    16,
+	ConnMaxLifetime: -1,
+}
+
+// Environment variables SettingsFromEnv reads.
+const (
+	JournalModeEnv     = "SWIFT_DB_JOURNAL_MODE"
+	BusyTimeoutEnv     = "SWIFT_DB_BUSY_TIMEOUT"
+	SynchronousEnv     = "SWIFT_DB_SYNCHRONOUS"
+	MaxOpenConnsEnv    = "SWIFT_DB_MAX_OPEN_CONNS"
+	MaxIdleConnsEnv    = "SWIFT_DB_MAX_IDLE_CONNS"
+	ConnMaxLifetimeEnv = "SWIFT_DB_CONN_MAX_LIFETIME"
+)
+
+// SettingsFromEnv returns DefaultSettings overridden by whichever of the
+// SWIFT_DB_* tuning variables are set. Durations use Go syntax, e.g. 5s.
+func SettingsFromEnv() (Settings, error) {
+	settings := DefaultSettings
+	settings.JournalMode = envOr(JournalModeEnv, settings.JournalMode)
+	settings.Synchronous = envOr(SynchronousEnv, settings.Synchronous)
+	for name, target := range map[string]*time.Duration{
+		BusyTimeoutEnv:     &settings.BusyTimeout,
+		ConnMaxLifetimeEnv: &settings.ConnMaxLifetime,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return Settings{}, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+	for name, target := range map[string]*int{
+		MaxOpenConnsEnv: &settings.MaxOpenConns,
+		MaxIdleConnsEnv: &settings.MaxIdleConns,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return Settings{}, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+	return settings, nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Open connects to dsn with the dialect's driver and migrates the schema.
+// A SQLite pool is held to one connection whatever MaxOpenConns says: SQLite
+// runs one writer at a time, and a second connection would only wait on the
+// file lock until BusyTimeout fails it. Concurrent SQLite reads belong on an
+// OpenReadOnly pool.
+func (settings Settings) Open(dialect Dialect, dsn string) (*DB, error) {
+	if dialect == SQLite {
+		settings.MaxOpenConns = 1
+	}
+	return settings.open(dialect, settings.dsn(dialect, dsn, true), func(database *DB) error {
+		return migrate(database.DB, dialect)
+	})
+}
+
+// OpenReadOnly connects to dsn without ever writing: SQLite opens the file
+// with mode=ro and PostgreSQL sessions default to read-only transactions.
+// The schema must already be fully migrated. The journal mode is left as the
+// file has it.
+func (settings Settings) OpenReadOnly(dialect Dialect, dsn string) (*DB, error) {
+	readOnlyDSN := settings.dsn(dialect, dialect.ReadOnlyDSN(dsn), false)
+	return settings.open(dialect, readOnlyDSN, func(database *DB) error {
+		return checkMigrated(database.DB)
+	})
+}
+
+// dsn adds the SQLite pragmas as go-sqlite3 connection parameters, unless
+// the DSN already sets them.
+func (settings Settings) dsn(dialect Dialect, dsn string, writable bool) string {
+	if dialect != SQLite {
+		return dsn
+	}
+	settings = settings.withDefaults()
+	parameters := []string{
+		"_busy_timeout=" + strconv.FormatInt(settings.BusyTimeout.Milliseconds(), 10),
+		"_synchronous=" + url.QueryEscape(settings.Synchronous),
+	}
+	if writable {
+		parameters = append(parameters, "_journal_mode="+url.QueryEscape(settings.JournalMode))
+	}
+	for _, parameter := range parameters {
+		key, _, _ := strings.Cut(parameter, "=")
+		if !strings.Contains(dsn, "?"+key+"=") && !strings.Contains(dsn, "&"+key+"=") {
+			dsn = withQueryParameter(dsn, parameter)
+		}
+	}
+	return dsn
+}
+
+func (settings Settings) withDefaults() Settings {
+	if settings.JournalMode == "" {
+		settings.JournalMode = DefaultSettings.JournalMode
+	}
+	if settings.BusyTimeout == 0 {
+		settings.BusyTimeout = DefaultSettings.BusyTimeout
+	}
+	if settings.Synchronous == "" {
+		settings.Synchronous = DefaultSettings.Synchronous
+	}
+	if settings.MaxOpenConns == 0 {
+		settings.MaxOpenConns = DefaultSettings.MaxOpenConns
+	}
+	if settings.MaxIdleConns == 0 {
+		settings.MaxIdleConns = DefaultSettings.MaxIdleConns
+	}
+	if settings.ConnMaxLifetime == 0 {
+		settings.ConnMaxLifetime = DefaultSettings.ConnMaxLifetime
+	}
+	return settings
+}
+
+// applyPool sets the pool limits; database/sql reads 0 as "no limit".
+func (settings Settings) applyPool(database *DB) {
+	settings = settings.withDefaults()
+	database.SetMaxOpenConns(max(settings.MaxOpenConns, 0))
+	database.SetMaxIdleConns(max(settings.MaxIdleConns, 0))
+	database.SetConnMaxLifetime(max(settings.ConnMaxLifetime, 0))
+}
@@ -0,0 +1,252 @@
+package dbsnapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/db/dbtest"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// newWriter returns a database with one swift code and a publisher for it.
+func newWriter(t *testing.T) (*service.SwiftRepository, *Publisher, ed25519.PublicKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := dbtest.OpenSQLite(t)
+	repo := &service.SwiftRepository{DB: database}
+	createCode(t, repo, "ZZBANKZZXXX")
+	return repo, &Publisher{DB: database, Dir: t.TempDir(), Key: privateKey}, publicKey
+}
+
+func createCode(t *testing.T, repo *service.SwiftRepository, code string) {
+	t.Helper()
+	err := repo.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: code, CodeType: "BIC11",
+		Name: "ZELAND NATIONAL BANK", CountryName: "ZELAND", IsHeadquarter: code[8:] == "XXX"})
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", code, err)
+	}
+}
+
+// installer records what a replica installs and hands back the previous one.
+type installer struct{ installed []*db.DB }
+
+func (recorder *installer) install(next *db.DB) *db.DB {
+	recorder.installed = append(recorder.installed, next)
+	if len(recorder.installed) < 2 {
+		return nil
+	}
+	return recorder.installed[len(recorder.installed)-2]
+}
+
+func countRows(t *testing.T, database *db.DB) int {
+	t.Helper()
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM swift_codes;`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	return count
+}
+
+func TestReplicaInstallsNewSnapshots(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher, publicKey := newWriter(t)
+	first, err := publisher.Publish(ctx)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if first.RowCount != 1 || first.ChangeSequence != 1 || len(first.SHA256) != 64 {
+		t.Errorf("Unexpected manifest %+v", first)
+	}
+
+	recorder := &installer{}
+	replica := &Replica{Source: DirSource(publisher.Dir), PublicKey: publicKey, Dir: t.TempDir(),
+		Install: recorder.install, RetireAfter: 1}
+	if installed, err := replica.Sync(ctx); err != nil || !installed {
+		t.Fatalf("Expected the first snapshot to be installed, got %v %v", installed, err)
+	}
+	if installed, err := replica.Sync(ctx); err != nil || installed {
+		t.Fatalf("Expected nothing new to install, got %v %v", installed, err)
+	}
+
+	createCode(t, repo, "ZZBANKZZ001")
+	second, err := publisher.Publish(ctx)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if installed, err := replica.Sync(ctx); err != nil || !installed {
+		t.Fatalf("Expected the second snapshot to be installed, got %v %v", installed, err)
+	}
+	if len(recorder.installed) != 2 || countRows(t, recorder.installed[1]) != 2 {
+		t.Fatalf("Expected the second snapshot with 2 rows to be serving, got %d installs", len(recorder.installed))
+	}
+	if replica.Current().SHA256 != second.SHA256 {
+		t.Errorf("Expected the replica to report the second manifest")
+	}
+	t.Cleanup(func() { recorder.installed[1].Close() })
+}
+
+func TestReplicaRejectsUntrustedSnapshots(t *testing.T) {
+	ctx := context.Background()
+	_, publisher, publicKey := newWriter(t)
+	manifest, err := publisher.Publish(ctx)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	recorder := &installer{}
+
+	strangerReplica := &Replica{Source: DirSource(publisher.Dir), PublicKey: otherKey, Dir: t.TempDir(), Install: recorder.install}
+	if _, err := strangerReplica.Sync(ctx); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for another key, got %v", err)
+	}
+
+	snapshotPath := filepath.Join(publisher.Dir, manifest.File)
+	contents, _ := os.ReadFile(snapshotPath)
+	contents[len(contents)-1] ^= 0xff
+	os.WriteFile(snapshotPath, contents, 0o644)
+	replica := &Replica{Source: DirSource(publisher.Dir), PublicKey: publicKey, Dir: t.TempDir(), Install: recorder.install}
+	if _, err := replica.Sync(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch for a modified file, got %v", err)
+	}
+	if entries, _ := os.ReadDir(replica.Dir); len(entries) != 0 {
+		t.Errorf("Expected the rejected download to be removed, found %d files", len(entries))
+	}
+
+	manifest.File = "../" + manifest.File
+	manifest.Sign(publisher.Key)
+	if err := manifest.Verify(publicKey); err == nil {
+		t.Errorf("Expected a manifest naming a path outside its directory to be rejected")
+	}
+	if len(recorder.installed) != 0 {
+		t.Errorf("Expected nothing to be installed, got %d", len(recorder.installed))
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	ctx := context.Background()
+	_, publisher, publicKey := newWriter(t)
+	if _, err := publisher.Publish(ctx); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	files := http.StripPrefix("/snapshots/", http.FileServer(http.Dir(publisher.Dir)))
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer replica-token" {
+			http.Error(responseWriter, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(responseWriter, request)
+	}))
+	defer server.Close()
+
+	recorder := &installer{}
+	anonymous := &Replica{Source: NewSource(server.URL+"/snapshots/", ""), PublicKey: publicKey, Dir: t.TempDir(), Install: recorder.install}
+	if _, err := anonymous.Sync(ctx); err == nil {
+		t.Fatalf("Expected a replica without the token to be refused")
+	}
+	replica := &Replica{Source: NewSource(server.URL+"/snapshots/", "replica-token"), PublicKey: publicKey, Dir: t.TempDir(), Install: recorder.install}
+	if installed, err := replica.Sync(ctx); err != nil || !installed {
+		t.Fatalf("Expected the snapshot to be installed over HTTP, got %v %v", installed, err)
+	}
+	defer recorder.installed[0].Close()
+	if countRows(t, recorder.installed[0]) != 1 {
+		t.Errorf("Expected the installed snapshot to hold 1 row")
+	}
+}
+
+func TestPublisherSkipsUnchangedDatabase(t *testing.T) {
+	ctx := context.Background()
+	repo, publisher, _ := newWriter(t)
+	publisher.Keep = 1
+	if err := publisher.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	first := *publisher.last
+
+	// a new publisher finds the manifest on disk, as after a restart
+	restarted := &Publisher{DB: publisher.DB, Dir: publisher.Dir, Key: publisher.Key, Keep: 1}
+	if err := restarted.RunOnce(ctx); err != nil || restarted.last.File != first.File {
+		t.Fatalf("Expected no new snapshot for an unchanged database, got %v", err)
+	}
+
+	createCode(t, repo, "ZZBANKZZ001")
+	if err := restarted.RunOnce(ctx); err != nil || restarted.last.File == first.File {
+		t.Fatalf("Expected a new snapshot after a change, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(publisher.Dir, first.File)); !os.IsNotExist(err) {
+		t.Errorf("Expected the old snapshot to be pruned, got %v", err)
+	}
+}
+
+func TestKeyFilesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "snapshot.key"), filepath.Join(dir, "snapshot.pub")
+	if err := GenerateKeyFiles(privatePath, publicPath); err != nil {
+		t.Fatalf("GenerateKeyFiles failed: %v", err)
+	}
+	privateKey, err := ReadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatalf("ReadPrivateKey failed: %v", err)
+	}
+	publicKey, err := ReadPublicKey(publicPath)
+	if err != nil {
+		t.Fatalf("ReadPublicKey failed: %v", err)
+	}
+	manifest := Manifest{File: "swift_codes-1.db", RowCount: 3}
+	manifest.Sign(privateKey)
+	if err := manifest.Verify(publicKey); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+	if err := GenerateKeyFiles(privatePath, publicPath); err == nil {
+		t.Errorf("Expected existing key files not to be overwritten")
+	}
+}
+
+// TestSnapshotsLeaveWebhookSecretsBehind publishes a writer with a webhook
+// subscription; neither the row nor its secret may reach the snapshot file.
+func TestSnapshotsLeaveWebhookSecretsBehind(t *testing.T) {
+	repo, publisher, _ := newWriter(t)
+	const secret = "whsec-do-not-publish"
+	if _, err := repo.CreateWebhookSubscription(models.WebhookSubscription{
+		URL: "https://example.com/hook", Secret: secret, EventTypes: []string{"swift_code.created"},
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	manifest, err := publisher.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	path := filepath.Join(publisher.Dir, manifest.File)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(contents, []byte(secret)) {
+		t.Errorf("Snapshot file still contains the webhook secret")
+	}
+	snapshot, err := db.OpenReadOnly(db.SQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	var subscriptions int
+	snapshot.QueryRow(`SELECT COUNT(*) FROM webhook_subscriptions;`).Scan(&subscriptions)
+	if subscriptions != 0 || countRows(t, snapshot) != 1 {
+		t.Errorf("Expected no subscriptions and the one swift code, got %d subscriptions", subscriptions)
+	}
+	if manifest.Verify(publisher.Key.Public().(ed25519.PublicKey)) != nil || manifest.SHA256 == "" {
+		t.Errorf("Expected a signed manifest of the scrubbed file")
+	}
+}
@@ -0,0 +1,138 @@
+// Package dbsnapshot ships the SQLite database to read-only replicas. The
+// writer publishes consistent copies, each described by a manifest signed with
+// Ed25519; replicas poll for the manifest, verify the copy it names and swap it
+// in while they keep serving.
+package dbsnapshot
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestName is the file, or URL path element, the current manifest is
+// published under, next to the snapshots it describes.
+const ManifestName = "manifest.json"
+
+// Errors returned when a snapshot cannot be trusted.
+var (
+	ErrBadSignature     = errors.New("snapshot manifest signature is invalid")
+	ErrChecksumMismatch = errors.New("snapshot does not match its manifest")
+)
+
+// Manifest describes one published snapshot.
+type Manifest struct {
+	// File is the snapshot's name, relative to the manifest.
+	File string `json:"file"`
+	// DatasetVersion and ChangeSequence are the newest dataset version and
+	// change log entry in the snapshot, 0 when there are none.
+	DatasetVersion int64     `json:"datasetVersion"`
+	ChangeSequence int64     `json:"changeSequence"`
+	RowCount       int64     `json:"rowCount"`
+	SHA256         string    `json:"sha256"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// Signature is the Ed25519 signature of every other field, encoded as
+	// the manifest's JSON with an empty signature.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+func (manifest Manifest) signedBytes() []byte {
+	manifest.Signature = nil
+	encoded, _ := json.Marshal(manifest)
+	return encoded
+}
+
+// Sign sets the signature for key.
+func (manifest *Manifest) Sign(key ed25519.PrivateKey) {
+	manifest.Signature = ed25519.Sign(key, manifest.signedBytes())
+}
+
+// Verify checks the signature against key, and that File is a plain name
+// that cannot point outside the snapshot directory.
+func (manifest Manifest) Verify(key ed25519.PublicKey) error {
+	if !ed25519.Verify(key, manifest.signedBytes(), manifest.Signature) {
+		return ErrBadSignature
+	}
+	if manifest.File == "" || manifest.File != filepath.Base(manifest.File) || strings.ContainsAny(manifest.File, `/\`) ||
+		manifest.File == "." || manifest.File == ".." {
+		return fmt.Errorf("snapshot manifest names an invalid file %q", manifest.File)
+	}
+	return nil
+}
+
+// GenerateKeyFiles creates a signing key pair. The private key is written to
+// privatePath, readable by the owner only, and the public key to publicPath;
+// both hold the base64 of the raw key.
+func GenerateKeyFiles(privatePath, publicPath string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyFile(privatePath, privateKey.Seed(), 0o600); err != nil {
+		return err
+	}
+	return writeKeyFile(publicPath, publicKey, 0o644)
+}
+
+func writeKeyFile(path string, key []byte, permissions os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, permissions)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, base64.StdEncoding.EncodeToString(key)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadPrivateKey reads a private key written by GenerateKeyFiles.
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	seed, err := readKeyFile(path, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ReadPublicKey reads a public key written by GenerateKeyFiles.
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	key, err := readKeyFile(path, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+func readKeyFile(path string, size int) ([]byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("key file %s: expected %d bytes, got %d", path, size, len(key))
+	}
+	return key, nil
+}
+
+// Environment variables the server reads. A writer publishes into DirEnv,
+// signing with the private key file in KeyEnv; a replica polls SourceEnv,
+// authenticating with SourceTokenEnv, checks signatures with the public key
+// file in PublicKeyEnv and keeps its copies in DirEnv.
+const (
+	DirEnv         = "SWIFT_SNAPSHOT_DIR"
+	KeyEnv         = "SWIFT_SNAPSHOT_KEY"
+	SourceEnv      = "SWIFT_SNAPSHOT_SOURCE"
+	SourceTokenEnv = "SWIFT_SNAPSHOT_SOURCE_TOKEN"
+	PublicKeyEnv   = "SWIFT_SNAPSHOT_PUBLIC_KEY"
+)
@@ -0,0 +1,255 @@
+package dbsnapshot
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"swift-codes-project/db"
+)
+
+const (
+	snapshotPrefix = "swift_codes-"
+	snapshotSuffix = ".db"
+)
+
+// Publisher writes snapshots of a SQLite database into Dir.
+type Publisher struct {
+	DB  *db.DB
+	Dir string
+	Key ed25519.PrivateKey
+	// Keep is how many snapshots stay in Dir, the newest included, so replicas
+	// still downloading the previous one can finish; 2 when zero.
+	Keep int
+	// PollInterval is how often Run checks for changes; a minute when zero.
+	PollInterval time.Duration
+
+	last *Manifest
+}
+
+// Publish copies the database with VACUUM INTO, which sees one consistent
+// state even while writes continue, scrubs the copy and then replaces the
+// manifest. Replicas never see a manifest for a snapshot that is not complete.
+func (publisher *Publisher) Publish(ctx context.Context) (Manifest, error) {
+	if publisher.DB.Dialect != db.SQLite {
+		return Manifest{}, fmt.Errorf("snapshots need SQLite, not %s", publisher.DB.Dialect.Name)
+	}
+	if err := os.MkdirAll(publisher.Dir, 0o755); err != nil {
+		return Manifest{}, err
+	}
+	createdAt := time.Now().UTC()
+	name := snapshotPrefix + createdAt.Format("20060102T150405.000000000Z") + snapshotSuffix
+	path := filepath.Join(publisher.Dir, name)
+	if _, err := publisher.DB.ExecContext(ctx, `VACUUM INTO ?;`, path); err != nil {
+		return Manifest{}, fmt.Errorf("error copying database %w", err)
+	}
+	if err := scrub(ctx, path); err != nil {
+		os.Remove(path)
+		return Manifest{}, fmt.Errorf("error scrubbing snapshot %w", err)
+	}
+
+	manifest, err := describe(ctx, path)
+	if err != nil {
+		os.Remove(path)
+		return Manifest{}, err
+	}
+	manifest.File = name
+	manifest.CreatedAt = createdAt
+	manifest.Sign(publisher.Key)
+	if err := writeManifest(publisher.Dir, manifest); err != nil {
+		os.Remove(path)
+		return Manifest{}, err
+	}
+	publisher.last = &manifest
+	return manifest, publisher.prune(name)
+}
+
+// Run publishes a snapshot whenever the change log or dataset history has
+// moved since the last one, until ctx is done.
+func (publisher *Publisher) Run(ctx context.Context) {
+	interval := publisher.PollInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := publisher.RunOnce(ctx); err != nil {
+			log.Printf("Snapshot publishing failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes a snapshot unless the latest one, in memory or in Dir,
+// already holds the database's newest change and dataset version.
+func (publisher *Publisher) RunOnce(ctx context.Context) error {
+	if publisher.last == nil {
+		if existing, err := readManifestFile(filepath.Join(publisher.Dir, ManifestName)); err == nil &&
+			existing.Verify(publisher.Key.Public().(ed25519.PublicKey)) == nil {
+			publisher.last = &existing
+		}
+	}
+	if publisher.last != nil {
+		current, err := inspect(ctx, publisher.DB)
+		if err != nil {
+			return err
+		}
+		if current.ChangeSequence == publisher.last.ChangeSequence && current.DatasetVersion == publisher.last.DatasetVersion {
+			return nil
+		}
+	}
+	manifest, err := publisher.Publish(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Published snapshot %s (%d rows, change %d)", manifest.File, manifest.RowCount, manifest.ChangeSequence)
+	return nil
+}
+
+// prune removes all but the newest Keep snapshots; names sort by creation time.
+func (publisher *Publisher) prune(newest string) error {
+	keep := publisher.Keep
+	if keep <= 0 {
+		keep = 2
+	}
+	entries, err := os.ReadDir(publisher.Dir)
+	if err != nil {
+		return err
+	}
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix) && name <= newest {
+			snapshots = append(snapshots, name)
+		}
+	}
+	sort.Strings(snapshots)
+	var errs []error
+	for _, name := range snapshots[:max(len(snapshots)-keep, 0)] {
+		errs = append(errs, os.Remove(filepath.Join(publisher.Dir, name)))
+	}
+	return errors.Join(errs...)
+}
+
+// privateTables hold webhook subscriber secrets and signed payloads, which
+// must not leave the writer. Children come before the table they reference.
+var privateTables = []string{"webhook_deliveries", "webhook_cursors", "webhook_subscriptions"}
+
+// scrub empties privateTables in a snapshot copy and vacuums it, so the
+// deleted rows do not linger in free pages of the published file.
+func scrub(ctx context.Context, path string) error {
+	snapshot, err := db.Settings{JournalMode: "DELETE"}.Open(db.SQLite, path)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	for _, table := range privateTables {
+		if _, err := snapshot.ExecContext(ctx, `DELETE FROM `+table+`;`); err != nil {
+			return err
+		}
+	}
+	_, err = snapshot.ExecContext(ctx, `VACUUM;`)
+	return err
+}
+
+// describe opens a snapshot file read-only and fills in everything a manifest
+// says about its contents.
+func describe(ctx context.Context, path string) (Manifest, error) {
+	checksum, err := fileSHA256(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	snapshot, err := db.OpenReadOnly(db.SQLite, path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer snapshot.Close()
+	manifest, err := inspect(ctx, snapshot)
+	manifest.SHA256 = checksum
+	return manifest, err
+}
+
+// inspect reads the row count, newest dataset version and newest change.
+func inspect(ctx context.Context, database *db.DB) (Manifest, error) {
+	var manifest Manifest
+	err := database.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM swift_codes),
+		       (SELECT COALESCE(MAX(version), 0) FROM dataset_versions),
+		       (SELECT COALESCE(MAX(sequence), 0) FROM swift_code_changes);`,
+	).Scan(&manifest.RowCount, &manifest.DatasetVersion, &manifest.ChangeSequence)
+	return manifest, err
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeManifest replaces the manifest atomically, through a rename.
+func writeManifest(dir string, manifest Manifest) error {
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(dir, ManifestName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(encoded); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Sync(); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temporary.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), filepath.Join(dir, ManifestName))
+}
+
+func readManifestFile(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer file.Close()
+	return decodeManifest(file)
+}
+
+func decodeManifest(source io.Reader) (Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(source, 1<<20)).Decode(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("error decoding snapshot manifest %w", err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,175 @@
+package dbsnapshot
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"swift-codes-project/db"
+)
+
+// Replica keeps the newest published snapshot open for reading.
+type Replica struct {
+	Source    Source
+	PublicKey ed25519.PublicKey
+	// Dir holds the downloaded snapshots.
+	Dir string
+	// Install starts serving a verified snapshot, opened read-only, and returns
+	// the database it replaces, or nil for the first one.
+	Install func(*db.DB) *db.DB
+	// RetireAfter is how long a replaced database stays open for requests
+	// that already picked it; 30 seconds when zero.
+	RetireAfter time.Duration
+	// PollInterval is how often Run checks the source; 30 seconds when zero.
+	PollInterval time.Duration
+
+	mu      sync.Mutex
+	current Manifest
+}
+
+// Current returns the manifest of the installed snapshot, if any.
+func (replica *Replica) Current() Manifest {
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+	return replica.current
+}
+
+// Run syncs until ctx is done. Failures are logged, and the installed
+// snapshot keeps being served.
+func (replica *Replica) Run(ctx context.Context) {
+	interval := replica.PollInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		installed, err := replica.Sync(ctx)
+		if err != nil {
+			log.Printf("Snapshot sync failed: %v", err)
+		} else if installed {
+			log.Printf("Installed snapshot %s", replica.Current().File)
+		}
+	}
+}
+
+// Sync installs the source's snapshot if it is newer than the installed one
+// and reports whether it did. Nothing is installed unless the manifest
+// signature, the file checksum, the schema and the row count all check out.
+func (replica *Replica) Sync(ctx context.Context) (bool, error) {
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+
+	manifest, err := replica.Source.Manifest(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := manifest.Verify(replica.PublicKey); err != nil {
+		return false, err
+	}
+	if manifest.SHA256 == replica.current.SHA256 || manifest.CreatedAt.Before(replica.current.CreatedAt) {
+		return false, nil
+	}
+
+	path, err := replica.download(ctx, manifest)
+	if err != nil {
+		return false, err
+	}
+	snapshot, err := db.OpenReadOnly(db.SQLite, path)
+	if err != nil {
+		os.Remove(path)
+		return false, err
+	}
+	contents, err := inspect(ctx, snapshot)
+	if err == nil && contents.RowCount != manifest.RowCount {
+		err = fmt.Errorf("%w: %d rows, manifest says %d", ErrChecksumMismatch, contents.RowCount, manifest.RowCount)
+	}
+	if err != nil {
+		snapshot.Close()
+		os.Remove(path)
+		return false, err
+	}
+
+	previousFile := replica.current.File
+	previous := replica.Install(snapshot)
+	replica.current = manifest
+	if previousFile == "" {
+		replica.removeLeftovers(manifest.File)
+	}
+	if previous != nil {
+		retireAfter := replica.RetireAfter
+		if retireAfter <= 0 {
+			retireAfter = 30 * time.Second
+		}
+		time.AfterFunc(retireAfter, func() {
+			previous.Close()
+			if previousFile != "" && previousFile != manifest.File {
+				os.Remove(filepath.Join(replica.Dir, previousFile))
+			}
+		})
+	}
+	return true, nil
+}
+
+// removeLeftovers deletes snapshots downloaded before a restart.
+func (replica *Replica) removeLeftovers(installed string) {
+	entries, err := os.ReadDir(replica.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != installed && strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix) {
+			os.Remove(filepath.Join(replica.Dir, name))
+		}
+	}
+}
+
+// download copies the snapshot into Dir, checking its SHA-256 on the way,
+// and only gives it its final name once it matches.
+func (replica *Replica) download(ctx context.Context, manifest Manifest) (string, error) {
+	if err := os.MkdirAll(replica.Dir, 0o755); err != nil {
+		return "", err
+	}
+	body, err := replica.Source.Open(ctx, manifest.File)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	partial, err := os.CreateTemp(replica.Dir, manifest.File+".*.partial")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(partial.Name())
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(partial, hash), body); err != nil {
+		partial.Close()
+		return "", err
+	}
+	if err := partial.Close(); err != nil {
+		return "", err
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != manifest.SHA256 {
+		return "", fmt.Errorf("%w: sha256 %s, manifest says %s", ErrChecksumMismatch, checksum, manifest.SHA256)
+	}
+	path := filepath.Join(replica.Dir, manifest.File)
+	if err := os.Rename(partial.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
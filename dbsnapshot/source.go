@@ -0,0 +1,86 @@
+package dbsnapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is where a replica finds published snapshots.
+type Source interface {
+	// Manifest returns the current manifest, not yet verified.
+	Manifest(ctx context.Context) (Manifest, error)
+	// Open returns the contents of a snapshot named by a manifest.
+	Open(ctx context.Context, file string) (io.ReadCloser, error)
+}
+
+// NewSource returns an HTTPSource for http and https URLs, sending token, and
+// a DirSource for anything else.
+func NewSource(location, token string) Source {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return &HTTPSource{BaseURL: location, Token: token}
+	}
+	return DirSource(location)
+}
+
+// DirSource reads a publisher's directory, e.g. a shared volume.
+type DirSource string
+
+func (dir DirSource) Manifest(ctx context.Context) (Manifest, error) {
+	return readManifestFile(filepath.Join(string(dir), ManifestName))
+}
+
+func (dir DirSource) Open(ctx context.Context, file string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(dir), filepath.Base(file)))
+}
+
+// HTTPSource fetches from a server exposing a publisher's directory, such as
+// the writer's /snapshots/ path.
+type HTTPSource struct {
+	BaseURL string
+	// Token is sent as a bearer token, for writers that guard /snapshots/.
+	Token string
+	// Client sends the requests; http.DefaultClient when nil.
+	Client *http.Client
+}
+
+func (source *HTTPSource) Manifest(ctx context.Context) (Manifest, error) {
+	body, err := source.Open(ctx, ManifestName)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer body.Close()
+	return decodeManifest(body)
+}
+
+func (source *HTTPSource) Open(ctx context.Context, file string) (io.ReadCloser, error) {
+	target, err := url.JoinPath(source.BaseURL, url.PathEscape(file))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if source.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+source.Token)
+	}
+	client := source.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", target, response.Status)
+	}
+	return response.Body, nil
+}
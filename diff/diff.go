@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"swift-codes-project/models"
+)
+
+// FieldChange is a single field whose value differs between two datasets.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ModifiedCode lists the field changes of a code present in both datasets.
+type ModifiedCode struct {
+	SwiftCode string        `json:"swiftCode"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// Result is the difference between an old and a new dataset. Every list is
+// sorted by swift code.
+type Result struct {
+	Added    []models.SwiftCode `json:"added"`
+	Removed  []models.SwiftCode `json:"removed"`
+	Modified []ModifiedCode     `json:"modified"`
+}
+
+// Empty reports whether the two datasets were identical.
+func (result Result) Empty() bool {
+	return len(result.Added) == 0 && len(result.Removed) == 0 && len(result.Modified) == 0
+}
+
+// comparedFields lists every compared field by its JSON name.
+var comparedFields = []struct {
+	name  string
+	value func(models.SwiftCode) string
+}{
+	{"countryISO2", func(sc models.SwiftCode) string { return sc.CountryISO2 }},
+	{"codeType", func(sc models.SwiftCode) string { return sc.CodeType }},
+	{"bankName", func(sc models.SwiftCode) string { return sc.Name }},
+	{"address", func(sc models.SwiftCode) string { return sc.Address }},
+	{"townName", func(sc models.SwiftCode) string { return sc.TownName }},
+	{"countryName", func(sc models.SwiftCode) string { return sc.CountryName }},
+	{"timeZone", func(sc models.SwiftCode) string { return sc.TimeZone }},
+	{"isHeadquarter", func(sc models.SwiftCode) string { return strconv.FormatBool(sc.IsHeadquarter) }},
+	{"hqSwiftCode", func(sc models.SwiftCode) string { return sc.HqSwiftCode }},
+}
+
+// Compare reports the codes added, removed and modified going from oldCodes
+// to newCodes. Codes are matched by SwiftCode.
+func Compare(oldCodes, newCodes []models.SwiftCode) Result {
+	oldByCode := make(map[string]models.SwiftCode, len(oldCodes))
+	for _, sc := range oldCodes {
+		oldByCode[sc.SwiftCode] = sc
+	}
+
+	result := Result{Added: []models.SwiftCode{}, Removed: []models.SwiftCode{}, Modified: []ModifiedCode{}}
+	seen := make(map[string]bool, len(newCodes))
+	for _, newCode := range newCodes {
+		seen[newCode.SwiftCode] = true
+		oldCode, existed := oldByCode[newCode.SwiftCode]
+		if !existed {
+			result.Added = append(result.Added, newCode)
+			continue
+		}
+		if changes := compareFields(oldCode, newCode); len(changes) > 0 {
+			result.Modified = append(result.Modified, ModifiedCode{SwiftCode: newCode.SwiftCode, Changes: changes})
+		}
+	}
+	for _, oldCode := range oldCodes {
+		if !seen[oldCode.SwiftCode] {
+			result.Removed = append(result.Removed, oldCode)
+		}
+	}
+
+	sort.Slice(result.Added, func(i, j int) bool { return result.Added[i].SwiftCode < result.Added[j].SwiftCode })
+	sort.Slice(result.Removed, func(i, j int) bool { return result.Removed[i].SwiftCode < result.Removed[j].SwiftCode })
+	sort.Slice(result.Modified, func(i, j int) bool { return result.Modified[i].SwiftCode < result.Modified[j].SwiftCode })
+	return result
+}
+
+func compareFields(oldCode, newCode models.SwiftCode) []FieldChange {
+	var changes []FieldChange
+	for _, field := range comparedFields {
+		oldValue, newValue := field.value(oldCode), field.value(newCode)
+		if oldValue != newValue {
+			changes = append(changes, FieldChange{Field: field.name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// WriteText writes a human-readable, line-oriented summary of result:
+//
+//	$ swiftctl diff old_codes.xlsx new_codes.xlsx
+//	+ ZZBANK002    ZELAND NATIONAL BANK (ZZ)
+//	- ZZBANK001    ZELAND NATIONAL BANK (ZZ)
+//	~ ZZBANKXXX    address: "1 MAIN PLAZA" -> "9 NEW SQUARE"
+//	1 added, 1 removed, 1 modified
+func (result Result) WriteText(destination io.Writer) error {
+	for _, sc := range result.Added {
+		if _, err := fmt.Fprintf(destination, "+ %-11s  %s (%s)\n", sc.SwiftCode, sc.Name, sc.CountryISO2); err != nil {
+			return err
+		}
+	}
+	for _, sc := range result.Removed {
+		if _, err := fmt.Fprintf(destination, "- %-11s  %s (%s)\n", sc.SwiftCode, sc.Name, sc.CountryISO2); err != nil {
+			return err
+		}
+	}
+	for _, modified := range result.Modified {
+		for i, change := range modified.Changes {
+			code := modified.SwiftCode
+			if i > 0 {
+				code = ""
+			}
+			if _, err := fmt.Fprintf(destination, "~ %-11s  %s: %q -> %q\n", code, change.Field, change.Old, change.New); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(destination, "%d added, %d removed, %d modified\n",
+		len(result.Added), len(result.Removed), len(result.Modified))
+	return err
+}
@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"swift-codes-project/models"
+)
+
+// TestCompareReportsAddedRemovedAndModified covers every kind of change.
+func TestCompareReportsAddedRemovedAndModified(t *testing.T) {
+	headOffice := models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKXXX", Name: "ZELAND NATIONAL BANK",
+		Address: "1 MAIN PLAZA", IsHeadquarter: true}
+	closedBranch := models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANK001", Name: "ZELAND NATIONAL BANK",
+		HqSwiftCode: "ZZBANKXXX"}
+	unchangedBranch := models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANK003", Name: "ZELAND NATIONAL BANK",
+		HqSwiftCode: "ZZBANKXXX"}
+
+	movedHeadOffice := headOffice
+	movedHeadOffice.Address = "9 NEW SQUARE"
+	openedBranch := closedBranch
+	openedBranch.SwiftCode = "ZZBANK002"
+
+	result := Compare(
+		[]models.SwiftCode{headOffice, closedBranch, unchangedBranch},
+		[]models.SwiftCode{unchangedBranch, openedBranch, movedHeadOffice},
+	)
+
+	if len(result.Added) != 1 || result.Added[0].SwiftCode != "ZZBANK002" {
+		t.Errorf("Expected ZZBANK002 added, got %+v", result.Added)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].SwiftCode != "ZZBANK001" {
+		t.Errorf("Expected ZZBANK001 removed, got %+v", result.Removed)
+	}
+	if len(result.Modified) != 1 {
+		t.Fatalf("Expected 1 modified code, got %+v", result.Modified)
+	}
+	expectedChange := FieldChange{Field: "address", Old: "1 MAIN PLAZA", New: "9 NEW SQUARE"}
+	if changes := result.Modified[0].Changes; len(changes) != 1 || changes[0] != expectedChange {
+		t.Errorf("Expected only the address to change, got %+v", changes)
+	}
+
+	var textOutput bytes.Buffer
+	if writeError := result.WriteText(&textOutput); writeError != nil {
+		t.Fatalf("Failed to write text diff: %v", writeError)
+	}
+	if !strings.Contains(textOutput.String(), "1 added, 1 removed, 1 modified") {
+		t.Errorf("Expected summary line in text output, got:\n%s", textOutput.String())
+	}
+}
+
+// TestCompareIdenticalDatasetsIsEmpty checks that equal inputs produce no changes.
+func TestCompareIdenticalDatasetsIsEmpty(t *testing.T) {
+	codes := []models.SwiftCode{{CountryISO2: "ZZ", SwiftCode: "ZZBANKXXX", IsHeadquarter: true}}
+	if result := Compare(codes, codes); !result.Empty() {
+		t.Errorf("Expected an empty diff, got %+v", result)
+	}
+}
@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"swift-codes-project/lookup"
+	"swift-codes-project/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported export formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+	// FormatSnapshot is the binary format read by the lookup package.
+	FormatSnapshot = "snapshot"
+)
+
+// Header is the column layout of CSV and XLSX exports. It matches the vendor
+// spreadsheet so exported files can be imported again by the parser package.
+var Header = []string{
+	"COUNTRY ISO2 CODE", "SWIFT CODE", "CODE TYPE", "NAME",
+	"ADDRESS", "TOWN NAME", "COUNTRY NAME", "TIME ZONE",
+}
+
+// RowWriter writes exported rows in a single format. Close must be called to
+// flush buffered output; nothing is guaranteed to reach the destination before.
+type RowWriter interface {
+	WriteRow(sc models.SwiftCode) error
+	Close() error
+}
+
+// ContentType returns the MIME type for format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatSnapshot:
+		return lookup.SnapshotContentType
+	}
+	return "application/octet-stream"
+}
+
+// NewWriter returns a RowWriter producing format on destination.
+func NewWriter(format string, destination io.Writer) (RowWriter, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return newCSVWriter(destination)
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(destination)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(destination)
+	case FormatSnapshot:
+		return lookup.NewSnapshotWriter(destination)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+func rowValues(sc models.SwiftCode) []string {
+	return []string{
+		sc.CountryISO2, sc.SwiftCode, sc.CodeType, sc.Name,
+		sc.Address, sc.TownName, sc.CountryName, sc.TimeZone,
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(destination io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(destination)
+	if err := writer.Write(Header); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (w *csvWriter) WriteRow(sc models.SwiftCode) error {
+	return w.writer.Write(rowValues(sc))
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonlRow is one line of a JSON Lines export. Fields are named as in the
+// REST API, bankName included, so a line can be posted back to
+// POST /v1/swift-codes as it is.
+type jsonlRow struct {
+	SwiftCode     string `json:"swiftCode"`
+	CodeType      string `json:"codeType"`
+	BankName      string `json:"bankName"`
+	Address       string `json:"address"`
+	TownName      string `json:"townName"`
+	CountryISO2   string `json:"countryISO2"`
+	CountryName   string `json:"countryName"`
+	TimeZone      string `json:"timeZone"`
+	IsHeadquarter bool   `json:"isHeadquarter"`
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) WriteRow(sc models.SwiftCode) error {
+	return w.encoder.Encode(jsonlRow{
+		SwiftCode:     sc.SwiftCode,
+		CodeType:      sc.CodeType,
+		BankName:      sc.Name,
+		Address:       sc.Address,
+		TownName:      sc.TownName,
+		CountryISO2:   sc.CountryISO2,
+		CountryName:   sc.CountryName,
+		TimeZone:      sc.TimeZone,
+		IsHeadquarter: sc.IsHeadquarter,
+	})
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+// xlsxWriter uses the excelize stream writer, which spills rows to a temporary
+// file once they outgrow its buffer, so the sheet is never held in memory. On
+// Close the workbook is zipped straight onto destination; excelize only builds
+// the file in memory for encrypted workbooks, which exports never are.
+type xlsxWriter struct {
+	destination io.Writer
+	workbook    *excelize.File
+	stream      *excelize.StreamWriter
+	nextRow     int
+}
+
+func newXLSXWriter(destination io.Writer) (*xlsxWriter, error) {
+	workbook := excelize.NewFile()
+	stream, err := workbook.NewStreamWriter(workbook.GetSheetName(0))
+	if err != nil {
+		workbook.Close()
+		return nil, err
+	}
+	w := &xlsxWriter{destination: destination, workbook: workbook, stream: stream, nextRow: 1}
+	if err := w.writeCells(Header); err != nil {
+		workbook.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *xlsxWriter) writeCells(values []string) error {
+	cellName, err := excelize.CoordinatesToCellName(1, w.nextRow)
+	if err != nil {
+		return err
+	}
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = value
+	}
+	w.nextRow++
+	return w.stream.SetRow(cellName, cells)
+}
+
+func (w *xlsxWriter) WriteRow(sc models.SwiftCode) error {
+	return w.writeCells(rowValues(sc))
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.workbook.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := w.workbook.WriteTo(w.destination)
+	return err
+}
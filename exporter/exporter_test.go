@@ -0,0 +1,124 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/lookup"
+	"swift-codes-project/models"
+	"swift-codes-project/parser"
+	"swift-codes-project/service"
+)
+
+var exportFixture = []models.SwiftCode{
+	{CountryISO2: "PL", SwiftCode: "ZZBANKZZXXX", CodeType: "BIC11", Name: "ZELAND NATIONAL BANK",
+		Address: "1 MAIN PLAZA", TownName: "CAPITAL", CountryName: "POLAND", TimeZone: "Europe/London",
+		IsHeadquarter: true},
+	{CountryISO2: "PL", SwiftCode: "ZZBANKZZ001", CodeType: "BIC11", Name: "ZELAND NATIONAL BANK",
+		Address: "2 SECOND AVE", TownName: "CAPITAL", CountryName: "POLAND", TimeZone: "Europe/London",
+		HqSwiftCode: "ZZBANKZZXXX"},
+}
+
+// TestXLSXExportRoundTripsThroughParser writes the fixture as XLSX and imports
+// it into a fresh database with parser.ParseExcelAndStore.
+func TestXLSXExportRoundTripsThroughParser(t *testing.T) {
+	var buffer bytes.Buffer
+	rowWriter, writerError := NewWriter(FormatXLSX, &buffer)
+	if writerError != nil {
+		t.Fatalf("Failed to create xlsx writer: %v", writerError)
+	}
+	for _, row := range exportFixture {
+		if writeError := rowWriter.WriteRow(row); writeError != nil {
+			t.Fatalf("Failed to write row: %v", writeError)
+		}
+	}
+	if closeError := rowWriter.Close(); closeError != nil {
+		t.Fatalf("Failed to close xlsx writer: %v", closeError)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "export.xlsx")
+	if writeError := os.WriteFile(filePath, buffer.Bytes(), 0o644); writeError != nil {
+		t.Fatalf("Failed to save export: %v", writeError)
+	}
+
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	if importError := parser.ParseExcelAndStore(testDatabase, filePath); importError != nil {
+		t.Fatalf("Expected exported file to import cleanly, got: %v", importError)
+	}
+
+	repository := &service.SwiftRepository{DB: testDatabase}
+	var imported []models.SwiftCode
+	repository.ExportSwiftCodes("", func(sc models.SwiftCode) error {
+		imported = append(imported, sc)
+		return nil
+	})
+	if len(imported) != len(exportFixture) {
+		t.Fatalf("Expected %d rows after round trip, got %d", len(exportFixture), len(imported))
+	}
+	for i := range imported {
+		if imported[i] != exportFixture[len(exportFixture)-1-i] {
+			t.Errorf("Row %d changed in round trip: %+v", i, imported[i])
+		}
+	}
+}
+
+// TestJSONLExportWritesOneObjectPerLine checks the jsonl writer's framing
+// and that lines use the API's field names.
+func TestJSONLExportWritesOneObjectPerLine(t *testing.T) {
+	var buffer bytes.Buffer
+	rowWriter, _ := NewWriter(FormatJSONL, &buffer)
+	for _, row := range exportFixture {
+		rowWriter.WriteRow(row)
+	}
+	rowWriter.Close()
+
+	scanner := bufio.NewScanner(&buffer)
+	lineCount := 0
+	for scanner.Scan() {
+		var decoded map[string]interface{}
+		if decodeError := json.Unmarshal(scanner.Bytes(), &decoded); decodeError != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", lineCount+1, decodeError)
+		}
+		if decoded["bankName"] != exportFixture[lineCount].Name || decoded["Name"] != nil {
+			t.Errorf("Line %d does not carry the bank name as bankName: %s", lineCount+1, scanner.Bytes())
+		}
+		lineCount++
+	}
+	if lineCount != len(exportFixture) {
+		t.Errorf("Expected %d lines, got %d", len(exportFixture), lineCount)
+	}
+}
+
+// TestSnapshotExportLoadsIntoLookupIndex writes the fixture as a snapshot and
+// reads it back with the lookup package.
+func TestSnapshotExportLoadsIntoLookupIndex(t *testing.T) {
+	var buffer bytes.Buffer
+	rowWriter, writerError := NewWriter(FormatSnapshot, &buffer)
+	if writerError != nil {
+		t.Fatalf("Failed to create snapshot writer: %v", writerError)
+	}
+	for _, row := range exportFixture {
+		rowWriter.WriteRow(row)
+	}
+	if closeError := rowWriter.Close(); closeError != nil {
+		t.Fatalf("Failed to close snapshot writer: %v", closeError)
+	}
+
+	var directory lookup.Directory
+	if loadError := directory.LoadSnapshot(&buffer); loadError != nil {
+		t.Fatalf("Failed to load snapshot: %v", loadError)
+	}
+	if branch, ok := directory.Index().Get("ZZBANKZZ001"); !ok || branch != exportFixture[1] {
+		t.Errorf("Expected the branch to survive the snapshot, got %+v, %v", branch, ok)
+	}
+}
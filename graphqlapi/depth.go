@@ -0,0 +1,73 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// maxQueryDepth bounds how deeply fields may be nested. Each level of
+// headOffice and branches is one more batch of lookups, so without a bound
+// a single request could chain them indefinitely.
+const maxQueryDepth = 5
+
+// checkDepth refuses a query whose selections nest deeper than limit.
+// Introspection fields are not counted. A query that does not parse is let
+// through for graphql.Do to report.
+func checkDepth(query string, limit int) error {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if depth := selectionDepth(operation.SelectionSet, fragments, map[string]bool{}); depth > limit {
+			return fmt.Errorf("query is nested %d levels deep; at most %d are allowed", depth, limit)
+		}
+	}
+	return nil
+}
+
+// selectionDepth returns how many levels of fields selections holds.
+// visiting holds the fragments being expanded, so a cycle ends the walk;
+// validation rejects such queries anyway.
+func selectionDepth(selections *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, visiting map[string]bool) int {
+	if selections == nil {
+		return 0
+	}
+	deepest := 0
+	for _, selection := range selections.Selections {
+		depth := 0
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			depth = 1 + selectionDepth(selection.SelectionSet, fragments, visiting)
+		case *ast.InlineFragment:
+			depth = selectionDepth(selection.SelectionSet, fragments, visiting)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, known := fragments[name]
+			if !known || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			depth = selectionDepth(fragment.SelectionSet, fragments, visiting)
+			delete(visiting, name)
+		}
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
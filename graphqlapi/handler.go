@@ -0,0 +1,80 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	handler "swift-codes-project/handlers"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// graphQLRequest is the standard GraphQL-over-HTTP request body.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes GraphQL queries sent as a JSON POST body or, for simple
+// reads, as GET query parameters.
+type Handler struct {
+	Store  Store
+	Schema graphql.Schema
+}
+
+// NewHandler builds the schema for store and returns a handler serving it.
+func NewHandler(store Store) (*Handler, error) {
+	schema, err := NewSchema(store)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{Store: store, Schema: schema}, nil
+}
+
+// POST /graphql  {"query": "...", "variables": {...}}
+// GET  /graphql?query=...&variables=...
+func (h *Handler) ServeHTTP(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+	var request graphQLRequest
+	switch incomingRequest.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(incomingRequest.Body).Decode(&request); err != nil {
+			handler.WriteError(responseWriter, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	case http.MethodGet:
+		query := incomingRequest.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				handler.WriteError(responseWriter, http.StatusBadRequest, "invalid variables")
+				return
+			}
+		}
+	default:
+		responseWriter.Header().Set("Allow", "GET, POST")
+		handler.WriteError(responseWriter, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if request.Query == "" {
+		handler.WriteError(responseWriter, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	var result *graphql.Result
+	if err := checkDepth(request.Query, maxQueryDepth); err != nil {
+		result = &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	} else {
+		result = graphql.Do(graphql.Params{
+			Schema:         h.Schema,
+			RequestString:  request.Query,
+			OperationName:  request.OperationName,
+			VariableValues: request.Variables,
+			Context:        withLoaders(incomingRequest.Context(), newLoaders(h.Store)),
+		})
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(result)
+}
@@ -0,0 +1,207 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// countingStore counts the batch queries issued against the repository.
+type countingStore struct {
+	*service.SwiftRepository
+	codeQueries   int
+	branchQueries int
+}
+
+func (store *countingStore) GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error) {
+	store.codeQueries++
+	return store.SwiftRepository.GetSwiftCodesByCode(codes)
+}
+
+func (store *countingStore) GetBranchesByHeadOffice(hqCodes []string) ([]models.SwiftCode, error) {
+	store.branchQueries++
+	return store.SwiftRepository.GetBranchesByHeadOffice(hqCodes)
+}
+
+func newTestStore(t *testing.T) *countingStore {
+	t.Helper()
+	testDatabase, initError := db.InitDB("file:" + t.Name() + "?mode=memory&cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	t.Cleanup(func() { testDatabase.Close() })
+
+	repository := &service.SwiftRepository{DB: testDatabase}
+	for _, entry := range []models.SwiftCode{
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKAAXXX", Name: "BANK A", TownName: "CAPITAL", CountryName: "ZELAND", IsHeadquarter: true},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKAA001", Name: "BANK A", TownName: "CAPITAL", CountryName: "ZELAND", HqSwiftCode: "ZZBANKAAXXX"},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKAA002", Name: "BANK A", TownName: "CAPITAL", CountryName: "ZELAND", HqSwiftCode: "ZZBANKAAXXX"},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKAA003", Name: "BANK A", TownName: "PORT", CountryName: "ZELAND", HqSwiftCode: "ZZBANKAAXXX"},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKBBXXX", Name: "BANK B", TownName: "PORT", CountryName: "ZELAND", IsHeadquarter: true},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKBB001", Name: "BANK B", TownName: "PORT", CountryName: "ZELAND", HqSwiftCode: "ZZBANKBBXXX"},
+	} {
+		if insertError := repository.CreateSwiftCode(entry); insertError != nil {
+			t.Fatalf("Failed to insert %s: %v", entry.SwiftCode, insertError)
+		}
+	}
+	return &countingStore{SwiftRepository: repository}
+}
+
+// postQuery runs query through the handler and decodes the response.
+func postQuery(t *testing.T, store Store, query string) (map[string]interface{}, []interface{}) {
+	t.Helper()
+	graphQLHandler, schemaError := NewHandler(store)
+	if schemaError != nil {
+		t.Fatalf("Failed to build schema: %v", schemaError)
+	}
+	body, _ := json.Marshal(map[string]string{"query": query})
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	recorder := httptest.NewRecorder()
+	graphQLHandler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	var response struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []interface{}          `json:"errors"`
+	}
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&response); decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+	return response.Data, response.Errors
+}
+
+// TestBranchSiblingsInOneRoundTrip resolves a branch, its head office and the
+// head office's branches in the same town.
+func TestBranchSiblingsInOneRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	data, errors := postQuery(t, store, `{
+		swiftCode(code: "zzbankaa001") {
+			swiftCode
+			headOffice { swiftCode branches(townName: "capital") { swiftCode } }
+		}
+	}`)
+	if len(errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", errors)
+	}
+	headOffice := data["swiftCode"].(map[string]interface{})["headOffice"].(map[string]interface{})
+	if headOffice["swiftCode"] != "ZZBANKAAXXX" {
+		t.Errorf("Expected head office ZZBANKAAXXX, got %v", headOffice["swiftCode"])
+	}
+	siblings := headOffice["branches"].([]interface{})
+	if len(siblings) != 2 {
+		t.Fatalf("Expected 2 branches in CAPITAL, got %v", siblings)
+	}
+}
+
+// TestNestedResolutionIsBatched walks every code of a country to its head
+// office and branches and expects one query per nesting level, not per code.
+func TestNestedResolutionIsBatched(t *testing.T) {
+	store := newTestStore(t)
+	data, errors := postQuery(t, store, `{
+		country(iso2: "ZZ") {
+			countryName
+			swiftCodes {
+				swiftCode
+				headOffice { swiftCode }
+				branches { swiftCode headOffice { swiftCode } }
+			}
+		}
+	}`)
+	if len(errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", errors)
+	}
+	country := data["country"].(map[string]interface{})
+	if country["countryName"] != "ZELAND" || len(country["swiftCodes"].([]interface{})) != 6 {
+		t.Fatalf("Unexpected country result: %v", country)
+	}
+	if store.branchQueries != 1 {
+		t.Errorf("Expected 1 branch query, got %d", store.branchQueries)
+	}
+	// one batch for the codes' head offices; the branches' head offices are
+	// already cached by then
+	if store.codeQueries != 1 {
+		t.Errorf("Expected 1 code query, got %d", store.codeQueries)
+	}
+}
+
+// TestSearchAndMissingCode checks search filtering and that unknown codes
+// resolve to null instead of an error.
+func TestSearchAndMissingCode(t *testing.T) {
+	store := newTestStore(t)
+	data, errors := postQuery(t, store, `{
+		search(query: "port", country: "zz") { swiftCode }
+		swiftCode(code: "NOPENOPEXXX") { swiftCode }
+	}`)
+	if len(errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", errors)
+	}
+	if matches := data["search"].([]interface{}); len(matches) != 3 {
+		t.Errorf("Expected 3 codes in PORT, got %v", matches)
+	}
+	if data["swiftCode"] != nil {
+		t.Errorf("Expected null for an unknown code, got %v", data["swiftCode"])
+	}
+}
+
+// TestQueryDepthIsLimited expects a query nesting head offices and branches
+// past maxQueryDepth, directly or through fragments, to be refused before
+// any lookup runs.
+func TestQueryDepthIsLimited(t *testing.T) {
+	store := newTestStore(t)
+	for _, query := range []string{
+		`{ swiftCode(code: "ZZBANKAA001") { headOffice { branches { headOffice { branches { swiftCode } } } } } }`,
+		`{ swiftCode(code: "ZZBANKAA001") { ...Up } }
+		fragment Up on SwiftCode { headOffice { branches { ...Again } } }
+		fragment Again on SwiftCode { headOffice { branches { swiftCode } } }`,
+	} {
+		data, errors := postQuery(t, store, query)
+		if len(errors) != 1 || data != nil {
+			t.Errorf("Expected the query to be refused, got %v %v", data, errors)
+		}
+	}
+	if store.codeQueries != 0 || store.branchQueries != 0 {
+		t.Errorf("Expected no lookups for refused queries, got %d code and %d branch queries", store.codeQueries, store.branchQueries)
+	}
+
+	if _, errors := postQuery(t, store, `{ __schema { types { fields { type { ofType { ofType { name } } } } } } }`); len(errors) != 0 {
+		t.Errorf("Expected introspection to be allowed, got %v", errors)
+	}
+}
+
+// TestSearchRefusesNonPositiveLimit expects a limit below 1 to be an error
+// rather than the maximum.
+func TestSearchRefusesNonPositiveLimit(t *testing.T) {
+	data, errors := postQuery(t, newTestStore(t), `{ search(query: "port", limit: -1) { swiftCode } }`)
+	if len(errors) != 1 || data != nil {
+		t.Errorf("Expected an error for a negative limit, got %v %v", data, errors)
+	}
+}
+
+// TestRequestErrorsAreJSON expects malformed requests to get the JSON error
+// envelope the REST handlers use.
+func TestRequestErrorsAreJSON(t *testing.T) {
+	graphQLHandler, schemaError := NewHandler(newTestStore(t))
+	if schemaError != nil {
+		t.Fatalf("Failed to build schema: %v", schemaError)
+	}
+	recorder := httptest.NewRecorder()
+	graphQLHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+
+	var decoded struct {
+		Error string `json:"error"`
+	}
+	if recorder.Code != http.StatusBadRequest || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a 400 JSON response, got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&decoded); decodeError != nil || decoded.Error != "query is required" {
+		t.Errorf("Expected the error envelope, got %+v %v", decoded, decodeError)
+	}
+}
@@ -0,0 +1,120 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"swift-codes-project/models"
+)
+
+// batchLoader collects the keys requested while one level of a query is being
+// resolved and fetches them together the first time any of their values is
+// needed, so resolving N sibling fields costs one query instead of N.
+type batchLoader[V any] struct {
+	fetch func(keys []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]V
+	errs    map[string]error
+}
+
+func newBatchLoader[V any](fetch func(keys []string) (map[string]V, error)) *batchLoader[V] {
+	return &batchLoader[V]{fetch: fetch, results: map[string]V{}, errs: map[string]error{}}
+}
+
+// load queues key and returns a thunk that graphql-go calls once it has
+// resolved every field at the current depth. Keys already fetched are served
+// from the loader's cache.
+func (loader *batchLoader[V]) load(key string) func() (V, error) {
+	loader.mu.Lock()
+	_, cached := loader.results[key]
+	_, failed := loader.errs[key]
+	if !cached && !failed && !containsKey(loader.pending, key) {
+		loader.pending = append(loader.pending, key)
+	}
+	loader.mu.Unlock()
+
+	return func() (V, error) {
+		loader.mu.Lock()
+		defer loader.mu.Unlock()
+		loader.dispatch()
+		if err := loader.errs[key]; err != nil {
+			var zero V
+			return zero, err
+		}
+		return loader.results[key], nil
+	}
+}
+
+// dispatch fetches every pending key in one call. The caller holds mu.
+func (loader *batchLoader[V]) dispatch() {
+	if len(loader.pending) == 0 {
+		return
+	}
+	keys := loader.pending
+	loader.pending = nil
+
+	fetched, err := loader.fetch(keys)
+	for _, key := range keys {
+		if err != nil {
+			loader.errs[key] = err
+			continue
+		}
+		// remember misses too, so they are not fetched again
+		loader.results[key] = fetched[key]
+	}
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, existing := range keys {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
+
+// loaders holds the per-request batch loaders. Values are cached for the
+// lifetime of a single request only.
+type loaders struct {
+	swiftCodes *batchLoader[*models.SwiftCode]
+	branches   *batchLoader[[]models.SwiftCode]
+}
+
+func newLoaders(store Store) *loaders {
+	return &loaders{
+		swiftCodes: newBatchLoader(func(codes []string) (map[string]*models.SwiftCode, error) {
+			rows, err := store.GetSwiftCodesByCode(codes)
+			if err != nil {
+				return nil, err
+			}
+			byCode := make(map[string]*models.SwiftCode, len(rows))
+			for i := range rows {
+				byCode[rows[i].SwiftCode] = &rows[i]
+			}
+			return byCode, nil
+		}),
+		branches: newBatchLoader(func(hqCodes []string) (map[string][]models.SwiftCode, error) {
+			rows, err := store.GetBranchesByHeadOffice(hqCodes)
+			if err != nil {
+				return nil, err
+			}
+			byHeadOffice := make(map[string][]models.SwiftCode, len(hqCodes))
+			for _, row := range rows {
+				byHeadOffice[row.HqSwiftCode] = append(byHeadOffice[row.HqSwiftCode], row)
+			}
+			return byHeadOffice, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, requestLoaders *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, requestLoaders)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
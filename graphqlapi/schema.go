@@ -0,0 +1,214 @@
+// Package graphqlapi serves the SWIFT code directory as a GraphQL graph, so a
+// client can fetch a branch, its head office and sibling branches in one
+// round trip. Nested lookups are batched per request.
+package graphqlapi
+
+import (
+	"errors"
+	"strings"
+
+	"swift-codes-project/models"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// Store is the part of the repository the GraphQL resolvers read from.
+type Store interface {
+	GetCountrySwiftCodes(iso2 string) ([]models.SwiftCode, error)
+	SearchSwiftCodes(query, iso2 string, limit int) ([]models.SwiftCode, error)
+	GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error)
+	GetBranchesByHeadOffice(hqCodes []string) ([]models.SwiftCode, error)
+}
+
+// country is the source value of the Country type.
+type country struct {
+	iso2       string
+	name       string
+	swiftCodes []models.SwiftCode
+}
+
+// swiftCodeField resolves a scalar field of a SwiftCode source.
+func swiftCodeField(fieldType graphql.Output, value func(models.SwiftCode) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: fieldType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return value(p.Source.(models.SwiftCode)), nil
+		},
+	}
+}
+
+// optionalSwiftCode turns a loaded *SwiftCode into a nullable field value.
+func optionalSwiftCode(sc *models.SwiftCode) interface{} {
+	if sc == nil {
+		return nil
+	}
+	return *sc
+}
+
+// filterByTown keeps the codes in townName, ignoring case; empty keeps all.
+func filterByTown(codes []models.SwiftCode, townName string) []models.SwiftCode {
+	if townName == "" {
+		return codes
+	}
+	filtered := []models.SwiftCode{}
+	for _, sc := range codes {
+		if strings.EqualFold(sc.TownName, townName) {
+			filtered = append(filtered, sc)
+		}
+	}
+	return filtered
+}
+
+// NewSchema builds the GraphQL schema on top of store.
+func NewSchema(store Store) (graphql.Schema, error) {
+	var swiftCodeType *graphql.Object
+	swiftCodeType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SwiftCode",
+		Description: "A head office or branch in the SWIFT directory.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"swiftCode":     swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.SwiftCode }),
+				"bankName":      swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.Name }),
+				"address":       swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.Address }),
+				"townName":      swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.TownName }),
+				"countryISO2":   swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.CountryISO2 }),
+				"countryName":   swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.CountryName }),
+				"codeType":      swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.CodeType }),
+				"timeZone":      swiftCodeField(graphql.NewNonNull(graphql.String), func(sc models.SwiftCode) interface{} { return sc.TimeZone }),
+				"isHeadquarter": swiftCodeField(graphql.NewNonNull(graphql.Boolean), func(sc models.SwiftCode) interface{} { return sc.IsHeadquarter }),
+				"headOffice": &graphql.Field{
+					Type:        swiftCodeType,
+					Description: "The head office of a branch; null for head offices.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						sc := p.Source.(models.SwiftCode)
+						if sc.IsHeadquarter || sc.HqSwiftCode == "" {
+							return nil, nil
+						}
+						thunk := loadersFrom(p.Context).swiftCodes.load(sc.HqSwiftCode)
+						return func() (interface{}, error) {
+							headOffice, err := thunk()
+							return optionalSwiftCode(headOffice), err
+						}, nil
+					},
+				},
+				"branches": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(swiftCodeType))),
+					Description: "The branches of a head office, optionally limited to one town; empty for branches.",
+					Args: graphql.FieldConfigArgument{
+						"townName": &graphql.ArgumentConfig{Type: graphql.String},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						sc := p.Source.(models.SwiftCode)
+						if !sc.IsHeadquarter {
+							return []models.SwiftCode{}, nil
+						}
+						townName, _ := p.Args["townName"].(string)
+						thunk := loadersFrom(p.Context).branches.load(sc.SwiftCode)
+						return func() (interface{}, error) {
+							branches, err := thunk()
+							return filterByTown(branches, townName), err
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	countryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Country",
+		Fields: graphql.Fields{
+			"countryISO2": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(country).iso2, nil },
+			},
+			"countryName": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(country).name, nil },
+			},
+			"swiftCodes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(swiftCodeType))),
+				Args: graphql.FieldConfigArgument{
+					"headquartersOnly": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					codes := p.Source.(country).swiftCodes
+					if headquartersOnly, _ := p.Args["headquartersOnly"].(bool); !headquartersOnly {
+						return codes, nil
+					}
+					headOffices := []models.SwiftCode{}
+					for _, sc := range codes {
+						if sc.IsHeadquarter {
+							headOffices = append(headOffices, sc)
+						}
+					}
+					return headOffices, nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"swiftCode": &graphql.Field{
+				Type:        swiftCodeType,
+				Description: "Looks up one code; null when it does not exist.",
+				Args: graphql.FieldConfigArgument{
+					"code": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					code := strings.ToUpper(strings.TrimSpace(p.Args["code"].(string)))
+					thunk := loadersFrom(p.Context).swiftCodes.load(code)
+					return func() (interface{}, error) {
+						sc, err := thunk()
+						return optionalSwiftCode(sc), err
+					}, nil
+				},
+			},
+			"country": &graphql.Field{
+				Type:        countryType,
+				Description: "Lists the codes of one country; null when it has none.",
+				Args: graphql.FieldConfigArgument{
+					"iso2": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					iso2 := strings.ToUpper(strings.TrimSpace(p.Args["iso2"].(string)))
+					codes, err := store.GetCountrySwiftCodes(iso2)
+					if err != nil || len(codes) == 0 {
+						return nil, err
+					}
+					return country{iso2: iso2, name: codes[0].CountryName, swiftCodes: codes}, nil
+				},
+			},
+			"search": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(swiftCodeType))),
+				Description: "Matches codes by prefix and bank or town name by substring.",
+				Args: graphql.FieldConfigArgument{
+					"query":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"country": &graphql.ArgumentConfig{Type: graphql.String},
+					"limit":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultSearchLimit},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					iso2, _ := p.Args["country"].(string)
+					limit, _ := p.Args["limit"].(int)
+					if limit <= 0 {
+						return nil, errors.New("limit must be at least 1")
+					}
+					limit = min(limit, maxSearchLimit)
+					codes, err := store.SearchSwiftCodes(strings.TrimSpace(p.Args["query"].(string)), strings.ToUpper(iso2), limit)
+					if codes == nil {
+						codes = []models.SwiftCode{}
+					}
+					return codes, err
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
@@ -0,0 +1,299 @@
+// Package grpcapi serves the SWIFT code directory over gRPC, on top of the same
+// data store the HTTP handlers use.
+package grpcapi
+
+//go:generate protoc -I ../proto --go_out=.. --go_opt=module=swift-codes-project --go-grpc_out=.. --go-grpc_opt=module=swift-codes-project swiftcodes/v1/swift_codes.proto
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"swift-codes-project/changefeed"
+	"swift-codes-project/grpcapi/swiftcodespb"
+	handler "swift-codes-project/handlers"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+	"swift-codes-project/timezones"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+	maxBatchGet        = 1000
+)
+
+// SwiftCodeServer implements swiftcodespb.SwiftCodeServiceServer.
+type SwiftCodeServer struct {
+	swiftcodespb.UnimplementedSwiftCodeServiceServer
+
+	DataStore handler.SwiftDataStore
+	// Changes backs WatchChanges, which returns Unimplemented when it is nil.
+	Changes *changefeed.Feed
+	// ReadOnly makes Create, Update and Delete return PermissionDenied.
+	ReadOnly bool
+	// Countries, when set, makes Create and Update reject codes whose country
+	// is unknown or misnamed with InvalidArgument.
+	Countries handler.CountryStore
+}
+
+// errReadOnly is returned by the mutating RPCs of a read-only server.
+var errReadOnly = status.Error(codes.PermissionDenied, "this server is read-only; send writes to the primary")
+
+// checkCountry validates entry's country when the server has Countries.
+func (server *SwiftCodeServer) checkCountry(entry models.SwiftCode) error {
+	if server.Countries == nil {
+		return nil
+	}
+	message, err := handler.CheckCountry(server.Countries, entry)
+	if err != nil {
+		return statusFromError(err)
+	}
+	if message != "" {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return nil
+}
+
+// NewGRPCServer returns a gRPC server with the SWIFT code service, the
+// standard health service and server reflection registered.
+func NewGRPCServer(swiftCodeServer *SwiftCodeServer, options ...grpc.ServerOption) *grpc.Server {
+	grpcServer := grpc.NewServer(options...)
+	swiftcodespb.RegisterSwiftCodeServiceServer(grpcServer, swiftCodeServer)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(swiftcodespb.SwiftCodeService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// statusFromError maps service-layer errors onto gRPC status codes.
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrDatasetVersionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toProto(sc models.SwiftCode) *swiftcodespb.SwiftCode {
+	return &swiftcodespb.SwiftCode{
+		SwiftCode:     sc.SwiftCode,
+		CountryIso2:   sc.CountryISO2,
+		CountryName:   sc.CountryName,
+		CodeType:      sc.CodeType,
+		BankName:      sc.Name,
+		Address:       sc.Address,
+		TownName:      sc.TownName,
+		TimeZone:      sc.TimeZone,
+		IsHeadquarter: sc.IsHeadquarter,
+		HqSwiftCode:   sc.HqSwiftCode,
+	}
+}
+
+func toProtoList(rows []models.SwiftCode) []*swiftcodespb.SwiftCode {
+	list := make([]*swiftcodespb.SwiftCode, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, toProto(row))
+	}
+	return list
+}
+
+// fromProto validates an incoming code and derives the head office link from
+// the code itself, the same way the spreadsheet importer does.
+func fromProto(message *swiftcodespb.SwiftCode) (models.SwiftCode, error) {
+	if message == nil {
+		return models.SwiftCode{}, status.Error(codes.InvalidArgument, "swift_code is required")
+	}
+	sc := models.SwiftCode{
+		SwiftCode:   strings.ToUpper(message.GetSwiftCode()),
+		CountryISO2: strings.ToUpper(message.GetCountryIso2()),
+		CountryName: strings.ToUpper(message.GetCountryName()),
+		CodeType:    message.GetCodeType(),
+		Name:        message.GetBankName(),
+		Address:     message.GetAddress(),
+		TownName:    message.GetTownName(),
+		TimeZone:    message.GetTimeZone(),
+	}
+	if len(sc.SwiftCode) != 8 && len(sc.SwiftCode) != 11 {
+		return sc, status.Error(codes.InvalidArgument, "swift_code must be 8 or 11 characters")
+	}
+	if len(sc.CountryISO2) != 2 {
+		return sc, status.Error(codes.InvalidArgument, "country_iso2 must be 2 letters")
+	}
+	timeZone, err := timezones.Normalize(sc.TimeZone)
+	if err != nil {
+		return sc, status.Error(codes.InvalidArgument, err.Error())
+	}
+	sc.TimeZone = timeZone
+	sc.IsHeadquarter = len(sc.SwiftCode) == 8 || strings.HasSuffix(sc.SwiftCode, "XXX")
+	if !sc.IsHeadquarter {
+		sc.HqSwiftCode = sc.SwiftCode[:8] + "XXX"
+	}
+	return sc, nil
+}
+
+func (server *SwiftCodeServer) Get(ctx context.Context, request *swiftcodespb.GetRequest) (*swiftcodespb.GetResponse, error) {
+	requestedCode := strings.ToUpper(request.GetSwiftCode())
+	if requestedCode == "" {
+		return nil, status.Error(codes.InvalidArgument, "swift_code is required")
+	}
+	headOffice, branches, err := server.DataStore.GetSwiftCode(requestedCode)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return &swiftcodespb.GetResponse{SwiftCode: toProto(headOffice), Branches: toProtoList(branches)}, nil
+}
+
+func (server *SwiftCodeServer) BatchGet(ctx context.Context, request *swiftcodespb.BatchGetRequest) (*swiftcodespb.BatchGetResponse, error) {
+	if len(request.GetSwiftCodes()) > maxBatchGet {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d codes per batch", maxBatchGet)
+	}
+	requestedCodes := make([]string, len(request.GetSwiftCodes()))
+	for i, requestedCode := range request.GetSwiftCodes() {
+		requestedCodes[i] = strings.ToUpper(requestedCode)
+	}
+	rows, err := server.DataStore.GetSwiftCodesByCode(requestedCodes)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	byCode := make(map[string]models.SwiftCode, len(rows))
+	for _, row := range rows {
+		byCode[row.SwiftCode] = row
+	}
+
+	response := &swiftcodespb.BatchGetResponse{}
+	for i, requestedCode := range request.GetSwiftCodes() {
+		row, found := byCode[requestedCodes[i]]
+		if !found {
+			response.Missing = append(response.Missing, requestedCode)
+			continue
+		}
+		response.SwiftCodes = append(response.SwiftCodes, toProto(row))
+	}
+	return response, nil
+}
+
+func (server *SwiftCodeServer) ListByCountry(ctx context.Context, request *swiftcodespb.ListByCountryRequest) (*swiftcodespb.ListByCountryResponse, error) {
+	requestedISO2 := strings.ToUpper(request.GetCountryIso2())
+	if len(requestedISO2) != 2 {
+		return nil, status.Error(codes.InvalidArgument, "country_iso2 must be 2 letters")
+	}
+	rows, err := server.DataStore.GetCountrySwiftCodes(requestedISO2)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	if len(rows) == 0 {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	return &swiftcodespb.ListByCountryResponse{
+		CountryIso2: requestedISO2,
+		CountryName: rows[0].CountryName,
+		SwiftCodes:  toProtoList(rows),
+	}, nil
+}
+
+func (server *SwiftCodeServer) Search(ctx context.Context, request *swiftcodespb.SearchRequest) (*swiftcodespb.SearchResponse, error) {
+	if strings.TrimSpace(request.GetQuery()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	limit := int(request.GetLimit())
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	rows, err := server.DataStore.SearchSwiftCodes(
+		strings.TrimSpace(request.GetQuery()), strings.ToUpper(request.GetCountryIso2()), min(limit, maxSearchLimit),
+	)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return &swiftcodespb.SearchResponse{SwiftCodes: toProtoList(rows)}, nil
+}
+
+func (server *SwiftCodeServer) Create(ctx context.Context, request *swiftcodespb.CreateRequest) (*swiftcodespb.CreateResponse, error) {
+	if server.ReadOnly {
+		return nil, errReadOnly
+	}
+	newEntry, err := fromProto(request.GetSwiftCode())
+	if err != nil {
+		return nil, err
+	}
+	if err := server.checkCountry(newEntry); err != nil {
+		return nil, err
+	}
+	if err := server.DataStore.CreateSwiftCode(newEntry); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &swiftcodespb.CreateResponse{SwiftCode: toProto(newEntry)}, nil
+}
+
+func (server *SwiftCodeServer) Update(ctx context.Context, request *swiftcodespb.UpdateRequest) (*swiftcodespb.UpdateResponse, error) {
+	if server.ReadOnly {
+		return nil, errReadOnly
+	}
+	changedEntry, err := fromProto(request.GetSwiftCode())
+	if err != nil {
+		return nil, err
+	}
+	if err := server.checkCountry(changedEntry); err != nil {
+		return nil, err
+	}
+	if err := server.DataStore.UpdateSwiftCode(changedEntry); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &swiftcodespb.UpdateResponse{SwiftCode: toProto(changedEntry)}, nil
+}
+
+func (server *SwiftCodeServer) Delete(ctx context.Context, request *swiftcodespb.DeleteRequest) (*swiftcodespb.DeleteResponse, error) {
+	if server.ReadOnly {
+		return nil, errReadOnly
+	}
+	requestedCode := strings.ToUpper(request.GetSwiftCode())
+	if requestedCode == "" {
+		return nil, status.Error(codes.InvalidArgument, "swift_code is required")
+	}
+	if err := server.DataStore.DeleteSwiftCode(requestedCode); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &swiftcodespb.DeleteResponse{}, nil
+}
+
+func (server *SwiftCodeServer) WatchChanges(request *swiftcodespb.WatchChangesRequest, stream grpc.ServerStreamingServer[swiftcodespb.Change]) error {
+	if server.Changes == nil {
+		return status.Error(codes.Unimplemented, "change feed not available")
+	}
+	if request.GetSince() < 0 {
+		return status.Error(codes.InvalidArgument, "since must not be negative")
+	}
+	err := server.Changes.Follow(stream.Context(), request.GetSince(), func(change models.SwiftCodeChange) error {
+		return stream.Send(&swiftcodespb.Change{
+			Sequence:  change.Sequence,
+			Type:      change.Type,
+			ChangedAt: timestamppb.New(change.ChangedAt),
+			SwiftCode: toProto(change.Code),
+		})
+	})
+	if err != nil {
+		if _, isStatus := status.FromError(err); isStatus {
+			return err
+		}
+		return statusFromError(err)
+	}
+	return nil
+}
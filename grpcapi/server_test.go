@@ -0,0 +1,212 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"swift-codes-project/changefeed"
+	"swift-codes-project/db"
+	"swift-codes-project/grpcapi/swiftcodespb"
+	"swift-codes-project/memstore"
+	"swift-codes-project/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startTestServer serves an in-memory repository over a bufconn listener and
+// returns a connected client.
+func startTestServer(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	t.Cleanup(func() { testDatabase.Close() })
+	repository := &service.SwiftRepository{DB: testDatabase}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := NewGRPCServer(&SwiftCodeServer{
+		DataStore: repository,
+		Changes:   &changefeed.Feed{Store: repository, PollInterval: 5 * time.Millisecond},
+	})
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	connection, dialError := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if dialError != nil {
+		t.Fatalf("Failed to dial test server: %v", dialError)
+	}
+	t.Cleanup(func() { connection.Close() })
+	return connection
+}
+
+// TestSwiftCodeServiceLifecycle creates a head office and branch, reads them
+// back in every supported way, and checks error codes on the failure paths.
+func TestSwiftCodeServiceLifecycle(t *testing.T) {
+	client := swiftcodespb.NewSwiftCodeServiceClient(startTestServer(t))
+	ctx := context.Background()
+
+	for _, code := range []string{"ZZBANKZZXXX", "zzbankzz001"} {
+		_, createError := client.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: &swiftcodespb.SwiftCode{
+			SwiftCode: code, CountryIso2: "zz", CountryName: "Zeland", BankName: "ZELAND NATIONAL BANK", TownName: "CAPITAL",
+		}})
+		if createError != nil {
+			t.Fatalf("Unexpected error creating %s: %v", code, createError)
+		}
+	}
+
+	_, duplicateError := client.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: &swiftcodespb.SwiftCode{
+		SwiftCode: "ZZBANKZZXXX", CountryIso2: "ZZ",
+	}})
+	if status.Code(duplicateError) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for duplicate create, got %v", duplicateError)
+	}
+	_, invalidError := client.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: &swiftcodespb.SwiftCode{SwiftCode: "SHORT"}})
+	if status.Code(invalidError) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a short code, got %v", invalidError)
+	}
+
+	getResponse, getError := client.Get(ctx, &swiftcodespb.GetRequest{SwiftCode: "zzbankzzxxx"})
+	if getError != nil {
+		t.Fatalf("Unexpected error getting head office: %v", getError)
+	}
+	if !getResponse.GetSwiftCode().GetIsHeadquarter() || len(getResponse.GetBranches()) != 1 {
+		t.Errorf("Expected head office with 1 branch, got %+v", getResponse)
+	}
+
+	batchResponse, _ := client.BatchGet(ctx, &swiftcodespb.BatchGetRequest{SwiftCodes: []string{"ZZBANKZZ001", "ZZBANKZZ999"}})
+	if len(batchResponse.GetSwiftCodes()) != 1 || len(batchResponse.GetMissing()) != 1 {
+		t.Errorf("Expected 1 found and 1 missing, got %+v", batchResponse)
+	}
+
+	searchResponse, _ := client.Search(ctx, &swiftcodespb.SearchRequest{Query: "capital"})
+	if len(searchResponse.GetSwiftCodes()) != 2 {
+		t.Errorf("Expected 2 search results for town CAPITAL, got %d", len(searchResponse.GetSwiftCodes()))
+	}
+
+	_, updateError := client.Update(ctx, &swiftcodespb.UpdateRequest{SwiftCode: &swiftcodespb.SwiftCode{
+		SwiftCode: "ZZBANKZZ001", CountryIso2: "ZZ", CountryName: "ZELAND", Address: "9 NEW SQUARE",
+	}})
+	if updateError != nil {
+		t.Fatalf("Unexpected error updating branch: %v", updateError)
+	}
+
+	if _, deleteError := client.Delete(ctx, &swiftcodespb.DeleteRequest{SwiftCode: "ZZBANKZZ001"}); deleteError != nil {
+		t.Fatalf("Unexpected error deleting branch: %v", deleteError)
+	}
+	_, missingError := client.Get(ctx, &swiftcodespb.GetRequest{SwiftCode: "ZZBANKZZ001"})
+	if status.Code(missingError) != codes.NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", missingError)
+	}
+	_, missingError = client.Delete(ctx, &swiftcodespb.DeleteRequest{SwiftCode: "ZZBANKZZ001"})
+	if status.Code(missingError) != codes.NotFound {
+		t.Errorf("Expected NotFound deleting twice, got %v", missingError)
+	}
+
+	// the change log holds 2 creates, 1 update and 1 delete
+	watchContext, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	changeStream, watchError := client.WatchChanges(watchContext, &swiftcodespb.WatchChangesRequest{Since: 1})
+	if watchError != nil {
+		t.Fatalf("Unexpected error watching changes: %v", watchError)
+	}
+	var changeTypes []string
+	for len(changeTypes) < 3 {
+		change, receiveError := changeStream.Recv()
+		if receiveError != nil {
+			t.Fatalf("Unexpected error receiving change: %v", receiveError)
+		}
+		changeTypes = append(changeTypes, change.GetType())
+	}
+	if changeTypes[0] != "created" || changeTypes[1] != "updated" || changeTypes[2] != "deleted" {
+		t.Errorf("Expected created, updated, deleted after sequence 1, got %v", changeTypes)
+	}
+}
+
+// TestHealthService reports the SWIFT code service as serving.
+func TestHealthService(t *testing.T) {
+	healthClient := healthpb.NewHealthClient(startTestServer(t))
+	response, checkError := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: swiftcodespb.SwiftCodeService_ServiceDesc.ServiceName,
+	})
+	if checkError != nil || response.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected SERVING, got %v (%v)", response.GetStatus(), checkError)
+	}
+}
+
+// TestReadOnlyServerRejectsWrites checks every mutating RPC is refused before
+// it reaches the data store, which is nil here.
+func TestReadOnlyServerRejectsWrites(t *testing.T) {
+	server := &SwiftCodeServer{ReadOnly: true}
+	ctx := context.Background()
+	entry := &swiftcodespb.SwiftCode{SwiftCode: "ZZBANKZZXXX", CountryIso2: "ZZ", CountryName: "ZELAND"}
+
+	_, createError := server.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: entry})
+	_, updateError := server.Update(ctx, &swiftcodespb.UpdateRequest{SwiftCode: entry})
+	_, deleteError := server.Delete(ctx, &swiftcodespb.DeleteRequest{SwiftCode: entry.SwiftCode})
+	for name, err := range map[string]error{"Create": createError, "Update": updateError, "Delete": deleteError} {
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected PermissionDenied, got %v", name, err)
+		}
+	}
+}
+
+// TestServerChecksCountries checks Create and Update reject codes whose
+// country is unknown or misnamed once the server has a country store.
+func TestServerChecksCountries(t *testing.T) {
+	store := &memstore.Store{}
+	server := &SwiftCodeServer{DataStore: store, Countries: store}
+	ctx := context.Background()
+
+	for _, invalid := range []*swiftcodespb.SwiftCode{
+		{SwiftCode: "ZZBANKZZXXX", CountryIso2: "ZZ", CountryName: "ZELAND"},
+		{SwiftCode: "PLBANKPLXXX", CountryIso2: "PL", CountryName: "GERMANY"},
+	} {
+		_, createError := server.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: invalid})
+		if status.Code(createError) != codes.InvalidArgument {
+			t.Errorf("%s %s: expected InvalidArgument, got %v", invalid.CountryIso2, invalid.CountryName, createError)
+		}
+	}
+
+	entry := &swiftcodespb.SwiftCode{SwiftCode: "PLBANKPLXXX", CountryIso2: "pl", CountryName: "Poland"}
+	if _, createError := server.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: entry}); createError != nil {
+		t.Fatalf("Unexpected error creating a Polish code: %v", createError)
+	}
+	entry.CountryName = "Republic of Poland"
+	if _, updateError := server.Update(ctx, &swiftcodespb.UpdateRequest{SwiftCode: entry}); updateError != nil {
+		t.Errorf("Expected the official name to be accepted, got %v", updateError)
+	}
+	entry.CountryName = "POLSKA"
+	if _, updateError := server.Update(ctx, &swiftcodespb.UpdateRequest{SwiftCode: entry}); status.Code(updateError) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a misnamed update, got %v", updateError)
+	}
+}
+
+func TestServerNormalizesTimeZones(t *testing.T) {
+	store := &memstore.Store{}
+	server := &SwiftCodeServer{DataStore: store}
+	ctx := context.Background()
+
+	entry := &swiftcodespb.SwiftCode{SwiftCode: "PLBANKPLXXX", CountryIso2: "PL", CountryName: "POLAND", TimeZone: "europe/warsaw"}
+	created, createError := server.Create(ctx, &swiftcodespb.CreateRequest{SwiftCode: entry})
+	if createError != nil {
+		t.Fatalf("Unexpected error creating a code: %v", createError)
+	}
+	if created.GetSwiftCode().GetTimeZone() != "Europe/Warsaw" {
+		t.Errorf("Expected Europe/Warsaw, got %q", created.GetSwiftCode().GetTimeZone())
+	}
+	entry.TimeZone = "Europe/Zeland"
+	if _, updateError := server.Update(ctx, &swiftcodespb.UpdateRequest{SwiftCode: entry}); status.Code(updateError) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown zone, got %v", updateError)
+	}
+}
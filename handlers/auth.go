@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminTokenEnv names the environment variable holding the token admin
+// routes require. Admin routes are refused when it is empty.
+const AdminTokenEnv = "SWIFT_ADMIN_TOKEN"
+
+// RequireAdmin only passes requests carrying token, as "Authorization: Bearer
+// <token>" or in X-API-Key, the two schemes the Go client sends. With an
+// empty token every request is refused, so a server without a configured
+// token never exposes admin routes.
+func RequireAdmin(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+		if token == "" {
+			writeError(responseWriter, http.StatusForbidden, "admin routes are disabled; set "+AdminTokenEnv)
+			return
+		}
+		presented := incomingRequest.Header.Get("X-API-Key")
+		if bearer, ok := strings.CutPrefix(incomingRequest.Header.Get("Authorization"), "Bearer "); ok {
+			presented = bearer
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			responseWriter.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(responseWriter, http.StatusUnauthorized, "admin token required")
+			return
+		}
+		next.ServeHTTP(responseWriter, incomingRequest)
+	})
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"swift-codes-project/models"
+)
+
+// BackupStore takes and lists copies of the database.
+type BackupStore interface {
+	CreateBackup(ctx context.Context) (models.Backup, error)
+	ListBackups() ([]models.Backup, error)
+}
+
+// POST /v1/admin/backups
+// Takes a backup while the server keeps serving. Answers 429 while the newest
+// backup is younger than the configured minimum interval.
+
+func (httpHandler *SwiftHTTPHandler) CreateBackup(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Backups == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "backups not available")
+		return
+	}
+	created, backupError := httpHandler.Backups.CreateBackup(incomingRequest.Context())
+	var tooSoon interface{ RetryAfter() time.Duration }
+	if errors.As(backupError, &tooSoon) {
+		responseWriter.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(tooSoon.RetryAfter().Seconds()))))
+		writeError(responseWriter, http.StatusTooManyRequests, backupError.Error())
+		return
+	}
+	if backupError != nil {
+		log.Printf("Backup failed: %v", backupError)
+		writeError(responseWriter, http.StatusInternalServerError, "backup failed")
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusCreated)
+	json.NewEncoder(responseWriter).Encode(created)
+}
+
+// GET /v1/admin/backups
+
+func (httpHandler *SwiftHTTPHandler) ListBackups(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Backups == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "backups not available")
+		return
+	}
+	backups, listError := httpHandler.Backups.ListBackups()
+	if listError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "could not list backups")
+		return
+	}
+	if backups == nil {
+		backups = []models.Backup{}
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(map[string]interface{}{"backups": backups})
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"swift-codes-project/models"
+)
+
+// stubBackupStore records backups without writing anything.
+type stubBackupStore struct {
+	backups []models.Backup
+	fail    bool
+	wait    time.Duration // answer as if the last backup is this recent
+}
+
+// stubTooSoon is a rate-limit error as backup.TooSoonError reports it.
+type stubTooSoon time.Duration
+
+func (wait stubTooSoon) Error() string             { return "the last backup was taken too recently" }
+func (wait stubTooSoon) RetryAfter() time.Duration { return time.Duration(wait) }
+
+func (stub *stubBackupStore) CreateBackup(ctx context.Context) (models.Backup, error) {
+	if stub.fail {
+		return models.Backup{}, errors.New("disk full")
+	}
+	if stub.wait > 0 {
+		return models.Backup{}, stubTooSoon(stub.wait)
+	}
+	created := models.Backup{Name: "swift_codes-20250102T030405.000000000Z.db.gz", Compression: "gzip", Size: 512,
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	stub.backups = append([]models.Backup{created}, stub.backups...)
+	return created, nil
+}
+
+func (stub *stubBackupStore) ListBackups() ([]models.Backup, error) {
+	return stub.backups, nil
+}
+
+// TestCreateBackupHandler takes a backup and expects it in the list.
+func TestCreateBackupHandler(t *testing.T) {
+	store := &stubBackupStore{}
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Backups: store}
+
+	listRecorder := httptest.NewRecorder()
+	handlerInstance.ListBackups(listRecorder, httptest.NewRequest(http.MethodGet, "/v1/admin/backups", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/admin/backups", listRecorder)
+
+	createRecorder := httptest.NewRecorder()
+	handlerInstance.CreateBackup(createRecorder, httptest.NewRequest(http.MethodPost, "/v1/admin/backups", nil))
+	assertMatchesContract(t, http.MethodPost, "/v1/admin/backups", createRecorder)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 Created, got %d", createRecorder.Code)
+	}
+
+	listRecorder = httptest.NewRecorder()
+	handlerInstance.ListBackups(listRecorder, httptest.NewRequest(http.MethodGet, "/v1/admin/backups", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/admin/backups", listRecorder)
+	var listPayload struct {
+		Backups []models.Backup `json:"backups"`
+	}
+	json.NewDecoder(listRecorder.Body).Decode(&listPayload)
+	if len(listPayload.Backups) != 1 || listPayload.Backups[0].Compression != "gzip" {
+		t.Errorf("Expected the new backup in the list, got %+v", listPayload.Backups)
+	}
+}
+
+// TestCreateBackupHandler_Failures covers a failing backup and a server
+// without backups configured.
+func TestCreateBackupHandler_Failures(t *testing.T) {
+	failing := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Backups: &stubBackupStore{fail: true}}
+	responseRecorder := httptest.NewRecorder()
+	failing.CreateBackup(responseRecorder, httptest.NewRequest(http.MethodPost, "/v1/admin/backups", nil))
+	assertMatchesContract(t, http.MethodPost, "/v1/admin/backups", responseRecorder)
+	if responseRecorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for a failed backup, got %d", responseRecorder.Code)
+	}
+
+	unconfigured := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	responseRecorder = httptest.NewRecorder()
+	unconfigured.CreateBackup(responseRecorder, httptest.NewRequest(http.MethodPost, "/v1/admin/backups", nil))
+	assertMatchesContract(t, http.MethodPost, "/v1/admin/backups", responseRecorder)
+	if responseRecorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without backups, got %d", responseRecorder.Code)
+	}
+}
+
+// TestCreateBackupHandler_RateLimited expects 429 with Retry-After while the
+// last backup is too recent.
+func TestCreateBackupHandler_RateLimited(t *testing.T) {
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Backups: &stubBackupStore{wait: 90 * time.Second}}
+	responseRecorder := httptest.NewRecorder()
+	handlerInstance.CreateBackup(responseRecorder, httptest.NewRequest(http.MethodPost, "/v1/admin/backups", nil))
+	assertMatchesContract(t, http.MethodPost, "/v1/admin/backups", responseRecorder)
+	if responseRecorder.Code != http.StatusTooManyRequests || responseRecorder.Header().Get("Retry-After") != "90" {
+		t.Errorf("Expected 429 with Retry-After 90, got %d %q", responseRecorder.Code, responseRecorder.Header().Get("Retry-After"))
+	}
+}
+
+// TestBackupRoutesAreGuarded expects the backup routes to need the admin
+// token, and taking a backup to be refused on a read-only server.
+func TestBackupRoutesAreGuarded(t *testing.T) {
+	store := &stubBackupStore{}
+	router := NewRouter(&SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Backups: store, AdminToken: "s3cret"})
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, httptest.NewRequest(method, "/v1/admin/backups", nil))
+		assertMatchesContract(t, method, "/v1/admin/backups", responseRecorder)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("%s without the token: expected status 401, got %d", method, responseRecorder.Code)
+		}
+	}
+
+	readOnly := NewRouter(&SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Backups: store, AdminToken: "s3cret", ReadOnly: true})
+	testRequest := httptest.NewRequest(http.MethodPost, "/v1/admin/backups", nil)
+	testRequest.Header.Set("Authorization", "Bearer s3cret")
+	responseRecorder := httptest.NewRecorder()
+	readOnly.ServeHTTP(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodPost, "/v1/admin/backups", responseRecorder)
+	if responseRecorder.Code != http.StatusForbidden || len(store.backups) != 0 {
+		t.Errorf("Expected a read-only server to refuse backups with 403, got %d", responseRecorder.Code)
+	}
+}
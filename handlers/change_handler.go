@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"swift-codes-project/models"
+)
+
+const (
+	defaultChangeLimit = 100
+	maxChangeLimit     = 1000
+	maxChangeWait      = 60 * time.Second
+	// streamHeartbeat is how often an idle event stream sends a comment line
+	// so proxies do not close the connection.
+	streamHeartbeat = 15 * time.Second
+)
+
+type changeListResponsePayload struct {
+	Changes      []models.SwiftCodeChange `json:"changes"`
+	LastSequence int64                    `json:"lastSequence"`
+}
+
+// parseSequence reads a non-negative change sequence number; empty means 0.
+func parseSequence(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	sequence, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || sequence < 0 {
+		return 0, fmt.Errorf("invalid sequence %q", value)
+	}
+	return sequence, nil
+}
+
+// GET /v1/changes?since={seq}&limit={n}&wait={duration}
+// With wait set, the request is held open until a change arrives or the wait
+// elapses (long-polling).
+
+func (httpHandler *SwiftHTTPHandler) ListChanges(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Changes == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "change feed not available")
+		return
+	}
+	queryValues := incomingRequest.URL.Query()
+
+	since, parseError := parseSequence(queryValues.Get("since"))
+	if parseError != nil {
+		writeError(responseWriter, http.StatusBadRequest, "invalid since")
+		return
+	}
+	limit := defaultChangeLimit
+	if limitValue := queryValues.Get("limit"); limitValue != "" {
+		parsedLimit, err := strconv.Atoi(limitValue)
+		if err != nil || parsedLimit <= 0 {
+			writeError(responseWriter, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(parsedLimit, maxChangeLimit)
+	}
+	var wait time.Duration
+	if waitValue := queryValues.Get("wait"); waitValue != "" {
+		parsedWait, err := time.ParseDuration(waitValue)
+		if err != nil || parsedWait < 0 {
+			writeError(responseWriter, http.StatusBadRequest, "invalid wait")
+			return
+		}
+		wait = min(parsedWait, maxChangeWait)
+	}
+
+	waitContext, cancel := context.WithTimeout(incomingRequest.Context(), wait)
+	defer cancel()
+	changes, queryError := httpHandler.Changes.Wait(waitContext, since, limit)
+	if queryError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+
+	payload := changeListResponsePayload{Changes: changes, LastSequence: since}
+	if payload.Changes == nil {
+		payload.Changes = []models.SwiftCodeChange{}
+	}
+	if len(changes) > 0 {
+		payload.LastSequence = changes[len(changes)-1].Sequence
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(payload)
+}
+
+// GET /v1/changes/stream?since={seq}
+// Server-Sent Events; each event's id is its sequence number, so a
+// reconnecting EventSource resumes via the Last-Event-ID header.
+
+func (httpHandler *SwiftHTTPHandler) StreamChanges(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Changes == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "change feed not available")
+		return
+	}
+	flusher, canFlush := responseWriter.(http.Flusher)
+	if !canFlush {
+		writeError(responseWriter, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	sinceValue := incomingRequest.URL.Query().Get("since")
+	if lastEventID := incomingRequest.Header.Get("Last-Event-ID"); lastEventID != "" {
+		sinceValue = lastEventID
+	}
+	since, parseError := parseSequence(sinceValue)
+	if parseError != nil {
+		writeError(responseWriter, http.StatusBadRequest, "invalid since")
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/event-stream")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	responseWriter.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	requestContext := incomingRequest.Context()
+	for requestContext.Err() == nil {
+		waitContext, cancel := context.WithTimeout(requestContext, streamHeartbeat)
+		changes, queryError := httpHandler.Changes.Wait(waitContext, since, defaultChangeLimit)
+		cancel()
+		if queryError != nil {
+			fmt.Fprintf(responseWriter, "event: error\ndata: {\"error\":\"db failure\"}\n\n")
+			flusher.Flush()
+			return
+		}
+		if len(changes) == 0 {
+			fmt.Fprint(responseWriter, ": keep-alive\n\n")
+		}
+		for _, change := range changes {
+			encodedChange, _ := json.Marshal(change)
+			fmt.Fprintf(responseWriter, "id: %d\nevent: %s\ndata: %s\n\n", change.Sequence, change.Type, encodedChange)
+			since = change.Sequence
+		}
+		flusher.Flush()
+	}
+}
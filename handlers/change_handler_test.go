@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"swift-codes-project/changefeed"
+	"swift-codes-project/models"
+)
+
+// stubChangeStore serves changes appended by the test, like the change log table.
+type stubChangeStore struct {
+	mutex   sync.Mutex
+	changes []models.SwiftCodeChange
+}
+
+func (stub *stubChangeStore) append(changeType, swiftCode string) {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	stub.changes = append(stub.changes, models.SwiftCodeChange{
+		Sequence: int64(len(stub.changes) + 1),
+		Type:     changeType,
+		Code:     models.SwiftCode{SwiftCode: swiftCode},
+	})
+}
+
+func (stub *stubChangeStore) ListChanges(since int64, limit int) ([]models.SwiftCodeChange, error) {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	var result []models.SwiftCodeChange
+	for _, change := range stub.changes {
+		if change.Sequence > since && len(result) < limit {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+func newChangeTestHandler(store *stubChangeStore) *SwiftHTTPHandler {
+	return &SwiftHTTPHandler{
+		DataStore: &stubSwiftRepository{},
+		Changes:   &changefeed.Feed{Store: store, PollInterval: 5 * time.Millisecond},
+	}
+}
+
+// TestListChangesHandler_LongPoll holds the request open until a change is
+// appended, then returns it.
+func TestListChangesHandler_LongPoll(t *testing.T) {
+	store := &stubChangeStore{}
+	store.append(models.ChangeCreated, "ZZBANKXXX")
+	handlerInstance := newChangeTestHandler(store)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		store.append(models.ChangeDeleted, "ZZBANKXXX")
+	}()
+
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/changes?since=1&wait=5s", nil)
+	responseRecorder := httptest.NewRecorder()
+	handlerInstance.ListChanges(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/changes", responseRecorder)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", responseRecorder.Code)
+	}
+	var decodedPayload changeListResponsePayload
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode JSON response: %v", decodeError)
+	}
+	if len(decodedPayload.Changes) != 1 || decodedPayload.Changes[0].Type != models.ChangeDeleted {
+		t.Fatalf("Expected the delete change, got %+v", decodedPayload.Changes)
+	}
+	if decodedPayload.LastSequence != 2 {
+		t.Errorf("Expected lastSequence 2, got %d", decodedPayload.LastSequence)
+	}
+}
+
+// TestListChangesHandler_NoWait returns an empty list straight away.
+func TestListChangesHandler_NoWait(t *testing.T) {
+	handlerInstance := newChangeTestHandler(&stubChangeStore{})
+
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/changes?since=5", nil)
+	responseRecorder := httptest.NewRecorder()
+	handlerInstance.ListChanges(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/changes", responseRecorder)
+
+	var decodedPayload changeListResponsePayload
+	json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload)
+	if len(decodedPayload.Changes) != 0 || decodedPayload.LastSequence != 5 {
+		t.Errorf("Expected no changes and lastSequence 5, got %+v", decodedPayload)
+	}
+}
+
+// TestStreamChangesHandler resumes from Last-Event-ID and reads events over a
+// real connection.
+func TestStreamChangesHandler(t *testing.T) {
+	store := &stubChangeStore{}
+	store.append(models.ChangeCreated, "ZZBANKXXX")
+	store.append(models.ChangeCreated, "ZZBANK001")
+	store.append(models.ChangeDeleted, "ZZBANK001")
+	testServer := httptest.NewServer(http.HandlerFunc(newChangeTestHandler(store).StreamChanges))
+	defer testServer.Close()
+
+	streamRequest, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	streamRequest.Header.Set("Last-Event-ID", "1")
+	response, requestError := http.DefaultClient.Do(streamRequest)
+	if requestError != nil {
+		t.Fatalf("Failed to open stream: %v", requestError)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected Content-Type 'text/event-stream', got '%s'", contentType)
+	}
+
+	var eventIDs []string
+	scanner := bufio.NewScanner(response.Body)
+	for len(eventIDs) < 2 && scanner.Scan() {
+		if eventID, isID := strings.CutPrefix(scanner.Text(), "id: "); isID {
+			eventIDs = append(eventIDs, eventID)
+		}
+	}
+	if strings.Join(eventIDs, ",") != "2,3" {
+		t.Errorf("Expected events 2 and 3, got %v", eventIDs)
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const openAPIResourceURL = "openapi.json"
+
+// openAPISpec is the part of the OpenAPI document the contract checks walk.
+type openAPISpec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Headers   map[string]openAPIHeader   `json:"headers"`
+		Responses map[string]json.RawMessage `json:"responses"`
+		Schemas   map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+type openAPIResponse struct {
+	Ref     string                     `json:"$ref"`
+	Headers map[string]openAPIHeader   `json:"headers"`
+	Content map[string]json.RawMessage `json:"content"`
+}
+
+type openAPIHeader struct {
+	Ref      string `json:"$ref"`
+	Required bool   `json:"required"`
+}
+
+var (
+	loadSpecOnce   sync.Once
+	loadedSpec     openAPISpec
+	schemaCompiler *jsonschema.Compiler
+	loadSpecError  error
+)
+
+func loadSpec(t *testing.T) openAPISpec {
+	t.Helper()
+	loadSpecOnce.Do(func() {
+		if loadSpecError = json.Unmarshal(OpenAPIDocument, &loadedSpec); loadSpecError != nil {
+			return
+		}
+		schemaCompiler = jsonschema.NewCompiler()
+		schemaCompiler.Draft = jsonschema.Draft2020
+		schemaCompiler.AssertFormat = true
+		loadSpecError = schemaCompiler.AddResource(openAPIResourceURL, bytes.NewReader(OpenAPIDocument))
+	})
+	if loadSpecError != nil {
+		t.Fatalf("Failed to load OpenAPI document: %v", loadSpecError)
+	}
+	return loadedSpec
+}
+
+// escapePointer escapes a JSON pointer token.
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// assertMatchesContract fails the test when the recorded response is not
+// documented for the operation, or when a JSON body does not match its schema.
+func assertMatchesContract(t *testing.T, method, pathTemplate string, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	spec := loadSpec(t)
+
+	rawOperation, ok := spec.Paths[pathTemplate][strings.ToLower(method)]
+	if !ok {
+		t.Fatalf("Contract: %s %s is not documented", method, pathTemplate)
+	}
+	var operation openAPIOperation
+	json.Unmarshal(rawOperation, &operation)
+
+	statusCode := strconv.Itoa(recorder.Code)
+	rawResponse, ok := operation.Responses[statusCode]
+	if !ok {
+		t.Fatalf("Contract: status %s is not documented for %s %s", statusCode, method, pathTemplate)
+	}
+	responsePointer := "/paths/" + escapePointer(pathTemplate) + "/" + strings.ToLower(method) + "/responses/" + statusCode
+	var response openAPIResponse
+	json.Unmarshal(rawResponse, &response)
+	if response.Ref != "" {
+		name := strings.TrimPrefix(response.Ref, "#/components/responses/")
+		json.Unmarshal(spec.Components.Responses[name], &response)
+		responsePointer = "/components/responses/" + escapePointer(name)
+	}
+
+	for name, header := range response.Headers {
+		if header.Ref != "" {
+			header = spec.Components.Headers[strings.TrimPrefix(header.Ref, "#/components/headers/")]
+		}
+		if header.Required && recorder.Header().Get(name) == "" {
+			t.Errorf("Contract: %s %s %s is missing the %s header", method, pathTemplate, statusCode, name)
+		}
+	}
+	if len(response.Content) == 0 {
+		if recorder.Body.Len() != 0 {
+			t.Fatalf("Contract: %s %s %s documents no body but sent %q", method, pathTemplate, statusCode, recorder.Body.String())
+		}
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	if _, ok := response.Content[mediaType]; !ok {
+		t.Fatalf("Contract: content type %q is not documented for %s %s %s", mediaType, method, pathTemplate, statusCode)
+	}
+	if mediaType != "application/json" {
+		return
+	}
+
+	schema, err := schemaCompiler.Compile(openAPIResourceURL + "#" + responsePointer + "/content/" + escapePointer(mediaType) + "/schema")
+	if err != nil {
+		t.Fatalf("Contract: cannot compile schema for %s %s %s: %v", method, pathTemplate, statusCode, err)
+	}
+	var body interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Contract: %s %s %s returned invalid JSON: %v", method, pathTemplate, statusCode, err)
+	}
+	if err := schema.Validate(body); err != nil {
+		t.Errorf("Contract: %s %s %s does not match the schema: %#v", method, pathTemplate, statusCode, err)
+	}
+}
+
+// undocumentedRoutes serve the documentation itself.
+var undocumentedRoutes = map[string]bool{"/openapi.json": true, "/docs": true, "/docs/": true}
+
+// TestRouterMatchesOpenAPIDocument checks that every registered route is
+// documented and every documented operation is routed.
+func TestRouterMatchesOpenAPIDocument(t *testing.T) {
+	spec := loadSpec(t)
+
+	routed := map[string]bool{}
+	NewRouter(&SwiftHTTPHandler{}).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		if undocumentedRoutes[pathTemplate] {
+			return nil
+		}
+		for _, method := range methods {
+			operation := method + " " + pathTemplate
+			routed[operation] = true
+			if _, ok := spec.Paths[pathTemplate][strings.ToLower(method)]; !ok {
+				t.Errorf("Route %s is not in the OpenAPI document", operation)
+			}
+		}
+		return nil
+	})
+
+	var documented []string
+	for pathTemplate, operations := range spec.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			documented = append(documented, strings.ToUpper(method)+" "+pathTemplate)
+		}
+	}
+	sort.Strings(documented)
+	for _, operation := range documented {
+		if !routed[operation] {
+			t.Errorf("Documented operation %s has no route", operation)
+		}
+	}
+}
+
+// TestOpenAPISchemasCompile makes sure every component schema is valid JSON
+// Schema, so a typo in the document fails here rather than in a handler test.
+func TestOpenAPISchemasCompile(t *testing.T) {
+	spec := loadSpec(t)
+	for name := range spec.Components.Schemas {
+		if _, err := schemaCompiler.Compile(openAPIResourceURL + "#/components/schemas/" + name); err != nil {
+			t.Errorf("Schema %s does not compile: %v", name, err)
+		}
+	}
+}
+
+// TestServeOpenAPIDocument checks the document and the Swagger UI are served.
+func TestServeOpenAPIDocument(t *testing.T) {
+	router := NewRouter(&SwiftHTTPHandler{})
+
+	documentRecorder := httptest.NewRecorder()
+	router.ServeHTTP(documentRecorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var document map[string]interface{}
+	if err := json.Unmarshal(documentRecorder.Body.Bytes(), &document); err != nil || document["openapi"] != "3.1.0" {
+		t.Fatalf("Expected an OpenAPI 3.1.0 document, got error %v", err)
+	}
+
+	docsRecorder := httptest.NewRecorder()
+	router.ServeHTTP(docsRecorder, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	if docsRecorder.Code != http.StatusOK || !strings.Contains(docsRecorder.Body.String(), "/openapi.json") {
+		t.Errorf("Expected the Swagger UI page, got %d", docsRecorder.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"swift-codes-project/countries"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// CountryStore serves the ISO 3166-1 reference data swift codes are checked
+// against.
+type CountryStore interface {
+	ListCountries() ([]models.Country, error)
+	GetCountry(iso2 string) (models.Country, error)
+}
+
+// GET /v1/countries
+// Lists every ISO 3166-1 country with how many swift codes it has.
+
+func (httpHandler *SwiftHTTPHandler) ListCountries(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Countries == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "countries not available")
+		return
+	}
+	list, listError := httpHandler.Countries.ListCountries()
+	if listError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	if list == nil {
+		list = []models.Country{}
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(map[string]interface{}{"countries": list})
+}
+
+// CheckCountry validates a swift code's country against store: the ISO-2 code
+// must exist and the country name must be its short or official name. It
+// returns a message for the client, or "" when the country is valid.
+func CheckCountry(store CountryStore, entry models.SwiftCode) (string, error) {
+	country, lookupError := store.GetCountry(strings.ToUpper(entry.CountryISO2))
+	if errors.Is(lookupError, service.ErrNotFound) {
+		return "unknown countryISO2 " + entry.CountryISO2, nil
+	}
+	if lookupError != nil {
+		return "", lookupError
+	}
+	if countries.CheckName(country, entry.CountryName) != nil {
+		return "countryName does not match countryISO2 " + entry.CountryISO2 + " (" + country.Name + ")", nil
+	}
+	return "", nil
+}
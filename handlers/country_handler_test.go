@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"swift-codes-project/memstore"
+	"swift-codes-project/models"
+)
+
+// TestListCountriesHandler lists the embedded countries with their counts.
+func TestListCountriesHandler(t *testing.T) {
+	store := &memstore.Store{}
+	store.Load([]models.SwiftCode{
+		{CountryISO2: "PL", SwiftCode: "PLBANKPLXXX", CountryName: "POLAND", IsHeadquarter: true},
+		{CountryISO2: "PL", SwiftCode: "PLBANKPL001", CountryName: "POLAND", HqSwiftCode: "PLBANKPLXXX"},
+	})
+	handlerInstance := &SwiftHTTPHandler{DataStore: store, Countries: store}
+
+	recorder := httptest.NewRecorder()
+	handlerInstance.ListCountries(recorder, httptest.NewRequest(http.MethodGet, "/v1/countries", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/countries", recorder)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", recorder.Code)
+	}
+	var listPayload struct {
+		Countries []models.Country `json:"countries"`
+	}
+	json.NewDecoder(recorder.Body).Decode(&listPayload)
+	if len(listPayload.Countries) != 249 {
+		t.Fatalf("Expected all 249 ISO 3166-1 countries, got %d", len(listPayload.Countries))
+	}
+	for _, country := range listPayload.Countries {
+		expected := 0
+		if country.ISO2 == "PL" {
+			expected = 2
+		}
+		if country.SwiftCodeCount != expected {
+			t.Errorf("%s: expected %d swift codes, got %d", country.ISO2, expected, country.SwiftCodeCount)
+		}
+	}
+}
+
+func TestListCountriesWithoutStore(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	(&SwiftHTTPHandler{}).ListCountries(recorder, httptest.NewRequest(http.MethodGet, "/v1/countries", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/countries", recorder)
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 Not Implemented, got %d", recorder.Code)
+	}
+}
+
+// TestCreateSwiftCodeChecksCountry rejects unknown and misnamed countries
+// once the handler has a country store.
+func TestCreateSwiftCodeChecksCountry(t *testing.T) {
+	store := &memstore.Store{}
+	handlerInstance := &SwiftHTTPHandler{DataStore: store, Countries: store}
+
+	testCases := []struct {
+		iso2, countryName string
+		expectedStatus    int
+	}{
+		{"ZZ", "ZELAND", http.StatusBadRequest},
+		{"PL", "GERMANY", http.StatusBadRequest},
+		{"PL", "", http.StatusBadRequest},
+		{"pl", "Republic of Poland", http.StatusCreated},
+	}
+	for _, testCase := range testCases {
+		body, _ := json.Marshal(map[string]interface{}{
+			"swiftCode": "PLBANKPLXXX", "bankName": "POLISH BANK", "isHeadquarter": true,
+			"countryISO2": testCase.iso2, "countryName": testCase.countryName,
+		})
+		recorder := httptest.NewRecorder()
+		handlerInstance.CreateSwiftCode(recorder, httptest.NewRequest(http.MethodPost, "/v1/swift-codes", bytes.NewReader(body)))
+		assertMatchesContract(t, http.MethodPost, "/v1/swift-codes", recorder)
+		if recorder.Code != testCase.expectedStatus {
+			t.Errorf("%s %q: expected status %d, got %d: %s", testCase.iso2, testCase.countryName,
+				testCase.expectedStatus, recorder.Code, recorder.Body.String())
+		}
+	}
+}
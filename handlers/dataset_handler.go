@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"swift-codes-project/diff"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+
+	"github.com/gorilla/mux"
+)
+
+// DatasetStore answers lookups from historical dataset versions.
+type DatasetStore interface {
+	ListDatasetVersions() ([]models.DatasetVersion, error)
+	GetDatasetVersion(reference string) (models.DatasetVersion, error)
+	GetDatasetVersionAsOf(asOf time.Time) (models.DatasetVersion, error)
+	GetSwiftCodeAtVersion(requestedCode string, version int64) (models.SwiftCode, []models.SwiftCode, error)
+	GetCountrySwiftCodesAtVersion(requestedISO2 string, version int64) ([]models.SwiftCode, error)
+	ListSwiftCodesAtVersion(version int64) ([]models.SwiftCode, error)
+}
+
+// liveDatasetReference names the current swift_codes table in diff requests.
+const liveDatasetReference = "live"
+
+var errBadAsOf = errors.New("asOf must be a date (2006-01-02) or RFC 3339 timestamp")
+
+// parseAsOf accepts a plain date, meaning the end of that day in UTC, or a full
+// RFC 3339 timestamp.
+func parseAsOf(value string) (time.Time, error) {
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		return day.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	if instant, err := time.Parse(time.RFC3339, value); err == nil {
+		return instant, nil
+	}
+	return time.Time{}, errBadAsOf
+}
+
+// requestedDatasetVersion looks at the ?version= and ?asOf= query parameters.
+// It reports historical=false when neither is present and the live table should be used.
+func (httpHandler *SwiftHTTPHandler) requestedDatasetVersion(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) (datasetVersion models.DatasetVersion, historical bool, handled bool) {
+	queryValues := incomingRequest.URL.Query()
+	versionReference, asOfValue := queryValues.Get("version"), queryValues.Get("asOf")
+	if versionReference == "" && asOfValue == "" {
+		return datasetVersion, false, false
+	}
+	if httpHandler.Datasets == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "dataset history not available")
+		return datasetVersion, false, true
+	}
+
+	var lookupError error
+	if versionReference != "" {
+		datasetVersion, lookupError = httpHandler.Datasets.GetDatasetVersion(versionReference)
+	} else {
+		asOf, parseError := parseAsOf(asOfValue)
+		if parseError != nil {
+			writeError(responseWriter, http.StatusBadRequest, "invalid asOf")
+			return datasetVersion, false, true
+		}
+		datasetVersion, lookupError = httpHandler.Datasets.GetDatasetVersionAsOf(asOf)
+	}
+	if lookupError != nil {
+		writeError(responseWriter, http.StatusNotFound, "dataset version not found")
+		return datasetVersion, false, true
+	}
+	return datasetVersion, true, false
+}
+
+// GET /v1/admin/datasets
+
+func (httpHandler *SwiftHTTPHandler) ListDatasetVersions(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Datasets == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "dataset history not available")
+		return
+	}
+	versions, queryError := httpHandler.Datasets.ListDatasetVersions()
+	if queryError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	if versions == nil {
+		versions = []models.DatasetVersion{}
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(map[string]interface{}{"versions": versions})
+}
+
+// GET /v1/admin/datasets/{a}/diff/{b}?format=json|text
+// Either side may be "live" to compare against the current table.
+
+func (httpHandler *SwiftHTTPHandler) DiffDatasetVersions(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Datasets == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "dataset history not available")
+		return
+	}
+	pathVariables := mux.Vars(incomingRequest)
+
+	oldCodes, loadError := httpHandler.loadDataset(pathVariables["a"])
+	if loadError == nil {
+		var newCodes []models.SwiftCode
+		if newCodes, loadError = httpHandler.loadDataset(pathVariables["b"]); loadError == nil {
+			writeDiff(responseWriter, incomingRequest, diff.Compare(oldCodes, newCodes))
+			return
+		}
+	}
+	if errors.Is(loadError, service.ErrDatasetVersionNotFound) {
+		writeError(responseWriter, http.StatusNotFound, "dataset version not found")
+		return
+	}
+	writeError(responseWriter, http.StatusInternalServerError, "db failure")
+}
+
+// loadDataset returns every row of a version reference, or of the live table.
+func (httpHandler *SwiftHTTPHandler) loadDataset(reference string) ([]models.SwiftCode, error) {
+	if reference == liveDatasetReference {
+		var liveCodes []models.SwiftCode
+		err := httpHandler.DataStore.ExportSwiftCodes("", func(sc models.SwiftCode) error {
+			liveCodes = append(liveCodes, sc)
+			return nil
+		})
+		return liveCodes, err
+	}
+	datasetVersion, err := httpHandler.Datasets.GetDatasetVersion(reference)
+	if err != nil {
+		return nil, err
+	}
+	return httpHandler.Datasets.ListSwiftCodesAtVersion(datasetVersion.Version)
+}
+
+func writeDiff(responseWriter http.ResponseWriter, incomingRequest *http.Request, result diff.Result) {
+	if incomingRequest.URL.Query().Get("format") == "text" {
+		responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		result.WriteText(responseWriter)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(result)
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"swift-codes-project/diff"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+var errNotFoundStub = service.ErrDatasetVersionNotFound
+
+// stubDatasetStore knows a single version, 7, named "september".
+type stubDatasetStore struct{}
+
+var stubVersion = models.DatasetVersion{
+	Version:       7,
+	Name:          "september",
+	EffectiveFrom: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+	RowCount:      1,
+}
+
+func (stub *stubDatasetStore) ListDatasetVersions() ([]models.DatasetVersion, error) {
+	return []models.DatasetVersion{stubVersion}, nil
+}
+
+func (stub *stubDatasetStore) GetDatasetVersion(reference string) (models.DatasetVersion, error) {
+	if reference == "7" || reference == stubVersion.Name {
+		return stubVersion, nil
+	}
+	return models.DatasetVersion{}, errNotFoundStub
+}
+
+func (stub *stubDatasetStore) GetDatasetVersionAsOf(asOf time.Time) (models.DatasetVersion, error) {
+	if asOf.Before(stubVersion.EffectiveFrom) {
+		return models.DatasetVersion{}, errNotFoundStub
+	}
+	return stubVersion, nil
+}
+
+// GetSwiftCodeAtVersion returns a branch with a recognisable historical address.
+func (stub *stubDatasetStore) GetSwiftCodeAtVersion(requestedCode string, version int64) (models.SwiftCode, []models.SwiftCode, error) {
+	return models.SwiftCode{
+		SwiftCode:   requestedCode,
+		Address:     "HISTORICAL ADDRESS",
+		CountryISO2: "ZZ",
+	}, nil, nil
+}
+
+func (stub *stubDatasetStore) GetCountrySwiftCodesAtVersion(requestedISO2 string, version int64) ([]models.SwiftCode, error) {
+	return nil, nil
+}
+
+// ListSwiftCodesAtVersion returns the head office with its historical address.
+func (stub *stubDatasetStore) ListSwiftCodesAtVersion(version int64) ([]models.SwiftCode, error) {
+	return []models.SwiftCode{{
+		SwiftCode:     "ZZBANKXXX",
+		Address:       "HISTORICAL ADDRESS",
+		Name:          "HQ Bank",
+		CountryISO2:   "ZZ",
+		CountryName:   "ZELAND",
+		IsHeadquarter: true,
+	}}, nil
+}
+
+// TestGetSwiftCodeHandler_AsOf answers from the historical store when ?asOf= is set.
+func TestGetSwiftCodeHandler_AsOf(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZBANK001?asOf=2026-09-15", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"code": "ZZBANK001"})
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Datasets: &stubDatasetStore{}}
+	handlerInstance.GetSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", responseRecorder)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", responseRecorder.Code)
+	}
+	var decodedPayload branchResponsePayload
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode JSON response: %v", decodeError)
+	}
+	if decodedPayload.Address != "HISTORICAL ADDRESS" {
+		t.Errorf("Expected historical address, got '%s'", decodedPayload.Address)
+	}
+}
+
+// TestGetSwiftCodeHandler_UnknownVersion expects 404 for versions that do not
+// exist and 400 for unparseable dates.
+func TestGetSwiftCodeHandler_UnknownVersion(t *testing.T) {
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Datasets: &stubDatasetStore{}}
+
+	for target, expectedStatus := range map[string]int{
+		"/v1/swift-codes/ZZBANK001?version=unknown":   http.StatusNotFound,
+		"/v1/swift-codes/ZZBANK001?asOf=2026-08-01":   http.StatusNotFound,
+		"/v1/swift-codes/ZZBANK001?asOf=last-tuesday": http.StatusBadRequest,
+	} {
+		testRequest := httptest.NewRequest(http.MethodGet, target, nil)
+		testRequest = mux.SetURLVars(testRequest, map[string]string{"code": "ZZBANK001"})
+		responseRecorder := httptest.NewRecorder()
+
+		handlerInstance.GetSwiftCode(responseRecorder, testRequest)
+		assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", responseRecorder)
+
+		if responseRecorder.Code != expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", target, expectedStatus, responseRecorder.Code)
+		}
+	}
+}
+
+// TestListDatasetVersionsHandler tests the GET /v1/admin/datasets handler.
+func TestListDatasetVersionsHandler(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/admin/datasets", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Datasets: &stubDatasetStore{}}
+	handlerInstance.ListDatasetVersions(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/admin/datasets", responseRecorder)
+
+	var decodedPayload struct {
+		Versions []models.DatasetVersion `json:"versions"`
+	}
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode JSON response: %v", decodeError)
+	}
+	if len(decodedPayload.Versions) != 1 || decodedPayload.Versions[0].Name != "september" {
+		t.Errorf("Expected the 'september' version, got %+v", decodedPayload.Versions)
+	}
+}
+
+// TestDiffDatasetVersionsHandler compares version 7 with the live stub table,
+// where the head office moved and a branch was added.
+func TestDiffDatasetVersionsHandler(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/admin/datasets/7/diff/live", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"a": "7", "b": "live"})
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Datasets: &stubDatasetStore{}}
+	handlerInstance.DiffDatasetVersions(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/admin/datasets/{a}/diff/{b}", responseRecorder)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", responseRecorder.Code)
+	}
+	var decodedPayload diff.Result
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode JSON response: %v", decodeError)
+	}
+	if len(decodedPayload.Added) != 1 || len(decodedPayload.Removed) != 0 || len(decodedPayload.Modified) != 1 {
+		t.Fatalf("Expected 1 added and 1 modified code, got %+v", decodedPayload)
+	}
+	if decodedPayload.Modified[0].SwiftCode != "ZZBANKXXX" {
+		t.Errorf("Expected ZZBANKXXX to be modified, got %s", decodedPayload.Modified[0].SwiftCode)
+	}
+}
+
+// TestDiffDatasetVersionsHandler_UnknownVersion expects a 404.
+func TestDiffDatasetVersionsHandler_UnknownVersion(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/admin/datasets/7/diff/99", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"a": "7", "b": "99"})
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Datasets: &stubDatasetStore{}}
+	handlerInstance.DiffDatasetVersions(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/admin/datasets/{a}/diff/{b}", responseRecorder)
+
+	if responseRecorder.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404 Not Found, got %d", responseRecorder.Code)
+	}
+}
+
+// TestDatasetRoutesAreGuarded expects the dataset routes to need the admin
+// token, since a diff loads two whole datasets.
+func TestDatasetRoutesAreGuarded(t *testing.T) {
+	router := NewRouter(&SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Datasets: &stubDatasetStore{}, AdminToken: "s3cret"})
+	for _, route := range []struct{ path, template string }{
+		{"/v1/admin/datasets", "/v1/admin/datasets"},
+		{"/v1/admin/datasets/7/diff/live", "/v1/admin/datasets/{a}/diff/{b}"},
+	} {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, route.path, nil))
+		assertMatchesContract(t, http.MethodGet, route.template, responseRecorder)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("%s without the token: expected status 401, got %d", route.path, responseRecorder.Code)
+		}
+
+		testRequest := httptest.NewRequest(http.MethodGet, route.path, nil)
+		testRequest.Header.Set("Authorization", "Bearer s3cret")
+		responseRecorder = httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, testRequest)
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("%s with the token: expected status 200, got %d", route.path, responseRecorder.Code)
+		}
+	}
+}
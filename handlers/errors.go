@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// errorResponsePayload is the body of every error response.
+type errorResponsePayload struct {
+	Error string `json:"error"`
+}
+
+// writeError sends the JSON error envelope with the given status code.
+func writeError(responseWriter http.ResponseWriter, statusCode int, message string) {
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
+	responseWriter.WriteHeader(statusCode)
+	json.NewEncoder(responseWriter).Encode(errorResponsePayload{Error: message})
+}
+
+// WriteError is writeError for handlers served next to this package's, such
+// as the GraphQL endpoint, so their errors use the same envelope.
+func WriteError(responseWriter http.ResponseWriter, statusCode int, message string) {
+	writeError(responseWriter, statusCode, message)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultCacheControl is sent on lookup responses when SwiftHTTPHandler's
+// CacheControl is empty. Clients may reuse a response for a minute and must
+// revalidate it afterwards, which is cheap thanks to ETag and Last-Modified.
+const DefaultCacheControl = "public, max-age=60, must-revalidate"
+
+// FreshnessStore reports when live rows last changed, for Last-Modified.
+type FreshnessStore interface {
+	SwiftCodeLastModified(code string) (time.Time, error)
+	CountryLastModified(requestedISO2 string) (time.Time, error)
+}
+
+// cachePolicy holds the validators and caching directive of one response.
+// A zero lastModified leaves Last-Modified out; the ETag is always sent.
+type cachePolicy struct {
+	lastModified time.Time
+	cacheControl string
+}
+
+// cachePolicy builds the policy for a lookup response. lastModified comes
+// from the dataset version for historical lookups and from the change log,
+// when one is configured, for live ones.
+func (httpHandler *SwiftHTTPHandler) cachePolicy(lastModified time.Time) cachePolicy {
+	cacheControl := httpHandler.CacheControl
+	if cacheControl == "" {
+		cacheControl = DefaultCacheControl
+	}
+	return cachePolicy{lastModified: lastModified, cacheControl: cacheControl}
+}
+
+// liveLastModified asks the FreshnessStore, if any, via lookup. Failures only
+// cost the Last-Modified header, so they are not reported to the client.
+func (httpHandler *SwiftHTTPHandler) liveLastModified(
+	lookup func(FreshnessStore) (time.Time, error),
+) time.Time {
+	if httpHandler.Freshness == nil {
+		return time.Time{}
+	}
+	lastModified, err := lookup(httpHandler.Freshness)
+	if err != nil {
+		return time.Time{}
+	}
+	return lastModified
+}
+
+// strongETag derives an entity tag from a rendered body. Every representation
+// of the same rows gets its own tag, as RFC 9110 requires of strong validators.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeValidators sets ETag, Last-Modified and Cache-Control for body. It
+// reports true after answering 304 Not Modified, in which case body must not
+// be sent.
+func (policy cachePolicy) writeValidators(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+	body []byte,
+) bool {
+	etag := strongETag(body)
+	header := responseWriter.Header()
+	header.Set("ETag", etag)
+	if !policy.lastModified.IsZero() {
+		header.Set("Last-Modified", policy.lastModified.UTC().Format(http.TimeFormat))
+	}
+	if policy.cacheControl != "" {
+		header.Set("Cache-Control", policy.cacheControl)
+	}
+	if !policy.notModified(incomingRequest, etag) {
+		return false
+	}
+	responseWriter.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when the former is
+// absent, following RFC 9110 section 13.2.2.
+func (policy cachePolicy) notModified(incomingRequest *http.Request, etag string) bool {
+	if ifNoneMatch := incomingRequest.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagListMatches(ifNoneMatch, etag)
+	}
+	ifModifiedSince := incomingRequest.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || policy.lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	// Last-Modified only carries whole seconds
+	return !policy.lastModified.Truncate(time.Second).After(since)
+}
+
+// etagListMatches applies the weak comparison If-None-Match calls for.
+func etagListMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
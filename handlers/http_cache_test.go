@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// stubFreshness reports the same modification time for everything.
+type stubFreshness struct {
+	lastModified time.Time
+}
+
+func (stub stubFreshness) SwiftCodeLastModified(code string) (time.Time, error) {
+	return stub.lastModified, nil
+}
+
+func (stub stubFreshness) CountryLastModified(requestedISO2 string) (time.Time, error) {
+	return stub.lastModified, nil
+}
+
+func getSwiftCode(handlerInstance *SwiftHTTPHandler, header http.Header) *httptest.ResponseRecorder {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZBANKXXX", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"code": "ZZBANKXXX"})
+	for name, values := range header {
+		testRequest.Header[name] = values
+	}
+	responseRecorder := httptest.NewRecorder()
+	handlerInstance.GetSwiftCode(responseRecorder, testRequest)
+	return responseRecorder
+}
+
+// TestGetSwiftCodeHandler_IfNoneMatch revalidates a cached response with its
+// ETag, and checks each representation is tagged separately.
+func TestGetSwiftCodeHandler_IfNoneMatch(t *testing.T) {
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+
+	first := getSwiftCode(handlerInstance, nil)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag on the first response")
+	}
+	if cacheControl := first.Header().Get("Cache-Control"); cacheControl != DefaultCacheControl {
+		t.Errorf("Expected Cache-Control %q, got %q", DefaultCacheControl, cacheControl)
+	}
+
+	revalidated := getSwiftCode(handlerInstance, http.Header{"If-None-Match": {`"other", W/` + etag}})
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", revalidated)
+	if revalidated.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 for a matching ETag, got %d", revalidated.Code)
+	}
+	if revalidated.Header().Get("ETag") != etag {
+		t.Errorf("Expected the 304 to repeat the ETag")
+	}
+
+	asCSV := getSwiftCode(handlerInstance, http.Header{"Accept": {"text/csv"}, "If-None-Match": {etag}})
+	if asCSV.Code != http.StatusOK {
+		t.Fatalf("Expected the JSON ETag not to match the CSV representation, got %d", asCSV.Code)
+	}
+	if asCSV.Header().Get("ETag") == etag {
+		t.Errorf("Expected CSV and JSON representations to have different ETags")
+	}
+}
+
+// TestGetCountrySwiftCodesHandler_IfModifiedSince uses the FreshnessStore's
+// time for Last-Modified and compares If-Modified-Since to it.
+func TestGetCountrySwiftCodesHandler_IfModifiedSince(t *testing.T) {
+	lastModified := time.Date(2026, 3, 14, 9, 26, 53, 589_000_000, time.UTC)
+	handlerInstance := &SwiftHTTPHandler{
+		DataStore:    &stubSwiftRepository{},
+		Freshness:    stubFreshness{lastModified: lastModified},
+		CacheControl: "public, max-age=3600",
+	}
+	getCountry := func(header http.Header) *httptest.ResponseRecorder {
+		testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/country/ZZ", nil)
+		testRequest = mux.SetURLVars(testRequest, map[string]string{"iso2": "ZZ"})
+		testRequest.Header = header
+		responseRecorder := httptest.NewRecorder()
+		handlerInstance.GetCountrySwiftCodes(responseRecorder, testRequest)
+		return responseRecorder
+	}
+
+	first := getCountry(http.Header{})
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/country/{iso2}", first)
+	if got := first.Header().Get("Last-Modified"); got != "Sat, 14 Mar 2026 09:26:53 GMT" {
+		t.Errorf("Unexpected Last-Modified %q", got)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("Expected the configured Cache-Control, got %q", got)
+	}
+
+	testCases := []struct {
+		name     string
+		header   http.Header
+		expected int
+	}{
+		{"same second", http.Header{"If-Modified-Since": {first.Header().Get("Last-Modified")}}, http.StatusNotModified},
+		{"earlier", http.Header{"If-Modified-Since": {"Sat, 14 Mar 2026 09:26:52 GMT"}}, http.StatusOK},
+		{"unparseable", http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+		{"If-None-Match wins", http.Header{
+			"If-Modified-Since": {first.Header().Get("Last-Modified")},
+			"If-None-Match":     {`"stale"`},
+		}, http.StatusOK},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := getCountry(testCase.header)
+			if responseRecorder.Code != testCase.expected {
+				t.Errorf("Expected %d, got %d", testCase.expected, responseRecorder.Code)
+			}
+			assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/country/{iso2}", responseRecorder)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+
+	"github.com/gorilla/mux"
+)
+
+// InstitutionStore looks up a bank and its offices by bank code.
+type InstitutionStore interface {
+	GetInstitution(bankCode string) (models.Institution, []models.SwiftCode, error)
+}
+
+// one country's offices in the institution response
+type institutionCountryPayload struct {
+	CountryISO2 string                  `json:"countryISO2"`
+	CountryName string                  `json:"countryName"`
+	SwiftCodes  []branchResponsePayload `json:"swiftCodes"`
+}
+
+type institutionResponsePayload struct {
+	BankCode  string                      `json:"bankCode"`
+	BankName  string                      `json:"bankName"`
+	Countries []institutionCountryPayload `json:"countries"`
+}
+
+// GET /v1/institutions/{bankCode}
+// Returns every head office and branch of a bank worldwide, grouped by country.
+
+func (httpHandler *SwiftHTTPHandler) GetInstitution(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Institutions == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "institutions not available")
+		return
+	}
+	bankCode := strings.ToUpper(mux.Vars(incomingRequest)["bankCode"])
+	if len(bankCode) != 4 {
+		writeError(responseWriter, http.StatusBadRequest, "bankCode must be 4 characters")
+		return
+	}
+
+	institution, offices, queryError := httpHandler.Institutions.GetInstitution(bankCode)
+	if errors.Is(queryError, service.ErrNotFound) {
+		writeError(responseWriter, http.StatusNotFound, "not found")
+		return
+	}
+	if queryError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+
+	// offices arrive ordered by country, so each country is one run of rows
+	institutionPayload := institutionResponsePayload{
+		BankCode:  institution.BankCode,
+		BankName:  institution.Name,
+		Countries: []institutionCountryPayload{},
+	}
+	for _, office := range offices {
+		last := len(institutionPayload.Countries) - 1
+		if last < 0 || institutionPayload.Countries[last].CountryISO2 != office.CountryISO2 {
+			institutionPayload.Countries = append(institutionPayload.Countries, institutionCountryPayload{
+				CountryISO2: office.CountryISO2,
+				CountryName: office.CountryName,
+			})
+			last++
+		}
+		institutionPayload.Countries[last].SwiftCodes = append(institutionPayload.Countries[last].SwiftCodes, branchPayloadFrom(office))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(institutionPayload)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"swift-codes-project/memstore"
+	"swift-codes-project/models"
+
+	"github.com/gorilla/mux"
+)
+
+func getInstitution(handlerInstance *SwiftHTTPHandler, bankCode string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/v1/institutions/"+bankCode, nil)
+	request = mux.SetURLVars(request, map[string]string{"bankCode": bankCode})
+	recorder := httptest.NewRecorder()
+	handlerInstance.GetInstitution(recorder, request)
+	return recorder
+}
+
+// TestGetInstitutionGroupsByCountry returns a bank's offices country by
+// country, whatever the case of the requested bank code.
+func TestGetInstitutionGroupsByCountry(t *testing.T) {
+	store := &memstore.Store{}
+	store.Load([]models.SwiftCode{
+		{CountryISO2: "PL", SwiftCode: "ABCDPLPW001", CountryName: "POLAND", Name: "ABC BANK WARSAW", HqSwiftCode: "ABCDPLPWXXX"},
+		{CountryISO2: "PL", SwiftCode: "ABCDPLPWXXX", CountryName: "POLAND", Name: "ABC BANK", IsHeadquarter: true},
+		{CountryISO2: "DE", SwiftCode: "ABCDDEFFXXX", CountryName: "GERMANY", Name: "ABC BANK", IsHeadquarter: true},
+		{CountryISO2: "PL", SwiftCode: "WXYZPLPWXXX", CountryName: "POLAND", Name: "OTHER BANK", IsHeadquarter: true},
+	})
+	handlerInstance := &SwiftHTTPHandler{DataStore: store, Institutions: store}
+
+	recorder := getInstitution(handlerInstance, "abcd")
+	assertMatchesContract(t, http.MethodGet, "/v1/institutions/{bankCode}", recorder)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", recorder.Code)
+	}
+	var institutionPayload institutionResponsePayload
+	json.NewDecoder(recorder.Body).Decode(&institutionPayload)
+	if institutionPayload.BankCode != "ABCD" || institutionPayload.BankName != "ABC BANK" {
+		t.Errorf("Unexpected institution %q %q", institutionPayload.BankCode, institutionPayload.BankName)
+	}
+	if len(institutionPayload.Countries) != 2 {
+		t.Fatalf("Expected 2 countries, got %+v", institutionPayload.Countries)
+	}
+	germany, poland := institutionPayload.Countries[0], institutionPayload.Countries[1]
+	if germany.CountryISO2 != "DE" || len(germany.SwiftCodes) != 1 {
+		t.Errorf("Expected DE with one office first, got %+v", germany)
+	}
+	if poland.CountryISO2 != "PL" || poland.CountryName != "POLAND" || len(poland.SwiftCodes) != 2 ||
+		poland.SwiftCodes[0].SwiftCode != "ABCDPLPW001" || poland.SwiftCodes[1].SwiftCode != "ABCDPLPWXXX" {
+		t.Errorf("Expected PL with both offices in code order, got %+v", poland)
+	}
+}
+
+func TestGetInstitutionErrors(t *testing.T) {
+	store := &memstore.Store{}
+	handlerInstance := &SwiftHTTPHandler{DataStore: store, Institutions: store}
+
+	for _, testCase := range []struct {
+		handler  *SwiftHTTPHandler
+		bankCode string
+		status   int
+	}{
+		{handlerInstance, "NONE", http.StatusNotFound},
+		{handlerInstance, "ABCDE", http.StatusBadRequest},
+		{&SwiftHTTPHandler{}, "ABCD", http.StatusNotImplemented},
+	} {
+		recorder := getInstitution(testCase.handler, testCase.bankCode)
+		assertMatchesContract(t, http.MethodGet, "/v1/institutions/{bankCode}", recorder)
+		if recorder.Code != testCase.status {
+			t.Errorf("%s: expected status %d, got %d", testCase.bankCode, testCase.status, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"swift-codes-project/timezones"
+
+	"github.com/gorilla/mux"
+)
+
+// this is returned by the local time endpoint
+type localTimeResponsePayload struct {
+	SwiftCode     string `json:"swiftCode"`
+	TimeZone      string `json:"timeZone"`
+	LocalTime     string `json:"localTime"`
+	UTCOffset     string `json:"utcOffset"`
+	BusinessHours bool   `json:"businessHours"`
+}
+
+// GET /v1/swift-codes/{code}/local-time
+// Reports the office's time zone and what time it is there now.
+
+func (httpHandler *SwiftHTTPHandler) GetLocalTime(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	requestedSwiftCode := strings.ToUpper(mux.Vars(incomingRequest)["code"])
+	// only the office itself is needed, not a head office's branches
+	offices, queryError := httpHandler.DataStore.GetSwiftCodesByCode([]string{requestedSwiftCode})
+	if queryError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	if len(offices) == 0 {
+		writeError(responseWriter, http.StatusNotFound, "not found")
+		return
+	}
+	office := offices[0]
+
+	local, zoneError := timezones.Local(office.TimeZone, httpHandler.now())
+	if zoneError != nil {
+		writeError(responseWriter, http.StatusNotFound, "no known time zone for "+office.SwiftCode)
+		return
+	}
+
+	// the body changes every second, so it must never be served from a cache
+	responseWriter.Header().Set("Cache-Control", "no-store")
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(localTimeResponsePayload{
+		SwiftCode:     office.SwiftCode,
+		TimeZone:      office.TimeZone,
+		LocalTime:     local.Time.Format(time.RFC3339),
+		UTCOffset:     local.UTCOffset,
+		BusinessHours: local.BusinessHours,
+	})
+}
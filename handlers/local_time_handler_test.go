@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"swift-codes-project/memstore"
+	"swift-codes-project/models"
+
+	"github.com/gorilla/mux"
+)
+
+func getLocalTime(handlerInstance *SwiftHTTPHandler, code string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/"+code+"/local-time", nil)
+	request = mux.SetURLVars(request, map[string]string{"code": code})
+	recorder := httptest.NewRecorder()
+	handlerInstance.GetLocalTime(recorder, request)
+	return recorder
+}
+
+func TestGetLocalTime(t *testing.T) {
+	store := &memstore.Store{}
+	store.Load([]models.SwiftCode{
+		{CountryISO2: "PL", SwiftCode: "PLBANKPLXXX", CountryName: "POLAND", TimeZone: "Europe/Warsaw", IsHeadquarter: true},
+		{CountryISO2: "PL", SwiftCode: "PLBANKPL001", CountryName: "POLAND", HqSwiftCode: "PLBANKPLXXX"},
+	})
+	handlerInstance := &SwiftHTTPHandler{
+		DataStore: store,
+		// a Monday, 10:30 in Warsaw
+		Now: func() time.Time { return time.Date(2026, time.June, 15, 8, 30, 0, 0, time.UTC) },
+	}
+
+	recorder := getLocalTime(handlerInstance, "plbankplxxx")
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}/local-time", recorder)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", recorder.Code)
+	}
+	if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, got %q", cacheControl)
+	}
+	var localTimePayload localTimeResponsePayload
+	json.NewDecoder(recorder.Body).Decode(&localTimePayload)
+	expected := localTimeResponsePayload{
+		SwiftCode:     "PLBANKPLXXX",
+		TimeZone:      "Europe/Warsaw",
+		LocalTime:     "2026-06-15T10:30:00+02:00",
+		UTCOffset:     "+02:00",
+		BusinessHours: true,
+	}
+	if localTimePayload != expected {
+		t.Errorf("Expected %+v, got %+v", expected, localTimePayload)
+	}
+
+	for code, status := range map[string]int{"PLBANKPL001": http.StatusNotFound, "XXBANKXXXXX": http.StatusNotFound} {
+		recorder := getLocalTime(handlerInstance, code)
+		assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}/local-time", recorder)
+		if recorder.Code != status {
+			t.Errorf("%s: expected status %d, got %d", code, status, recorder.Code)
+		}
+	}
+}
+
+// TestCreateSwiftCodeNormalizesTimeZone stores the IANA spelling and rejects
+// unknown zones.
+func TestCreateSwiftCodeNormalizesTimeZone(t *testing.T) {
+	store := &memstore.Store{}
+	handlerInstance := &SwiftHTTPHandler{DataStore: store}
+
+	create := func(timeZone string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"swiftCode": "PLBANKPLXXX", "bankName": "BANK", "countryISO2": "PL",
+			"countryName": "POLAND", "isHeadquarter": true, "timeZone": timeZone,
+		})
+		recorder := httptest.NewRecorder()
+		handlerInstance.CreateSwiftCode(recorder, httptest.NewRequest(http.MethodPost, "/v1/swift-codes", bytes.NewReader(body)))
+		assertMatchesContract(t, http.MethodPost, "/v1/swift-codes", recorder)
+		return recorder
+	}
+
+	if recorder := create("Europe/Zeland"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown zone, got %d", recorder.Code)
+	}
+	if recorder := create("europe/warsaw"); recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 Created, got %d", recorder.Code)
+	}
+	stored, _, _ := store.GetSwiftCode("PLBANKPLXXX")
+	if stored.TimeZone != "Europe/Warsaw" {
+		t.Errorf("Expected Europe/Warsaw to be stored, got %q", stored.TimeZone)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import "swift-codes-project/models"
+
+// The functions below are the only place response payloads are built from
+// models.SwiftCode, so v1 and v2 cannot drift apart on what a row means.
+
+func branchPayloadFrom(row models.SwiftCode) branchResponsePayload {
+	return branchResponsePayload{
+		Address:       row.Address,
+		BankName:      row.Name,
+		CountryISO2:   row.CountryISO2,
+		CountryName:   row.CountryName,
+		IsHeadquarter: row.IsHeadquarter,
+		SwiftCode:     row.SwiftCode,
+	}
+}
+
+// headOfficePayloadFrom leaves Branches nil for a head office without
+// branches, which v1 has always sent as "branches": null.
+func headOfficePayloadFrom(headOffice models.SwiftCode, branches []models.SwiftCode) headOfficeResponsePayload {
+	headOfficePayload := headOfficeResponsePayload{
+		Address:       headOffice.Address,
+		BankName:      headOffice.Name,
+		CountryISO2:   headOffice.CountryISO2,
+		CountryName:   headOffice.CountryName,
+		IsHeadquarter: true,
+		SwiftCode:     headOffice.SwiftCode,
+	}
+	for _, branch := range branches {
+		headOfficePayload.Branches = append(headOfficePayload.Branches, branchPayloadFrom(branch))
+	}
+	return headOfficePayload
+}
+
+// countryPayloadFrom lists rows, which must not be empty, under iso2.
+func countryPayloadFrom(iso2 string, rows []models.SwiftCode) countryResponsePayload {
+	countryPayload := countryResponsePayload{CountryISO2: iso2, CountryName: rows[0].CountryName}
+	for _, row := range rows {
+		countryPayload.SwiftCodes = append(countryPayload.SwiftCodes, branchPayloadFrom(row))
+	}
+	return countryPayload
+}
+
+// swiftCodeV2From maps every stored field. Branches stay nil; the single
+// code lookup fills them in for head offices.
+func swiftCodeV2From(row models.SwiftCode) swiftCodeV2Payload {
+	swiftCodePayload := swiftCodeV2Payload{
+		SwiftCode:     row.SwiftCode,
+		CodeType:      row.CodeType,
+		BankName:      row.Name,
+		Address:       row.Address,
+		TownName:      row.TownName,
+		CountryISO2:   row.CountryISO2,
+		CountryName:   row.CountryName,
+		TimeZone:      row.TimeZone,
+		IsHeadquarter: row.IsHeadquarter,
+	}
+	if !row.IsHeadquarter && row.HqSwiftCode != "" {
+		swiftCodePayload.HeadOffice = &headOfficeLinkV2{
+			SwiftCode: row.HqSwiftCode,
+			Href:      "/v2/swift-codes/" + row.HqSwiftCode,
+		}
+	}
+	return swiftCodePayload
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Media types the read endpoints can render. The first entry is the default
+// used when the client sends no Accept header or accepts anything.
+var supportedMediaTypes = []string{"application/json", "application/xml", "text/csv"}
+
+// csvPayload is implemented by response payloads that can be flattened to CSV.
+type csvPayload interface {
+	csvRecords() [][]string
+}
+
+var branchCSVHeader = []string{"swiftCode", "bankName", "address", "countryISO2", "countryName", "isHeadquarter"}
+
+func (payload branchResponsePayload) csvRecord() []string {
+	return []string{
+		payload.SwiftCode, payload.BankName, payload.Address,
+		payload.CountryISO2, payload.CountryName, strconv.FormatBool(payload.IsHeadquarter),
+	}
+}
+
+func (payload branchResponsePayload) csvRecords() [][]string {
+	return [][]string{branchCSVHeader, payload.csvRecord()}
+}
+
+// the head office is the first data row, followed by its branches
+func (payload headOfficeResponsePayload) csvRecords() [][]string {
+	records := [][]string{branchCSVHeader, branchResponsePayload{
+		Address:       payload.Address,
+		BankName:      payload.BankName,
+		CountryISO2:   payload.CountryISO2,
+		CountryName:   payload.CountryName,
+		IsHeadquarter: payload.IsHeadquarter,
+		SwiftCode:     payload.SwiftCode,
+	}.csvRecord()}
+	for _, branch := range payload.Branches {
+		records = append(records, branch.csvRecord())
+	}
+	return records
+}
+
+func (payload countryResponsePayload) csvRecords() [][]string {
+	records := [][]string{branchCSVHeader}
+	for _, entry := range payload.SwiftCodes {
+		records = append(records, entry.csvRecord())
+	}
+	return records
+}
+
+func (payload swiftCodeListResponsePayload) csvRecords() [][]string {
+	records := [][]string{branchCSVHeader}
+	for _, entry := range payload.SwiftCodes {
+		records = append(records, entry.csvRecord())
+	}
+	return records
+}
+
+type acceptedRange struct {
+	mediaRange string
+	quality    float64
+}
+
+// negotiateMediaType picks the supported media type the Accept header prefers,
+// honouring q-values and wildcards. It returns "" when nothing is acceptable.
+func negotiateMediaType(acceptHeader string) string {
+	if strings.TrimSpace(acceptHeader) == "" {
+		return supportedMediaTypes[0]
+	}
+
+	var ranges []acceptedRange
+	for _, part := range strings.Split(acceptHeader, ",") {
+		fields := strings.Split(part, ";")
+		accepted := acceptedRange{mediaRange: strings.ToLower(strings.TrimSpace(fields[0])), quality: 1}
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if quality, err := strconv.ParseFloat(value, 64); err == nil {
+					accepted.quality = quality
+				}
+			}
+		}
+		if accepted.mediaRange != "" && accepted.quality > 0 {
+			ranges = append(ranges, accepted)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, accepted := range ranges {
+		for _, mediaType := range supportedMediaTypes {
+			if mediaRangeMatches(accepted.mediaRange, mediaType) {
+				return mediaType
+			}
+		}
+	}
+	return ""
+}
+
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(mediaRange, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	// text/xml is a common alias for application/xml
+	return mediaRange == "text/xml" && mediaType == "application/xml"
+}
+
+// writeNegotiated renders payload as JSON, XML or CSV according to the
+// request's Accept header, using xmlRoot as the XML document element. It
+// responds 406 when none of the supported types is acceptable. The body is
+// rendered before anything is sent so that policy can answer conditional
+// requests with 304.
+func writeNegotiated(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+	xmlRoot string,
+	payload csvPayload,
+	policy cachePolicy,
+) {
+	responseWriter.Header().Add("Vary", "Accept")
+	mediaType := negotiateMediaType(incomingRequest.Header.Get("Accept"))
+
+	var body bytes.Buffer
+	var encodeError error
+	switch mediaType {
+	case "application/json":
+		encodeError = json.NewEncoder(&body).Encode(payload)
+	case "application/xml":
+		body.WriteString(xml.Header)
+		encodeError = xml.NewEncoder(&body).EncodeElement(payload, xml.StartElement{Name: xml.Name{Local: xmlRoot}})
+	case "text/csv":
+		encodeError = csv.NewWriter(&body).WriteAll(payload.csvRecords())
+	default:
+		writeError(responseWriter, http.StatusNotAcceptable, "not acceptable")
+		return
+	}
+	if encodeError != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "encoding failure")
+		return
+	}
+
+	if policy.writeValidators(responseWriter, incomingRequest, body.Bytes()) {
+		return
+	}
+	responseWriter.Header().Set("Content-Type", mediaType)
+	responseWriter.Write(body.Bytes())
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPIDocument is the OpenAPI 3.1 description of every REST endpoint. The
+// contract tests check handler responses against it, so it has to be updated
+// together with the handlers.
+//
+//go:embed openapi.json
+var OpenAPIDocument []byte
+
+// swaggerUIPage renders /openapi.json with Swagger UI loaded from a CDN.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>SWIFT Codes API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
+
+// GET /openapi.json
+func ServeOpenAPIDocument(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Write(OpenAPIDocument)
+}
+
+// GET /docs/
+func ServeSwaggerUI(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+	responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	responseWriter.Write([]byte(swaggerUIPage))
+}
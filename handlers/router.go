@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// NewRouter registers every REST endpoint of httpHandler, along with the
+// OpenAPI document and its Swagger UI.
+func NewRouter(httpHandler *SwiftHTTPHandler) *mux.Router {
+	router := mux.NewRouter()
+	// export and search are registered before {code} so they are not taken for swift codes
+	router.HandleFunc("/v1/swift-codes/export", httpHandler.ExportSwiftCodes).Methods("GET")
+	router.HandleFunc("/v1/swift-codes/search", httpHandler.SearchSwiftCodes).Methods("GET")
+	router.HandleFunc("/v1/swift-codes/batch-get", httpHandler.BatchGetSwiftCodes).Methods("POST")
+	router.HandleFunc("/v1/swift-codes/country/{iso2}", httpHandler.GetCountrySwiftCodes).Methods("GET")
+	router.HandleFunc("/v1/swift-codes/{code}", httpHandler.GetSwiftCode).Methods("GET")
+	router.HandleFunc("/v1/swift-codes/{code}/local-time", httpHandler.GetLocalTime).Methods("GET")
+	router.HandleFunc("/v1/swift-codes", httpHandler.writable(httpHandler.CreateSwiftCode)).Methods("POST")
+	router.HandleFunc("/v1/swift-codes/{code}", httpHandler.writable(httpHandler.DeleteSwiftCode)).Methods("DELETE")
+	router.HandleFunc("/v2/swift-codes/country/{iso2}", httpHandler.GetCountrySwiftCodesV2).Methods("GET")
+	router.HandleFunc("/v2/swift-codes/{code}", httpHandler.GetSwiftCodeV2).Methods("GET")
+	router.HandleFunc("/v1/countries", httpHandler.ListCountries).Methods("GET")
+	router.HandleFunc("/v1/institutions/{bankCode}", httpHandler.GetInstitution).Methods("GET")
+	router.HandleFunc("/v1/changes", httpHandler.ListChanges).Methods("GET")
+	router.HandleFunc("/v1/changes/stream", httpHandler.StreamChanges).Methods("GET")
+	router.HandleFunc("/v1/webhooks", httpHandler.admin(httpHandler.writable(httpHandler.CreateWebhookSubscription))).Methods("POST")
+	router.HandleFunc("/v1/webhooks", httpHandler.admin(httpHandler.ListWebhookSubscriptions)).Methods("GET")
+	router.HandleFunc("/v1/webhooks/{id}", httpHandler.admin(httpHandler.writable(httpHandler.DeleteWebhookSubscription))).Methods("DELETE")
+	router.HandleFunc("/v1/webhooks/{id}/deliveries", httpHandler.admin(httpHandler.ListWebhookDeliveries)).Methods("GET")
+	router.HandleFunc("/v1/admin/datasets", httpHandler.admin(httpHandler.ListDatasetVersions)).Methods("GET")
+	router.HandleFunc("/v1/admin/backups", httpHandler.admin(httpHandler.writable(httpHandler.CreateBackup))).Methods("POST")
+	router.HandleFunc("/v1/admin/backups", httpHandler.admin(httpHandler.ListBackups)).Methods("GET")
+	router.HandleFunc("/v1/admin/datasets/{a}/diff/{b}", httpHandler.admin(httpHandler.DiffDatasetVersions)).Methods("GET")
+
+	router.HandleFunc("/openapi.json", ServeOpenAPIDocument).Methods("GET")
+	router.Handle("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently)).Methods("GET")
+	router.HandleFunc("/docs/", ServeSwaggerUI).Methods("GET")
+	return router
+}
+
+// writable guards a mutating route: on a read-only server it answers 403
+// without calling next.
+func (httpHandler *SwiftHTTPHandler) writable(next http.HandlerFunc) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+		if httpHandler.ReadOnly {
+			writeError(responseWriter, http.StatusForbidden, "this server is read-only; send writes to the primary")
+			return
+		}
+		next(responseWriter, incomingRequest)
+	}
+}
+
+// admin guards a route with httpHandler.AdminToken; see RequireAdmin.
+func (httpHandler *SwiftHTTPHandler) admin(next http.HandlerFunc) http.HandlerFunc {
+	return RequireAdmin(httpHandler.AdminToken, next).ServeHTTP
+}
@@ -1,51 +1,159 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"swift-codes-project/changefeed"
+	"swift-codes-project/exporter"
 	"swift-codes-project/models"
+	"swift-codes-project/service"
+	"swift-codes-project/timezones"
 
 	"github.com/gorilla/mux"
 )
 
 // this is returned if the requested code is the branch
 type branchResponsePayload struct {
+	Address       string `json:"address" xml:"address"`
+	BankName      string `json:"bankName" xml:"bankName"`
+	CountryISO2   string `json:"countryISO2" xml:"countryISO2"`
+	CountryName   string `json:"countryName" xml:"countryName"`
+	IsHeadquarter bool   `json:"isHeadquarter" xml:"isHeadquarter"`
+	SwiftCode     string `json:"swiftCode" xml:"swiftCode"`
+}
+
+type headOfficeResponsePayload struct {
+	Address       string                  `json:"address" xml:"address"`
+	BankName      string                  `json:"bankName" xml:"bankName"`
+	CountryISO2   string                  `json:"countryISO2" xml:"countryISO2"`
+	CountryName   string                  `json:"countryName" xml:"countryName"`
+	IsHeadquarter bool                    `json:"isHeadquarter" xml:"isHeadquarter"`
+	SwiftCode     string                  `json:"swiftCode" xml:"swiftCode"`
+	Branches      []branchResponsePayload `json:"branches" xml:"branches>branch"`
+}
+
+// this is returned with “list by country” endpoint
+type countryResponsePayload struct {
+	CountryISO2 string                  `json:"countryISO2" xml:"countryISO2"`
+	CountryName string                  `json:"countryName" xml:"countryName"`
+	SwiftCodes  []branchResponsePayload `json:"swiftCodes" xml:"swiftCodes>swiftCode"`
+}
+
+// createRequestPayload is the body of POST /v1/swift-codes. bankName matches
+// the response field; name is still accepted from older clients.
+type createRequestPayload struct {
 	Address       string `json:"address"`
 	BankName      string `json:"bankName"`
+	LegacyName    string `json:"name"`
 	CountryISO2   string `json:"countryISO2"`
 	CountryName   string `json:"countryName"`
 	IsHeadquarter bool   `json:"isHeadquarter"`
 	SwiftCode     string `json:"swiftCode"`
+	CodeType      string `json:"codeType"`
+	TownName      string `json:"townName"`
+	TimeZone      string `json:"timeZone"`
 }
 
-type headOfficeResponsePayload struct {
-	Address       string                  `json:"address"`
-	BankName      string                  `json:"bankName"`
-	CountryISO2   string                  `json:"countryISO2"`
-	CountryName   string                  `json:"countryName"`
-	IsHeadquarter bool                    `json:"isHeadquarter"`
-	SwiftCode     string                  `json:"swiftCode"`
-	Branches      []branchResponsePayload `json:"branches"`
+func (payload createRequestPayload) toModel() models.SwiftCode {
+	bankName := payload.BankName
+	if bankName == "" {
+		bankName = payload.LegacyName
+	}
+	return models.SwiftCode{
+		CountryISO2:   payload.CountryISO2,
+		SwiftCode:     payload.SwiftCode,
+		CodeType:      payload.CodeType,
+		Name:          bankName,
+		Address:       payload.Address,
+		TownName:      payload.TownName,
+		CountryName:   payload.CountryName,
+		TimeZone:      payload.TimeZone,
+		IsHeadquarter: payload.IsHeadquarter,
+		HqSwiftCode:   headOfficeCodeOf(payload.SwiftCode, payload.IsHeadquarter),
+	}
 }
 
-// this is returned with “list by country” endpoint
-type countryResponsePayload struct {
-	CountryISO2 string                  `json:"countryISO2"`
-	CountryName string                  `json:"countryName"`
-	SwiftCodes  []branchResponsePayload `json:"swiftCodes"`
+// headOfficeCodeOf derives the head office of a branch the way the importer
+// does: the first eight characters of its code followed by XXX.
+func headOfficeCodeOf(swiftCode string, isHeadquarter bool) string {
+	if isHeadquarter || len(swiftCode) < 8 {
+		return ""
+	}
+	return swiftCode[:8] + "XXX"
 }
 
+// this is returned by search and batch lookups
+type swiftCodeListResponsePayload struct {
+	SwiftCodes []branchResponsePayload `json:"swiftCodes" xml:"swiftCode"`
+}
+
+type batchGetRequestPayload struct {
+	SwiftCodes []string `json:"swiftCodes"`
+}
+
+type batchGetResponsePayload struct {
+	SwiftCodes []branchResponsePayload `json:"swiftCodes"`
+	Missing    []string                `json:"missing"`
+}
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+	maxBatchGet        = 1000
+)
+
 type SwiftDataStore interface {
 	GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error)
 	GetCountrySwiftCodes(requestedISO2 string) ([]models.SwiftCode, error)
+	GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error)
 	CreateSwiftCode(newEntry models.SwiftCode) error
+	UpdateSwiftCode(changedEntry models.SwiftCode) error
 	DeleteSwiftCode(codeToDelete string) error
+	SearchSwiftCodes(query, requestedISO2 string, limit int) ([]models.SwiftCode, error)
+	ExportSwiftCodes(requestedISO2 string, visit func(models.SwiftCode) error) error
 }
 
 type SwiftHTTPHandler struct {
 	DataStore SwiftDataStore
+	// Datasets serves ?version= and ?asOf= lookups; they are rejected when nil.
+	Datasets DatasetStore
+	// Changes serves the change log endpoints; they are rejected when nil.
+	Changes *changefeed.Feed
+	// Webhooks serves the webhook subscription endpoints; they are rejected when nil.
+	Webhooks WebhookStore
+	// Backups serves the backup endpoints; they are rejected when nil.
+	Backups BackupStore
+	// Countries serves GET /v1/countries and, when set, new codes must name
+	// a known country; the endpoint is rejected and nothing is checked when nil.
+	Countries CountryStore
+	// Institutions serves GET /v1/institutions/{bankCode}.
+	Institutions InstitutionStore
+	// Freshness supplies Last-Modified for live lookups; it is omitted when nil.
+	Freshness FreshnessStore
+	// CacheControl is sent on lookup responses; DefaultCacheControl when empty.
+	CacheControl string
+	// ReadOnly rejects every mutating route with 403, for replicas serving a
+	// shipped database.
+	ReadOnly bool
+	// AdminToken must be presented on admin routes, such as the webhook
+	// endpoints; they answer 403 when it is empty. See RequireAdmin.
+	AdminToken string
+	// Now tells the time for the local time endpoint; time.Now when nil.
+	Now func() time.Time
+}
+
+func (httpHandler *SwiftHTTPHandler) now() time.Time {
+	if httpHandler.Now != nil {
+		return httpHandler.Now()
+	}
+	return time.Now()
 }
 
 // GET /v1/swift-codes/{code}
@@ -53,82 +161,163 @@ func (httpHandler *SwiftHTTPHandler) GetSwiftCode(
 	responseWriter http.ResponseWriter,
 	incomingRequest *http.Request,
 ) {
-	pathVariables := mux.Vars(incomingRequest)
-	requestedSwiftCode := strings.ToUpper(pathVariables["code"])
-
-	headOfficeRow, branchRows, queryError := httpHandler.DataStore.GetSwiftCode(requestedSwiftCode)
-
-	if queryError != nil {
-		http.Error(responseWriter, `{"error": "not found"}`, http.StatusNotFound)
+	headOfficeRow, branchRows, lastModified, found := httpHandler.findSwiftCode(responseWriter, incomingRequest)
+	if !found {
 		return
 	}
-	responseWriter.Header().Set("Content-Type", "application/json")
 
 	//case 1, the requested row itself is a branch
 	if !headOfficeRow.IsHeadquarter {
-		branchPayload := branchResponsePayload{
-			Address:       headOfficeRow.Address,
-			BankName:      headOfficeRow.Name,
-			CountryISO2:   headOfficeRow.CountryISO2,
-			CountryName:   headOfficeRow.CountryName,
-			IsHeadquarter: false,
-			SwiftCode:     headOfficeRow.SwiftCode,
-		}
-		json.NewEncoder(responseWriter).Encode(branchPayload)
+		writeNegotiated(responseWriter, incomingRequest, "bank", branchPayloadFrom(headOfficeRow), httpHandler.cachePolicy(lastModified))
 		return
 	}
 	//case 2 the requested row is a head office
-	headOfficePayload := headOfficeResponsePayload{
-		Address:       headOfficeRow.Address,
-		BankName:      headOfficeRow.Name,
-		CountryISO2:   headOfficeRow.CountryISO2,
-		CountryName:   headOfficeRow.CountryName,
-		IsHeadquarter: true,
-		SwiftCode:     headOfficeRow.SwiftCode,
-	}
-	for _, branchRow := range branchRows {
-		headOfficePayload.Branches = append(headOfficePayload.Branches, branchResponsePayload{
-			Address:       branchRow.Address,
-			BankName:      branchRow.Name,
-			CountryISO2:   branchRow.CountryISO2,
-			CountryName:   branchRow.CountryName,
-			IsHeadquarter: false,
-			SwiftCode:     branchRow.SwiftCode,
+	headOfficePayload := headOfficePayloadFrom(headOfficeRow, branchRows)
+	writeNegotiated(responseWriter, incomingRequest, "bank", headOfficePayload, httpHandler.cachePolicy(lastModified))
+}
+
+// findSwiftCode loads the {code} of the request, from the dataset version
+// ?version= or ?asOf= name when given. It reports found=false once it has
+// answered the request with an error.
+func (httpHandler *SwiftHTTPHandler) findSwiftCode(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) (headOfficeRow models.SwiftCode, branchRows []models.SwiftCode, lastModified time.Time, found bool) {
+	requestedSwiftCode := strings.ToUpper(mux.Vars(incomingRequest)["code"])
+
+	var queryError error
+	if datasetVersion, historical, handled := httpHandler.requestedDatasetVersion(responseWriter, incomingRequest); handled {
+		return headOfficeRow, nil, lastModified, false
+	} else if historical {
+		headOfficeRow, branchRows, queryError = httpHandler.Datasets.GetSwiftCodeAtVersion(requestedSwiftCode, datasetVersion.Version)
+		lastModified = datasetVersion.ImportedAt
+	} else {
+		headOfficeRow, branchRows, queryError = httpHandler.DataStore.GetSwiftCode(requestedSwiftCode)
+		lastModified = httpHandler.liveLastModified(func(freshness FreshnessStore) (time.Time, error) {
+			return freshness.SwiftCodeLastModified(requestedSwiftCode)
 		})
 	}
-	json.NewEncoder(responseWriter).Encode(headOfficePayload)
+
+	if queryError != nil {
+		writeError(responseWriter, http.StatusNotFound, "not found")
+		return headOfficeRow, nil, lastModified, false
+	}
+	return headOfficeRow, branchRows, lastModified, true
 }
 
 // GET /v1/swift-codes/country/{iso2}
-
 func (httpHandler *SwiftHTTPHandler) GetCountrySwiftCodes(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
-	pathVariables := mux.Vars(incomingRequest)
-	requestedISO2 := strings.ToUpper(pathVariables["iso2"])
-	allRows, queryError :=
-		httpHandler.DataStore.GetCountrySwiftCodes(requestedISO2)
-	if queryError != nil || len(allRows) == 0 {
-		http.Error(responseWriter, `{"error":"not found"}`, http.StatusNotFound)
+	requestedISO2, allRows, lastModified, found := httpHandler.findCountrySwiftCodes(responseWriter, incomingRequest)
+	if !found {
 		return
 	}
+	writeNegotiated(responseWriter, incomingRequest, "country", countryPayloadFrom(requestedISO2, allRows), httpHandler.cachePolicy(lastModified))
+}
 
-	var listPayload []branchResponsePayload
-	for _, row := range allRows {
-		listPayload = append(listPayload, branchResponsePayload{
-			Address:       row.Address,
-			BankName:      row.Name,
-			CountryISO2:   row.CountryISO2,
-			CountryName:   row.CountryName,
-			IsHeadquarter: row.IsHeadquarter,
-			SwiftCode:     row.SwiftCode,
+// findCountrySwiftCodes is findSwiftCode for the codes of the {iso2} country.
+// A country without codes is not found.
+func (httpHandler *SwiftHTTPHandler) findCountrySwiftCodes(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) (requestedISO2 string, allRows []models.SwiftCode, lastModified time.Time, found bool) {
+	requestedISO2 = strings.ToUpper(mux.Vars(incomingRequest)["iso2"])
+
+	var queryError error
+	if datasetVersion, historical, handled := httpHandler.requestedDatasetVersion(responseWriter, incomingRequest); handled {
+		return requestedISO2, nil, lastModified, false
+	} else if historical {
+		allRows, queryError = httpHandler.Datasets.GetCountrySwiftCodesAtVersion(requestedISO2, datasetVersion.Version)
+		lastModified = datasetVersion.ImportedAt
+	} else {
+		allRows, queryError = httpHandler.DataStore.GetCountrySwiftCodes(requestedISO2)
+		lastModified = httpHandler.liveLastModified(func(freshness FreshnessStore) (time.Time, error) {
+			return freshness.CountryLastModified(requestedISO2)
 		})
 	}
-	countryPayload := countryResponsePayload{
-		CountryISO2: requestedISO2,
-		CountryName: allRows[0].CountryName,
-		SwiftCodes:  listPayload,
+	if queryError != nil || len(allRows) == 0 {
+		writeError(responseWriter, http.StatusNotFound, "not found")
+		return requestedISO2, nil, lastModified, false
+	}
+	return requestedISO2, allRows, lastModified, true
+}
+
+// GET /v1/swift-codes/search?q={query}&country={iso2}&limit={n}
+// Matches swift code prefixes and bank or town name substrings.
+
+func (httpHandler *SwiftHTTPHandler) SearchSwiftCodes(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	queryValues := incomingRequest.URL.Query()
+	query := strings.TrimSpace(queryValues.Get("q"))
+	if query == "" {
+		writeError(responseWriter, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit := defaultSearchLimit
+	if limitValue := queryValues.Get("limit"); limitValue != "" {
+		parsedLimit, err := strconv.Atoi(limitValue)
+		if err != nil || parsedLimit <= 0 {
+			writeError(responseWriter, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(parsedLimit, maxSearchLimit)
+	}
+
+	rows, err := httpHandler.DataStore.SearchSwiftCodes(query, strings.ToUpper(queryValues.Get("country")), limit)
+	if err != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	listPayload := swiftCodeListResponsePayload{SwiftCodes: []branchResponsePayload{}}
+	for _, row := range rows {
+		listPayload.SwiftCodes = append(listPayload.SwiftCodes, branchPayloadFrom(row))
+	}
+	writeNegotiated(responseWriter, incomingRequest, "swiftCodes", listPayload, httpHandler.cachePolicy(time.Time{}))
+}
+
+// POST /v1/swift-codes/batch-get  {"swiftCodes": [...]}
+// Codes that do not exist are listed in missing instead of failing the batch.
+
+func (httpHandler *SwiftHTTPHandler) BatchGetSwiftCodes(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	var incomingBody batchGetRequestPayload
+	if err := json.NewDecoder(incomingRequest.Body).Decode(&incomingBody); err != nil {
+		writeError(responseWriter, http.StatusBadRequest, "bad json")
+		return
+	}
+	if len(incomingBody.SwiftCodes) > maxBatchGet {
+		writeError(responseWriter, http.StatusBadRequest, "at most "+strconv.Itoa(maxBatchGet)+" codes per batch")
+		return
+	}
+
+	requestedCodes := make([]string, len(incomingBody.SwiftCodes))
+	for i, requestedCode := range incomingBody.SwiftCodes {
+		requestedCodes[i] = strings.ToUpper(requestedCode)
+	}
+	rows, err := httpHandler.DataStore.GetSwiftCodesByCode(requestedCodes)
+	if err != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	byCode := make(map[string]models.SwiftCode, len(rows))
+	for _, row := range rows {
+		byCode[row.SwiftCode] = row
+	}
+
+	batchPayload := batchGetResponsePayload{SwiftCodes: []branchResponsePayload{}, Missing: []string{}}
+	for i, requestedCode := range incomingBody.SwiftCodes {
+		row, found := byCode[requestedCodes[i]]
+		if !found {
+			batchPayload.Missing = append(batchPayload.Missing, requestedCode)
+			continue
+		}
+		batchPayload.SwiftCodes = append(batchPayload.SwiftCodes, branchPayloadFrom(row))
 	}
 	responseWriter.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(responseWriter).Encode(countryPayload)
+	json.NewEncoder(responseWriter).Encode(batchPayload)
 }
 
 // POST /v1/swift-codes
@@ -136,14 +325,33 @@ func (httpHandler *SwiftHTTPHandler) CreateSwiftCode(
 	responseWriter http.ResponseWriter,
 	incomingRequest *http.Request,
 ) {
-	var incomingBody models.SwiftCode
+	var incomingBody createRequestPayload
 	if err := json.NewDecoder(incomingRequest.Body).Decode(&incomingBody); err != nil {
-		http.Error(responseWriter, `{"error":"bad json"}`, http.StatusBadRequest)
+		writeError(responseWriter, http.StatusBadRequest, "bad json")
 		return
 	}
 
-	if err := httpHandler.DataStore.CreateSwiftCode(incomingBody); err != nil {
-		http.Error(responseWriter, `{"error":"cannot insert"}`, http.StatusConflict)
+	newEntry := incomingBody.toModel()
+	timeZone, zoneError := timezones.Normalize(newEntry.TimeZone)
+	if zoneError != nil {
+		writeError(responseWriter, http.StatusBadRequest, zoneError.Error())
+		return
+	}
+	newEntry.TimeZone = timeZone
+	if httpHandler.Countries != nil {
+		message, checkError := CheckCountry(httpHandler.Countries, newEntry)
+		if checkError != nil {
+			writeError(responseWriter, http.StatusInternalServerError, "db failure")
+			return
+		}
+		if message != "" {
+			writeError(responseWriter, http.StatusBadRequest, message)
+			return
+		}
+	}
+
+	if err := httpHandler.DataStore.CreateSwiftCode(newEntry); err != nil {
+		writeError(responseWriter, http.StatusConflict, "cannot insert")
 		return
 	}
 
@@ -161,11 +369,100 @@ func (httpHandler *SwiftHTTPHandler) DeleteSwiftCode(
 	requestedSwiftCode := strings.ToUpper(mux.Vars(incomingRequest)["code"])
 
 	if err := httpHandler.DataStore.DeleteSwiftCode(requestedSwiftCode); err != nil {
-		http.Error(responseWriter, `{"error":"db failure"}`,
-			http.StatusInternalServerError)
+		if errors.Is(err, service.ErrNotFound) {
+			writeError(responseWriter, http.StatusNotFound, "not found")
+			return
+		}
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
 		return
 	}
 
 	responseWriter.Header().Set("Content-Type", "application/json")
 	responseWriter.Write([]byte(`{"message":"swift code deleted"}`))
 }
+
+// GET /v1/swift-codes/export?format=csv|jsonl|xlsx&country={iso2}
+
+func (httpHandler *SwiftHTTPHandler) ExportSwiftCodes(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	queryValues := incomingRequest.URL.Query()
+	requestedFormat := strings.ToLower(queryValues.Get("format"))
+	if requestedFormat == "" {
+		requestedFormat = exporter.FormatCSV
+	}
+	requestedISO2 := strings.ToUpper(queryValues.Get("country"))
+
+	spooled := &spooledResponse{responseWriter: responseWriter, limit: exportSpoolLimit}
+	rowWriter, formatError := exporter.NewWriter(requestedFormat, spooled)
+	if formatError != nil {
+		writeError(responseWriter, http.StatusBadRequest, "unsupported format")
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", exporter.ContentType(requestedFormat))
+	responseWriter.Header().Set("Content-Disposition", `attachment; filename="swift_codes.`+requestedFormat+`"`)
+
+	exportError := httpHandler.DataStore.ExportSwiftCodes(requestedISO2, rowWriter.WriteRow)
+	// Close also releases the writer's temporary files, so it runs either way
+	if closeError := rowWriter.Close(); exportError == nil {
+		exportError = closeError
+	}
+	if exportError != nil {
+		log.Printf("Export failed: %v", exportError)
+		if !spooled.streaming {
+			responseWriter.Header().Del("Content-Disposition")
+			writeError(responseWriter, http.StatusInternalServerError, "export failed")
+			return
+		}
+		// The 200 and part of the body are gone. Aborting the response
+		// breaks off the chunked encoding, so clients see an incomplete
+		// body rather than a shorter file that looks whole.
+		panic(http.ErrAbortHandler)
+	}
+
+	if err := spooled.finish(); err != nil {
+		log.Printf("Export failed: %v", err)
+	}
+}
+
+// exportSpoolLimit is how much of an export is held back before it starts
+// streaming. Country exports fit, so they can still fail with a 500.
+const exportSpoolLimit = 4 << 20
+
+// spooledResponse holds a response body back until it outgrows limit, so
+// that a failure before then can still be answered with an error status.
+// Headers must be set before the first write.
+type spooledResponse struct {
+	responseWriter http.ResponseWriter
+	limit          int
+	buffer         bytes.Buffer
+	// streaming is set once the status and the start of the body were sent.
+	streaming bool
+}
+
+func (spooled *spooledResponse) Write(data []byte) (int, error) {
+	if spooled.streaming {
+		return spooled.responseWriter.Write(data)
+	}
+	spooled.buffer.Write(data)
+	if spooled.buffer.Len() <= spooled.limit {
+		return len(data), nil
+	}
+	spooled.streaming = true
+	if _, err := spooled.buffer.WriteTo(spooled.responseWriter); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// finish sends whatever is still held back, with its length when it is the
+// whole body.
+func (spooled *spooledResponse) finish() error {
+	if !spooled.streaming {
+		spooled.responseWriter.Header().Set("Content-Length", strconv.Itoa(spooled.buffer.Len()))
+	}
+	_, err := spooled.buffer.WriteTo(spooled.responseWriter)
+	return err
+}
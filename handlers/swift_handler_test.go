@@ -2,13 +2,19 @@ package handler
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gorilla/mux"
 
+	"swift-codes-project/memstore"
 	"swift-codes-project/models"
 )
 
@@ -47,16 +53,51 @@ func (stub *stubSwiftRepository) GetCountrySwiftCodes(requestedISO2 string) ([]m
 	return []models.SwiftCode{entry}, nil
 }
 
+// GetSwiftCodesByCode returns a head office for every code.
+func (stub *stubSwiftRepository) GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error) {
+	var rows []models.SwiftCode
+	for _, code := range codes {
+		headOfficeData, _, _ := stub.GetSwiftCode(code)
+		rows = append(rows, headOfficeData)
+	}
+	return rows, nil
+}
+
 // CreateSwiftCode always succeeds.
 func (stub *stubSwiftRepository) CreateSwiftCode(newCode models.SwiftCode) error {
 	return nil
 }
 
+// UpdateSwiftCode always succeeds.
+func (stub *stubSwiftRepository) UpdateSwiftCode(changedCode models.SwiftCode) error {
+	return nil
+}
+
+// SearchSwiftCodes returns the country entry for any query.
+func (stub *stubSwiftRepository) SearchSwiftCodes(query, requestedISO2 string, limit int) ([]models.SwiftCode, error) {
+	return stub.GetCountrySwiftCodes("ZZ")
+}
+
 // DeleteSwiftCode always succeeds.
 func (stub *stubSwiftRepository) DeleteSwiftCode(codeToDelete string) error {
 	return nil
 }
 
+// ExportSwiftCodes visits a head office and one branch for the requested ISO‑2
+// code; an unfiltered export covers the ZZ country only.
+func (stub *stubSwiftRepository) ExportSwiftCodes(requestedISO2 string, visit func(models.SwiftCode) error) error {
+	if requestedISO2 == "" {
+		requestedISO2 = "ZZ"
+	}
+	headOfficeData, branchRows, _ := stub.GetSwiftCode(requestedISO2 + "BANKXXX")
+	for _, row := range append([]models.SwiftCode{headOfficeData}, branchRows...) {
+		if err := visit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // TestGetSwiftCodeHandler_Success tests the GET /v1/swift-codes/{code} handler.
 func TestGetSwiftCodeHandler_Success(t *testing.T) {
 	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZBANKXXX", nil)
@@ -65,6 +106,7 @@ func TestGetSwiftCodeHandler_Success(t *testing.T) {
 
 	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
 	handlerInstance.GetSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", responseRecorder)
 
 	response := responseRecorder.Result()
 	if response.StatusCode != http.StatusOK {
@@ -92,6 +134,7 @@ func TestGetCountrySwiftCodesHandler_Success(t *testing.T) {
 
 	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
 	handlerInstance.GetCountrySwiftCodes(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/country/{iso2}", responseRecorder)
 
 	response := responseRecorder.Result()
 	if response.StatusCode != http.StatusOK {
@@ -132,6 +175,7 @@ func TestCreateSwiftCodeHandler_Success(t *testing.T) {
 
 	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
 	handlerInstance.CreateSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodPost, "/v1/swift-codes", responseRecorder)
 
 	response := responseRecorder.Result()
 	if response.StatusCode != http.StatusCreated {
@@ -147,9 +191,364 @@ func TestDeleteSwiftCodeHandler_Success(t *testing.T) {
 
 	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
 	handlerInstance.DeleteSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodDelete, "/v1/swift-codes/{code}", responseRecorder)
 
 	response := responseRecorder.Result()
 	if response.StatusCode != http.StatusOK {
 		t.Fatalf("Expected status 200 OK, got %d", response.StatusCode)
 	}
 }
+
+// TestExportSwiftCodesHandler_CSV tests the GET /v1/swift-codes/export handler.
+func TestExportSwiftCodesHandler_CSV(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/export?format=csv&country=zz", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.ExportSwiftCodes(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/export", responseRecorder)
+
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", response.StatusCode)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/csv" {
+		t.Errorf("Expected Content-Type 'text/csv', got '%s'", contentType)
+	}
+
+	records, readError := csv.NewReader(response.Body).ReadAll()
+	if readError != nil {
+		t.Fatalf("Failed to read CSV response: %v", readError)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d records", len(records))
+	}
+	if records[1][1] != "ZZBANKXXX" {
+		t.Errorf("Expected first exported code 'ZZBANKXXX', got '%s'", records[1][1])
+	}
+}
+
+// failingExportRepository visits rows codes and then fails, as a database
+// connection dropping mid-export would.
+type failingExportRepository struct {
+	stubSwiftRepository
+	rows int
+}
+
+func (stub *failingExportRepository) ExportSwiftCodes(requestedISO2 string, visit func(models.SwiftCode) error) error {
+	for i := 0; i < stub.rows; i++ {
+		if err := visit(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: fmt.Sprintf("ZZBANK%05d", i), Name: "ZELAND BANK"}); err != nil {
+			return err
+		}
+	}
+	return errors.New("connection lost")
+}
+
+// TestExportSwiftCodesHandler_Failure expects a small export that fails to
+// answer 500, and a large one that fails after streaming started to end in a
+// broken body rather than a clean one.
+func TestExportSwiftCodesHandler_Failure(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	handlerInstance := &SwiftHTTPHandler{DataStore: &failingExportRepository{rows: 10}}
+	handlerInstance.ExportSwiftCodes(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/export", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/export", responseRecorder)
+	if responseRecorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for a failed small export, got %d", responseRecorder.Code)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc((&SwiftHTTPHandler{DataStore: &failingExportRepository{rows: 200000}}).ExportSwiftCodes))
+	defer server.Close()
+	response, requestError := server.Client().Get(server.URL + "/v1/swift-codes/export")
+	if requestError != nil {
+		t.Fatalf("Export request failed: %v", requestError)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected streaming to have started with 200, got %d", response.StatusCode)
+	}
+	if _, readError := io.Copy(io.Discard, response.Body); readError == nil {
+		t.Errorf("Expected reading the failed export to end in an error")
+	}
+}
+
+// TestExportSwiftCodesHandler_UnsupportedFormat expects a 400 for unknown formats.
+func TestExportSwiftCodesHandler_UnsupportedFormat(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/export?format=pdf", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.ExportSwiftCodes(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/export", responseRecorder)
+
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 Bad Request, got %d", responseRecorder.Code)
+	}
+}
+
+// TestGetSwiftCodeHandler_XML requests XML and decodes the head office document.
+func TestGetSwiftCodeHandler_XML(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZBANKXXX", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"code": "ZZBANKXXX"})
+	testRequest.Header.Set("Accept", "application/xml")
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.GetSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", responseRecorder)
+
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", response.StatusCode)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "application/xml" {
+		t.Errorf("Expected Content-Type 'application/xml', got '%s'", contentType)
+	}
+
+	var decodedPayload headOfficeResponsePayload
+	if decodeError := xml.NewDecoder(response.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode XML response: %v", decodeError)
+	}
+	if decodedPayload.SwiftCode != "ZZBANKXXX" {
+		t.Errorf("Expected SwiftCode 'ZZBANKXXX', got '%s'", decodedPayload.SwiftCode)
+	}
+	if len(decodedPayload.Branches) != 1 {
+		t.Errorf("Expected 1 branch, got %d", len(decodedPayload.Branches))
+	}
+}
+
+// TestGetCountrySwiftCodesHandler_CSV prefers CSV by q-value over JSON.
+func TestGetCountrySwiftCodesHandler_CSV(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/country/ZZ", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"iso2": "ZZ"})
+	testRequest.Header.Set("Accept", "application/json;q=0.5, text/csv")
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.GetCountrySwiftCodes(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/country/{iso2}", responseRecorder)
+
+	response := responseRecorder.Result()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/csv" {
+		t.Fatalf("Expected Content-Type 'text/csv', got '%s'", contentType)
+	}
+	records, readError := csv.NewReader(response.Body).ReadAll()
+	if readError != nil {
+		t.Fatalf("Failed to read CSV response: %v", readError)
+	}
+	if len(records) != 2 || records[1][0] != "ZZBANKXXX" {
+		t.Errorf("Expected header and one ZZBANKXXX row, got %v", records)
+	}
+}
+
+// TestGetSwiftCodeHandler_NotAcceptable expects a 406 for unsupported media types.
+func TestGetSwiftCodeHandler_NotAcceptable(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZBANKXXX", nil)
+	testRequest = mux.SetURLVars(testRequest, map[string]string{"code": "ZZBANKXXX"})
+	testRequest.Header.Set("Accept", "application/pdf")
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.GetSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", responseRecorder)
+
+	if responseRecorder.Code != http.StatusNotAcceptable {
+		t.Fatalf("Expected status 406 Not Acceptable, got %d", responseRecorder.Code)
+	}
+}
+
+// recordingSwiftRepository remembers the last created code.
+type recordingSwiftRepository struct {
+	stubSwiftRepository
+	created models.SwiftCode
+}
+
+func (stub *recordingSwiftRepository) CreateSwiftCode(newCode models.SwiftCode) error {
+	stub.created = newCode
+	return nil
+}
+
+// TestCreateSwiftCodeHandler_BankName sends the documented bankName field and
+// expects it to be stored as the bank name.
+func TestCreateSwiftCodeHandler_BankName(t *testing.T) {
+	requestBody := `{"address":"123 Test Ave","bankName":"Test Bank","countryISO2":"ZZ","countryName":"ZELAND","isHeadquarter":false,"swiftCode":"ZZTEST001"}`
+	testRequest := httptest.NewRequest(http.MethodPost, "/v1/swift-codes", bytes.NewBufferString(requestBody))
+	responseRecorder := httptest.NewRecorder()
+
+	repository := &recordingSwiftRepository{}
+	handlerInstance := &SwiftHTTPHandler{DataStore: repository}
+	handlerInstance.CreateSwiftCode(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodPost, "/v1/swift-codes", responseRecorder)
+
+	if repository.created.Name != "Test Bank" {
+		t.Errorf("Expected bank name 'Test Bank', got '%s'", repository.created.Name)
+	}
+	if repository.created.HqSwiftCode != "ZZTEST00XXX" {
+		t.Errorf("Expected head office 'ZZTEST00XXX', got '%s'", repository.created.HqSwiftCode)
+	}
+}
+
+// TestSearchSwiftCodesHandler tests the GET /v1/swift-codes/search handler.
+func TestSearchSwiftCodesHandler(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/search?q=bank&limit=10", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.SearchSwiftCodes(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/search", responseRecorder)
+
+	var decodedPayload swiftCodeListResponsePayload
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode JSON response: %v", decodeError)
+	}
+	if len(decodedPayload.SwiftCodes) != 1 || decodedPayload.SwiftCodes[0].SwiftCode != "ZZBANKXXX" {
+		t.Errorf("Expected ZZBANKXXX as the only match, got %+v", decodedPayload.SwiftCodes)
+	}
+
+	csvRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/search?q=bank", nil)
+	csvRequest.Header.Set("Accept", "text/csv")
+	csvRecorder := httptest.NewRecorder()
+	handlerInstance.SearchSwiftCodes(csvRecorder, csvRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/search", csvRecorder)
+	records, csvError := csv.NewReader(csvRecorder.Body).ReadAll()
+	if csvError != nil || len(records) != 2 || records[1][0] != "ZZBANKXXX" {
+		t.Errorf("Expected a header and ZZBANKXXX as CSV, got %v (%v)", records, csvError)
+	}
+
+	missingQueryRecorder := httptest.NewRecorder()
+	handlerInstance.SearchSwiftCodes(missingQueryRecorder, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/search", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/search", missingQueryRecorder)
+	if missingQueryRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without q, got %d", missingQueryRecorder.Code)
+	}
+}
+
+// TestBatchGetSwiftCodesHandler tests the POST /v1/swift-codes/batch-get handler.
+func TestBatchGetSwiftCodesHandler(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodPost, "/v1/swift-codes/batch-get",
+		bytes.NewBufferString(`{"swiftCodes":["zzbankxxx","ZZBANKXXX001"]}`))
+	responseRecorder := httptest.NewRecorder()
+
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}}
+	handlerInstance.BatchGetSwiftCodes(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodPost, "/v1/swift-codes/batch-get", responseRecorder)
+
+	var decodedPayload batchGetResponsePayload
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&decodedPayload); decodeError != nil {
+		t.Fatalf("Failed to decode JSON response: %v", decodeError)
+	}
+	if len(decodedPayload.SwiftCodes) != 2 || decodedPayload.SwiftCodes[0].SwiftCode != "ZZBANKXXX" {
+		t.Errorf("Expected both codes in request order, got %+v", decodedPayload.SwiftCodes)
+	}
+}
+
+// TestSwiftCodeLifecycleWithMemoryStore runs requests through the router
+// against a real store, covering what the canned stub cannot: branch
+// linking, conflicts and codes that disappear.
+func TestSwiftCodeLifecycleWithMemoryStore(t *testing.T) {
+	store := &memstore.Store{}
+	store.Load([]models.SwiftCode{
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", Name: "ZELAND NATIONAL BANK", Address: "1 MAIN PLAZA",
+			CountryName: "ZELAND", IsHeadquarter: true},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ001", Name: "ZELAND NATIONAL BANK", Address: "2 SECOND AVE",
+			CountryName: "ZELAND", HqSwiftCode: "ZZBANKZZXXX"},
+	})
+	router := NewRouter(&SwiftHTTPHandler{DataStore: store})
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		return responseRecorder
+	}
+
+	headOffice := serve(http.MethodGet, "/v1/swift-codes/zzbankzzxxx", "")
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", headOffice)
+	var decodedPayload headOfficeResponsePayload
+	json.NewDecoder(headOffice.Body).Decode(&decodedPayload)
+	if len(decodedPayload.Branches) != 1 || decodedPayload.Branches[0].SwiftCode != "ZZBANKZZ001" {
+		t.Fatalf("Expected the head office to list its branch, got %+v", decodedPayload.Branches)
+	}
+
+	duplicate := serve(http.MethodPost, "/v1/swift-codes",
+		`{"swiftCode":"ZZBANKZZXXX","bankName":"IMPOSTER","countryISO2":"ZZ","countryName":"ZELAND","isHeadquarter":true}`)
+	assertMatchesContract(t, http.MethodPost, "/v1/swift-codes", duplicate)
+	if duplicate.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate code, got %d", duplicate.Code)
+	}
+
+	if deleted := serve(http.MethodDelete, "/v1/swift-codes/ZZBANKZZ001", ""); deleted.Code != http.StatusOK {
+		t.Fatalf("Expected the branch to be deleted, got %d", deleted.Code)
+	}
+	missing := serve(http.MethodGet, "/v1/swift-codes/ZZBANKZZ001", "")
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", missing)
+	if missing.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for the deleted branch, got %d", missing.Code)
+	}
+	deletedAgain := serve(http.MethodDelete, "/v1/swift-codes/ZZBANKZZ001", "")
+	assertMatchesContract(t, http.MethodDelete, "/v1/swift-codes/{code}", deletedAgain)
+	if deletedAgain.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", deletedAgain.Code)
+	}
+
+	decodedPayload = headOfficeResponsePayload{}
+	json.NewDecoder(serve(http.MethodGet, "/v1/swift-codes/ZZBANKZZXXX", "").Body).Decode(&decodedPayload)
+	if len(decodedPayload.Branches) != 0 {
+		t.Errorf("Expected no branches after the delete, got %+v", decodedPayload.Branches)
+	}
+	if country := serve(http.MethodGet, "/v1/swift-codes/country/XX", ""); country.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a country with no codes, got %d", country.Code)
+	}
+}
+
+func TestReadOnlyHandlerRejectsWrites(t *testing.T) {
+	store := &memstore.Store{}
+	store.Load([]models.SwiftCode{{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", Name: "ZELAND NATIONAL BANK",
+		CountryName: "ZELAND", IsHeadquarter: true}})
+	router := NewRouter(&SwiftHTTPHandler{DataStore: store, ReadOnly: true})
+
+	writes := []struct{ method, target, pathTemplate, body string }{
+		{http.MethodPost, "/v1/swift-codes", "/v1/swift-codes",
+			`{"swiftCode":"ZZBANKZZ001","bankName":"ZELAND NATIONAL BANK","countryISO2":"ZZ","countryName":"ZELAND","isHeadquarter":false}`},
+		{http.MethodDelete, "/v1/swift-codes/ZZBANKZZXXX", "/v1/swift-codes/{code}", ""},
+		{http.MethodPost, "/v1/webhooks", "/v1/webhooks", `{"url":"https://example.com/hook"}`},
+		{http.MethodDelete, "/v1/webhooks/1", "/v1/webhooks/{id}", ""},
+	}
+	for _, write := range writes {
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, httptest.NewRequest(write.method, write.target, bytes.NewBufferString(write.body)))
+		assertMatchesContract(t, write.method, write.pathTemplate, responseRecorder)
+		if responseRecorder.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", write.method, write.target, responseRecorder.Code)
+		}
+	}
+
+	if store.Len() != 1 {
+		t.Errorf("Expected the store to be untouched, got %d rows", store.Len())
+	}
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZBANKZZXXX", nil))
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Expected reads to keep working, got %d", responseRecorder.Code)
+	}
+}
+
+// branchlessRepository has head offices without branches.
+type branchlessRepository struct{ stubSwiftRepository }
+
+func (stub *branchlessRepository) GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error) {
+	headOfficeData, _, err := stub.stubSwiftRepository.GetSwiftCode(requestedCode)
+	return headOfficeData, nil, err
+}
+
+// TestGetSwiftCodeHandler_HeadOfficeWithoutBranches pins the v1 body of a
+// head office without branches, which sends "branches": null.
+func TestGetSwiftCodeHandler_HeadOfficeWithoutBranches(t *testing.T) {
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ZZLONELYXXX", nil)
+	responseRecorder := httptest.NewRecorder()
+	NewRouter(&SwiftHTTPHandler{DataStore: &branchlessRepository{}}).ServeHTTP(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodGet, "/v1/swift-codes/{code}", responseRecorder)
+
+	const golden = `{"address":"HQ Address","bankName":"HQ Bank","countryISO2":"ZZ","countryName":"ZELAND",` +
+		`"isHeadquarter":true,"swiftCode":"ZZLONELYXXX","branches":null}` + "\n"
+	if body := responseRecorder.Body.String(); body != golden {
+		t.Errorf("Expected the v1 body\n%s\ngot\n%s", golden, body)
+	}
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// swiftCodeV2Payload is the v2 representation of a code: every stored field,
+// named as in the rest of the API. Head offices have no headOffice link;
+// branches is only set on a head office looked up by its code. It is always
+// rendered through project.
+type swiftCodeV2Payload struct {
+	SwiftCode     string
+	CodeType      string
+	BankName      string
+	Address       string
+	TownName      string
+	CountryISO2   string
+	CountryName   string
+	TimeZone      string
+	IsHeadquarter bool
+	HeadOffice    *headOfficeLinkV2
+	Branches      []swiftCodeV2Payload
+}
+
+type headOfficeLinkV2 struct {
+	SwiftCode string `json:"swiftCode"`
+	Href      string `json:"href"`
+}
+
+// this is returned by the v2 “list by country” endpoint
+type countryV2ResponsePayload struct {
+	CountryISO2 string                   `json:"countryISO2"`
+	CountryName string                   `json:"countryName"`
+	SwiftCodes  []map[string]interface{} `json:"swiftCodes"`
+}
+
+// v2Fields are the names ?fields= may select.
+var v2Fields = []string{
+	"swiftCode", "codeType", "bankName", "address", "townName",
+	"countryISO2", "countryName", "timeZone", "isHeadquarter", "headOffice", "branches",
+}
+
+// fieldSelection holds the fields a v2 response is narrowed to; nil keeps them all.
+type fieldSelection map[string]bool
+
+// parseFieldSelection reads ?fields=, a comma separated list of v2Fields;
+// empty names are ignored. It returns the first unknown name as invalid.
+func parseFieldSelection(incomingRequest *http.Request) (selection fieldSelection, invalid string) {
+	fieldsValue := incomingRequest.URL.Query().Get("fields")
+	if fieldsValue == "" {
+		return nil, ""
+	}
+	selection = make(fieldSelection)
+	for _, name := range strings.Split(fieldsValue, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, field := range v2Fields {
+			known = known || field == name
+		}
+		if !known {
+			return nil, name
+		}
+		selection[name] = true
+	}
+	return selection, ""
+}
+
+// project renders payload as a JSON object holding the selected fields.
+// Branches are narrowed to the same fields.
+func (payload swiftCodeV2Payload) project(selection fieldSelection) map[string]interface{} {
+	projected := map[string]interface{}{
+		"swiftCode":     payload.SwiftCode,
+		"codeType":      payload.CodeType,
+		"bankName":      payload.BankName,
+		"address":       payload.Address,
+		"townName":      payload.TownName,
+		"countryISO2":   payload.CountryISO2,
+		"countryName":   payload.CountryName,
+		"timeZone":      payload.TimeZone,
+		"isHeadquarter": payload.IsHeadquarter,
+		"headOffice":    payload.HeadOffice,
+	}
+	if payload.Branches != nil {
+		branches := make([]map[string]interface{}, 0, len(payload.Branches))
+		for _, branch := range payload.Branches {
+			branches = append(branches, branch.project(selection))
+		}
+		projected["branches"] = branches
+	}
+	if selection != nil {
+		for name := range projected {
+			if !selection[name] {
+				delete(projected, name)
+			}
+		}
+	}
+	return projected
+}
+
+// GET /v2/swift-codes/{code}?fields=
+// Returns every stored field of a code; head offices come with their branches.
+func (httpHandler *SwiftHTTPHandler) GetSwiftCodeV2(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	selection, invalidField := parseFieldSelection(incomingRequest)
+	if invalidField != "" {
+		writeError(responseWriter, http.StatusBadRequest, "unknown field "+invalidField)
+		return
+	}
+	row, branchRows, lastModified, found := httpHandler.findSwiftCode(responseWriter, incomingRequest)
+	if !found {
+		return
+	}
+
+	swiftCodePayload := swiftCodeV2From(row)
+	if row.IsHeadquarter {
+		swiftCodePayload.Branches = []swiftCodeV2Payload{}
+		for _, branchRow := range branchRows {
+			swiftCodePayload.Branches = append(swiftCodePayload.Branches, swiftCodeV2From(branchRow))
+		}
+	}
+	writeCachedJSON(responseWriter, incomingRequest, swiftCodePayload.project(selection), httpHandler.cachePolicy(lastModified))
+}
+
+// GET /v2/swift-codes/country/{iso2}?fields=
+// Lists every code of a country; ?fields= narrows each entry.
+func (httpHandler *SwiftHTTPHandler) GetCountrySwiftCodesV2(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	selection, invalidField := parseFieldSelection(incomingRequest)
+	if invalidField != "" {
+		writeError(responseWriter, http.StatusBadRequest, "unknown field "+invalidField)
+		return
+	}
+	requestedISO2, allRows, lastModified, found := httpHandler.findCountrySwiftCodes(responseWriter, incomingRequest)
+	if !found {
+		return
+	}
+
+	countryPayload := countryV2ResponsePayload{CountryISO2: requestedISO2, CountryName: allRows[0].CountryName}
+	for _, row := range allRows {
+		countryPayload.SwiftCodes = append(countryPayload.SwiftCodes, swiftCodeV2From(row).project(selection))
+	}
+	writeCachedJSON(responseWriter, incomingRequest, countryPayload, httpHandler.cachePolicy(lastModified))
+}
+
+// writeCachedJSON is writeNegotiated for responses only offered as JSON.
+func writeCachedJSON(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+	payload interface{},
+	policy cachePolicy,
+) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "encoding failure")
+		return
+	}
+	if policy.writeValidators(responseWriter, incomingRequest, body.Bytes()) {
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Write(body.Bytes())
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"swift-codes-project/memstore"
+	"swift-codes-project/models"
+
+	"github.com/gorilla/mux"
+)
+
+func v2TestHandler() *SwiftHTTPHandler {
+	store := &memstore.Store{}
+	store.Load([]models.SwiftCode{
+		{
+			CountryISO2: "PL", SwiftCode: "PLBANKPLXXX", CodeType: "BIC11", Name: "BANK",
+			Address: "1 MAIN ST", TownName: "WARSZAWA", CountryName: "POLAND", TimeZone: "Europe/Warsaw",
+			IsHeadquarter: true,
+		},
+		{
+			CountryISO2: "PL", SwiftCode: "PLBANKPLKRK", CodeType: "BIC11", Name: "BANK KRAKOW",
+			Address: "2 SIDE ST", TownName: "KRAKOW", CountryName: "POLAND", TimeZone: "Europe/Warsaw",
+			HqSwiftCode: "PLBANKPLXXX",
+		},
+	})
+	return &SwiftHTTPHandler{DataStore: store}
+}
+
+func serveV2(handlerInstance *SwiftHTTPHandler, target string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	recorder := httptest.NewRecorder()
+	NewRouter(handlerInstance).ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decodeObject(t *testing.T, recorder *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var object map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &object); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", recorder.Body.String(), err)
+	}
+	return object
+}
+
+func keysOf(object map[string]interface{}) []string {
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestGetSwiftCodeV2 returns every stored field, with branches linking to
+// their head office.
+func TestGetSwiftCodeV2(t *testing.T) {
+	handlerInstance := v2TestHandler()
+
+	recorder := serveV2(handlerInstance, "/v2/swift-codes/plbankplxxx", nil)
+	assertMatchesContract(t, http.MethodGet, "/v2/swift-codes/{code}", recorder)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", recorder.Code)
+	}
+	headOffice := decodeObject(t, recorder)
+	expected := map[string]interface{}{
+		"swiftCode": "PLBANKPLXXX", "codeType": "BIC11", "bankName": "BANK",
+		"address": "1 MAIN ST", "townName": "WARSZAWA", "countryISO2": "PL",
+		"countryName": "POLAND", "timeZone": "Europe/Warsaw", "isHeadquarter": true,
+		"headOffice": nil,
+		"branches": []interface{}{map[string]interface{}{
+			"swiftCode": "PLBANKPLKRK", "codeType": "BIC11", "bankName": "BANK KRAKOW",
+			"address": "2 SIDE ST", "townName": "KRAKOW", "countryISO2": "PL",
+			"countryName": "POLAND", "timeZone": "Europe/Warsaw", "isHeadquarter": false,
+			"headOffice": map[string]interface{}{"swiftCode": "PLBANKPLXXX", "href": "/v2/swift-codes/PLBANKPLXXX"},
+		}},
+	}
+	if !reflect.DeepEqual(headOffice, expected) {
+		t.Errorf("Unexpected head office:\n%#v", headOffice)
+	}
+
+	recorder = serveV2(handlerInstance, "/v2/swift-codes/PLBANKPLKRK", nil)
+	assertMatchesContract(t, http.MethodGet, "/v2/swift-codes/{code}", recorder)
+	branch := decodeObject(t, recorder)
+	if _, hasBranches := branch["branches"]; hasBranches || branch["headOffice"] == nil {
+		t.Errorf("Expected a branch with a head office link and no branches, got %v", branch)
+	}
+
+	recorder = serveV2(handlerInstance, "/v2/swift-codes/PLBANKPLKRK", http.Header{"If-None-Match": {recorder.Header().Get("ETag")}})
+	assertMatchesContract(t, http.MethodGet, "/v2/swift-codes/{code}", recorder)
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 Not Modified for a matching ETag, got %d", recorder.Code)
+	}
+
+	recorder = serveV2(handlerInstance, "/v2/swift-codes/XXBANKXXXXX", nil)
+	assertMatchesContract(t, http.MethodGet, "/v2/swift-codes/{code}", recorder)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 Not Found, got %d", recorder.Code)
+	}
+}
+
+func TestV2FieldSelection(t *testing.T) {
+	handlerInstance := v2TestHandler()
+
+	recorder := serveV2(handlerInstance, "/v2/swift-codes/PLBANKPLXXX?fields=swiftCode,%20timeZone,branches", nil)
+	assertMatchesContract(t, http.MethodGet, "/v2/swift-codes/{code}", recorder)
+	headOffice := decodeObject(t, recorder)
+	if keys := keysOf(headOffice); !reflect.DeepEqual(keys, []string{"branches", "swiftCode", "timeZone"}) {
+		t.Errorf("Expected only the selected fields, got %v", keys)
+	}
+	branches, _ := headOffice["branches"].([]interface{})
+	if len(branches) != 1 {
+		t.Fatalf("Expected one branch, got %v", headOffice["branches"])
+	}
+	if keys := keysOf(branches[0].(map[string]interface{})); !reflect.DeepEqual(keys, []string{"swiftCode", "timeZone"}) {
+		t.Errorf("Expected branches narrowed to the same fields, got %v", keys)
+	}
+
+	recorder = serveV2(handlerInstance, "/v2/swift-codes/country/pl?fields=swiftCode,headOffice", nil)
+	assertMatchesContract(t, http.MethodGet, "/v2/swift-codes/country/{iso2}", recorder)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 OK, got %d", recorder.Code)
+	}
+	var countryPayload countryV2ResponsePayload
+	json.Unmarshal(recorder.Body.Bytes(), &countryPayload)
+	if countryPayload.CountryISO2 != "PL" || countryPayload.CountryName != "POLAND" || len(countryPayload.SwiftCodes) != 2 {
+		t.Fatalf("Unexpected country payload %+v", countryPayload)
+	}
+	for _, entry := range countryPayload.SwiftCodes {
+		if keys := keysOf(entry); !reflect.DeepEqual(keys, []string{"headOffice", "swiftCode"}) {
+			t.Errorf("Expected headOffice and swiftCode, got %v", keys)
+		}
+	}
+
+	for _, target := range []string{"/v2/swift-codes/PLBANKPLXXX?fields=Name", "/v2/swift-codes/country/PL?fields=swiftCode,,hqSwiftCode"} {
+		recorder := serveV2(handlerInstance, target, nil)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400 Bad Request, got %d", target, recorder.Code)
+		}
+	}
+}
+
+// TestV1AndV2Agree checks the fields both versions expose carry the same
+// values, as they come from the same mapper.
+func TestV1AndV2Agree(t *testing.T) {
+	handlerInstance := v2TestHandler()
+	request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/swift-codes/PLBANKPLKRK", nil), map[string]string{"code": "PLBANKPLKRK"})
+	v1Recorder := httptest.NewRecorder()
+	handlerInstance.GetSwiftCode(v1Recorder, request)
+	v1Branch := decodeObject(t, v1Recorder)
+
+	v2Branch := decodeObject(t, serveV2(handlerInstance, "/v2/swift-codes/PLBANKPLKRK", nil))
+	for field, value := range v1Branch {
+		if v2Branch[field] != value {
+			t.Errorf("%s: v1 has %v, v2 has %v", field, value, v2Branch[field])
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+	"swift-codes-project/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+// WebhookStore manages webhook subscriptions and their delivery log.
+type WebhookStore interface {
+	CreateWebhookSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	ListWebhookSubscriptions() ([]models.WebhookSubscription, error)
+	DeleteWebhookSubscription(id int64) error
+	ListWebhookDeliveries(subscriptionID int64, limit int) ([]models.WebhookDelivery, error)
+}
+
+// validateSubscription normalizes a subscription request, returning a
+// client-facing message when it is invalid.
+func validateSubscription(ctx context.Context, subscription *models.WebhookSubscription) string {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "url must be an absolute http or https URL"
+	}
+	if err := webhook.CheckTarget(ctx, subscription.URL); err != nil {
+		return "url must point to a public address"
+	}
+	if len(subscription.EventTypes) == 0 {
+		return "eventTypes must not be empty"
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !slices.Contains(webhook.EventTypes, eventType) {
+			return "unknown event type " + strconv.Quote(eventType)
+		}
+	}
+	subscription.CountryISO2 = strings.ToUpper(subscription.CountryISO2)
+	if subscription.CountryISO2 != "" && len(subscription.CountryISO2) != 2 {
+		return "countryISO2 must be a two-letter code"
+	}
+	return ""
+}
+
+// POST /v1/webhooks
+// The signing secret is generated when none is supplied and is only returned
+// in this response.
+
+func (httpHandler *SwiftHTTPHandler) CreateWebhookSubscription(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Webhooks == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "webhooks not available")
+		return
+	}
+	var subscription models.WebhookSubscription
+	if err := json.NewDecoder(incomingRequest.Body).Decode(&subscription); err != nil {
+		writeError(responseWriter, http.StatusBadRequest, "bad json")
+		return
+	}
+	if message := validateSubscription(incomingRequest.Context(), &subscription); message != "" {
+		writeError(responseWriter, http.StatusBadRequest, message)
+		return
+	}
+	if subscription.Secret == "" {
+		secretBytes := make([]byte, 32)
+		if _, err := rand.Read(secretBytes); err != nil {
+			writeError(responseWriter, http.StatusInternalServerError, "could not generate a secret")
+			return
+		}
+		subscription.Secret = hex.EncodeToString(secretBytes)
+	}
+
+	created, err := httpHandler.Webhooks.CreateWebhookSubscription(subscription)
+	if err != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusCreated)
+	json.NewEncoder(responseWriter).Encode(created)
+}
+
+// GET /v1/webhooks
+
+func (httpHandler *SwiftHTTPHandler) ListWebhookSubscriptions(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Webhooks == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "webhooks not available")
+		return
+	}
+	subscriptions, err := httpHandler.Webhooks.ListWebhookSubscriptions()
+	if err != nil {
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	listPayload := []models.WebhookSubscription{}
+	for _, subscription := range subscriptions {
+		subscription.Secret = ""
+		listPayload = append(listPayload, subscription)
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(map[string]interface{}{"webhooks": listPayload})
+}
+
+// parseSubscriptionID reads the {id} path variable, responding 404 when it is
+// not a number.
+func parseSubscriptionID(responseWriter http.ResponseWriter, incomingRequest *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(incomingRequest)["id"], 10, 64)
+	if err != nil {
+		writeError(responseWriter, http.StatusNotFound, "not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// DELETE /v1/webhooks/{id}
+
+func (httpHandler *SwiftHTTPHandler) DeleteWebhookSubscription(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Webhooks == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "webhooks not available")
+		return
+	}
+	id, ok := parseSubscriptionID(responseWriter, incomingRequest)
+	if !ok {
+		return
+	}
+	if err := httpHandler.Webhooks.DeleteWebhookSubscription(id); err != nil {
+		if errors.Is(err, service.ErrWebhookSubscriptionNotFound) {
+			writeError(responseWriter, http.StatusNotFound, "not found")
+			return
+		}
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Write([]byte(`{"message":"webhook deleted"}`))
+}
+
+// GET /v1/webhooks/{id}/deliveries
+
+func (httpHandler *SwiftHTTPHandler) ListWebhookDeliveries(
+	responseWriter http.ResponseWriter,
+	incomingRequest *http.Request,
+) {
+	if httpHandler.Webhooks == nil {
+		writeError(responseWriter, http.StatusNotImplemented, "webhooks not available")
+		return
+	}
+	id, ok := parseSubscriptionID(responseWriter, incomingRequest)
+	if !ok {
+		return
+	}
+	deliveries, err := httpHandler.Webhooks.ListWebhookDeliveries(id, 100)
+	if err != nil {
+		if errors.Is(err, service.ErrWebhookSubscriptionNotFound) {
+			writeError(responseWriter, http.StatusNotFound, "not found")
+			return
+		}
+		writeError(responseWriter, http.StatusInternalServerError, "db failure")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(responseWriter).Encode(map[string]interface{}{"deliveries": deliveries})
+}
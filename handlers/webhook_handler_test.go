@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"swift-codes-project/models"
+)
+
+// stubWebhookStore keeps subscriptions in a slice.
+type stubWebhookStore struct {
+	subscriptions []models.WebhookSubscription
+}
+
+func (stub *stubWebhookStore) CreateWebhookSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	subscription.ID = int64(len(stub.subscriptions) + 1)
+	stub.subscriptions = append(stub.subscriptions, subscription)
+	return subscription, nil
+}
+
+func (stub *stubWebhookStore) ListWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	return stub.subscriptions, nil
+}
+
+func (stub *stubWebhookStore) DeleteWebhookSubscription(id int64) error {
+	return nil
+}
+
+func (stub *stubWebhookStore) ListWebhookDeliveries(subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+// TestCreateWebhookSubscriptionHandler creates a subscription, expects a
+// generated secret in the response, and checks the list hides it.
+func TestCreateWebhookSubscriptionHandler(t *testing.T) {
+	store := &stubWebhookStore{}
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Webhooks: store}
+
+	requestBody := `{"url":"https://example.com/hook","eventTypes":["created","deleted"],"countryISO2":"pl"}`
+	testRequest := httptest.NewRequest(http.MethodPost, "/v1/webhooks", bytes.NewBufferString(requestBody))
+	responseRecorder := httptest.NewRecorder()
+	handlerInstance.CreateWebhookSubscription(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodPost, "/v1/webhooks", responseRecorder)
+
+	if responseRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 Created, got %d", responseRecorder.Code)
+	}
+	var created models.WebhookSubscription
+	json.NewDecoder(responseRecorder.Body).Decode(&created)
+	if created.Secret == "" || created.CountryISO2 != "PL" {
+		t.Errorf("Expected a generated secret and country PL, got %+v", created)
+	}
+
+	listRecorder := httptest.NewRecorder()
+	handlerInstance.ListWebhookSubscriptions(listRecorder, httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil))
+	assertMatchesContract(t, http.MethodGet, "/v1/webhooks", listRecorder)
+	var listPayload struct {
+		Webhooks []models.WebhookSubscription `json:"webhooks"`
+	}
+	json.NewDecoder(listRecorder.Body).Decode(&listPayload)
+	if len(listPayload.Webhooks) != 1 || listPayload.Webhooks[0].Secret != "" {
+		t.Errorf("Expected one subscription without its secret, got %+v", listPayload.Webhooks)
+	}
+}
+
+// TestCreateWebhookSubscriptionHandler_Invalid rejects unknown event types, bad
+// URLs and receivers inside the server's network.
+func TestCreateWebhookSubscriptionHandler_Invalid(t *testing.T) {
+	handlerInstance := &SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Webhooks: &stubWebhookStore{}}
+
+	for _, requestBody := range []string{
+		`{"url":"https://example.com/hook","eventTypes":["renamed"]}`,
+		`{"url":"ftp://example.com/hook","eventTypes":["created"]}`,
+		`{"url":"https://example.com/hook","eventTypes":[]}`,
+		`{"url":"http://169.254.169.254/latest/meta-data/","eventTypes":["created"]}`,
+		`{"url":"http://localhost:8080/v1/swift-codes","eventTypes":["created"]}`,
+	} {
+		testRequest := httptest.NewRequest(http.MethodPost, "/v1/webhooks", bytes.NewBufferString(requestBody))
+		responseRecorder := httptest.NewRecorder()
+		handlerInstance.CreateWebhookSubscription(responseRecorder, testRequest)
+		assertMatchesContract(t, http.MethodPost, "/v1/webhooks", responseRecorder)
+
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400 Bad Request, got %d", requestBody, responseRecorder.Code)
+		}
+	}
+}
+
+// TestWebhookRoutesRequireTheAdminToken expects the webhook routes to be
+// refused without the token, and when the server has none configured.
+func TestWebhookRoutesRequireTheAdminToken(t *testing.T) {
+	router := NewRouter(&SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Webhooks: &stubWebhookStore{}, AdminToken: "s3cret"})
+
+	for _, authorization := range []string{"", "Bearer wrong"} {
+		testRequest := httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil)
+		if authorization != "" {
+			testRequest.Header.Set("Authorization", authorization)
+		}
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, testRequest)
+		assertMatchesContract(t, http.MethodGet, "/v1/webhooks", responseRecorder)
+		if responseRecorder.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected status 401 Unauthorized, got %d", authorization, responseRecorder.Code)
+		}
+	}
+
+	testRequest := httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil)
+	testRequest.Header.Set("X-API-Key", "s3cret")
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, testRequest)
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 OK with the token, got %d", responseRecorder.Code)
+	}
+
+	unconfigured := NewRouter(&SwiftHTTPHandler{DataStore: &stubSwiftRepository{}, Webhooks: &stubWebhookStore{}})
+	testRequest = httptest.NewRequest(http.MethodPost, "/v1/webhooks", bytes.NewBufferString(`{}`))
+	responseRecorder = httptest.NewRecorder()
+	unconfigured.ServeHTTP(responseRecorder, testRequest)
+	assertMatchesContract(t, http.MethodPost, "/v1/webhooks", responseRecorder)
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 Forbidden without a configured token, got %d", responseRecorder.Code)
+	}
+}
@@ -0,0 +1,84 @@
+package lookup
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+
+	"swift-codes-project/parser/sheet"
+)
+
+// Directory holds the current Index and lets a new one be swapped in while
+// readers keep using the one they already loaded. The zero value holds an
+// empty index.
+type Directory struct {
+	current atomic.Pointer[Index]
+}
+
+var emptyIndex = NewIndex(nil)
+
+// Index returns the index in use. Callers doing several lookups that must
+// agree with each other should keep the returned value rather than calling
+// Index again.
+func (directory *Directory) Index() *Index {
+	if index := directory.current.Load(); index != nil {
+		return index
+	}
+	return emptyIndex
+}
+
+// Swap installs index and returns the one it replaced.
+func (directory *Directory) Swap(index *Index) *Index {
+	previous := directory.current.Swap(index)
+	if previous == nil {
+		previous = emptyIndex
+	}
+	return previous
+}
+
+// LoadSnapshot reads a snapshot and swaps it in. The current index is kept
+// when the snapshot is invalid.
+func (directory *Directory) LoadSnapshot(source io.Reader) error {
+	codes, err := ReadSnapshot(source)
+	if err != nil {
+		return err
+	}
+	directory.Swap(NewIndex(codes))
+	return nil
+}
+
+// LoadSnapshotFile is LoadSnapshot for a file path.
+func (directory *Directory) LoadSnapshotFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return directory.LoadSnapshot(file)
+}
+
+// LoadExcel maps a vendor spreadsheet as the importer does and swaps it in.
+// Rows an import would skip are left out; they are listed in the returned
+// report.
+func (directory *Directory) LoadExcel(path string, options sheet.Options) (sheet.Report, error) {
+	codes, report, err := sheet.ReadExcel(path, options)
+	if err != nil {
+		return report, err
+	}
+	directory.Swap(NewIndex(codes))
+	return report, nil
+}
+
+// WriteSnapshot writes every code of index in the snapshot format.
+func (index *Index) WriteSnapshot(destination io.Writer) error {
+	snapshotWriter, err := NewSnapshotWriter(destination)
+	if err != nil {
+		return err
+	}
+	for _, sc := range index.codes {
+		if err := snapshotWriter.WriteRow(sc); err != nil {
+			return err
+		}
+	}
+	return snapshotWriter.Close()
+}
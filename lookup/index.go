@@ -0,0 +1,129 @@
+// Package lookup answers SWIFT code queries from an immutable in-memory index,
+// for services that need BIC validation without a network hop. It depends on
+// neither HTTP nor any database: an Index is built from the vendor spreadsheet,
+// read by the parser/sheet package, or from a binary snapshot exported by the
+// service.
+package lookup
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"swift-codes-project/models"
+)
+
+// Index is an immutable set of codes. All methods are safe for concurrent use
+// and return copies, so callers cannot modify the index.
+type Index struct {
+	codes     []models.SwiftCode // sorted by swift code
+	byCode    map[string]int
+	byBIC8    map[string][]int
+	byCountry map[string][]int
+}
+
+// NewIndex builds an index over codes. When a code appears more than once the
+// last occurrence wins.
+func NewIndex(codes []models.SwiftCode) *Index {
+	unique := make(map[string]models.SwiftCode, len(codes))
+	for _, sc := range codes {
+		sc.SwiftCode = strings.ToUpper(sc.SwiftCode)
+		sc.CountryISO2 = strings.ToUpper(sc.CountryISO2)
+		unique[sc.SwiftCode] = sc
+	}
+
+	index := &Index{
+		codes:     make([]models.SwiftCode, 0, len(unique)),
+		byCode:    make(map[string]int, len(unique)),
+		byBIC8:    make(map[string][]int),
+		byCountry: make(map[string][]int),
+	}
+	for _, sc := range unique {
+		index.codes = append(index.codes, sc)
+	}
+	sort.Slice(index.codes, func(i, j int) bool { return index.codes[i].SwiftCode < index.codes[j].SwiftCode })
+
+	for position, sc := range index.codes {
+		index.byCode[sc.SwiftCode] = position
+		if len(sc.SwiftCode) >= 8 {
+			bic8 := sc.SwiftCode[:8]
+			index.byBIC8[bic8] = append(index.byBIC8[bic8], position)
+		}
+		index.byCountry[sc.CountryISO2] = append(index.byCountry[sc.CountryISO2], position)
+	}
+	return index
+}
+
+// Len returns the number of codes in the index.
+func (index *Index) Len() int {
+	return len(index.codes)
+}
+
+func (index *Index) collect(positions []int) []models.SwiftCode {
+	if len(positions) == 0 {
+		return nil
+	}
+	results := make([]models.SwiftCode, len(positions))
+	for i, position := range positions {
+		results[i] = index.codes[position]
+	}
+	return results
+}
+
+// Get returns the code, ignoring case. An 8-character code is treated as the
+// primary office, as if it ended in XXX.
+func (index *Index) Get(code string) (models.SwiftCode, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) == 8 {
+		code += "XXX"
+	}
+	position, ok := index.byCode[code]
+	if !ok {
+		return models.SwiftCode{}, false
+	}
+	return index.codes[position], true
+}
+
+// Valid reports whether code is in the index; see Get.
+func (index *Index) Valid(code string) bool {
+	_, ok := index.Get(code)
+	return ok
+}
+
+// ByBIC8 returns every office whose code starts with the 8-character
+// institution and location prefix of code, in code order.
+func (index *Index) ByBIC8(code string) []models.SwiftCode {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) < 8 {
+		return nil
+	}
+	return index.collect(index.byBIC8[code[:8]])
+}
+
+// ByCountry returns every code of an ISO-2 country, in code order.
+func (index *Index) ByCountry(iso2 string) []models.SwiftCode {
+	return index.collect(index.byCountry[strings.ToUpper(strings.TrimSpace(iso2))])
+}
+
+// ByPrefix returns up to limit codes starting with prefix, in code order. A
+// limit of zero or less returns every match.
+func (index *Index) ByPrefix(prefix string, limit int) []models.SwiftCode {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	start := sort.Search(len(index.codes), func(i int) bool { return index.codes[i].SwiftCode >= prefix })
+	end := start
+	for end < len(index.codes) && strings.HasPrefix(index.codes[end].SwiftCode, prefix) {
+		if limit > 0 && end-start >= limit {
+			break
+		}
+		end++
+	}
+	if start == end {
+		return nil
+	}
+	return slices.Clone(index.codes[start:end])
+}
+
+// Codes returns every code in code order.
+func (index *Index) Codes() []models.SwiftCode {
+	return slices.Clone(index.codes)
+}
@@ -0,0 +1,176 @@
+package lookup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"swift-codes-project/models"
+	"swift-codes-project/parser/sheet"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var testCodes = []models.SwiftCode{
+	{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", Name: "ZELAND BANK", TownName: "CAPITAL", CountryName: "ZELAND", TimeZone: "Europe/Zeland", IsHeadquarter: true},
+	{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ001", Name: "ZELAND BANK", TownName: "PORT", CountryName: "ZELAND", HqSwiftCode: "ZZBANKZZXXX"},
+	{CountryISO2: "ZZ", SwiftCode: "ZZOTHERZXXX", Name: "OTHER BANK", CountryName: "ZELAND", IsHeadquarter: true},
+	{CountryISO2: "YY", SwiftCode: "YYBANKYYXXX", Name: "YLAND BANK", CountryName: "YLAND", IsHeadquarter: true},
+}
+
+// TestIndexLookups covers exact, 8-character, country and prefix lookups.
+func TestIndexLookups(t *testing.T) {
+	index := NewIndex(testCodes)
+	if index.Len() != 4 {
+		t.Fatalf("Expected 4 codes, got %d", index.Len())
+	}
+
+	if sc, ok := index.Get("zzbankzz001"); !ok || sc.TownName != "PORT" {
+		t.Errorf("Expected lowercase lookup of ZZBANKZZ001, got %+v, %v", sc, ok)
+	}
+	if sc, ok := index.Get("ZZBANKZZ"); !ok || sc.SwiftCode != "ZZBANKZZXXX" {
+		t.Errorf("Expected 8-character lookup to find the primary office, got %+v, %v", sc, ok)
+	}
+	if index.Valid("ZZBANKZZ999") {
+		t.Errorf("Expected ZZBANKZZ999 to be invalid")
+	}
+
+	if offices := index.ByBIC8("ZZBANKZZ001"); len(offices) != 2 || offices[0].SwiftCode != "ZZBANKZZ001" {
+		t.Errorf("Expected both ZZBANKZZ offices in code order, got %+v", offices)
+	}
+	if countryCodes := index.ByCountry("zz"); len(countryCodes) != 3 {
+		t.Errorf("Expected 3 codes in ZZ, got %d", len(countryCodes))
+	}
+	if matches := index.ByPrefix("ZZB", 0); len(matches) != 2 {
+		t.Errorf("Expected 2 codes starting with ZZB, got %d", len(matches))
+	}
+	if matches := index.ByPrefix("ZZ", 1); len(matches) != 1 || matches[0].SwiftCode != "ZZBANKZZ001" {
+		t.Errorf("Expected the first ZZ code only, got %+v", matches)
+	}
+	if matches := index.ByPrefix("QQ", 10); matches != nil {
+		t.Errorf("Expected no codes starting with QQ, got %+v", matches)
+	}
+
+	// results are copies
+	index.ByCountry("ZZ")[0].Name = "CHANGED"
+	if sc, _ := index.Get("ZZBANKZZ001"); sc.Name != "ZELAND BANK" {
+		t.Errorf("Expected the index to be unaffected by callers, got name %q", sc.Name)
+	}
+}
+
+// TestSnapshotRoundTrip writes and reads a snapshot and checks corruption and
+// truncation are detected.
+func TestSnapshotRoundTrip(t *testing.T) {
+	var snapshot bytes.Buffer
+	if writeError := NewIndex(testCodes).WriteSnapshot(&snapshot); writeError != nil {
+		t.Fatalf("Failed to write snapshot: %v", writeError)
+	}
+
+	codes, readError := ReadSnapshot(bytes.NewReader(snapshot.Bytes()))
+	if readError != nil {
+		t.Fatalf("Failed to read snapshot: %v", readError)
+	}
+	if !reflect.DeepEqual(codes, NewIndex(testCodes).Codes()) {
+		t.Errorf("Snapshot did not round-trip:\n%+v", codes)
+	}
+
+	corrupted := bytes.Clone(snapshot.Bytes())
+	corrupted[20] ^= 0xFF
+	if _, err := ReadSnapshot(bytes.NewReader(corrupted)); !errors.Is(err, ErrCorruptSnapshot) {
+		t.Errorf("Expected ErrCorruptSnapshot for a flipped byte, got: %v", err)
+	}
+	truncated := snapshot.Bytes()[:snapshot.Len()-3]
+	if _, err := ReadSnapshot(bytes.NewReader(truncated)); !errors.Is(err, ErrCorruptSnapshot) {
+		t.Errorf("Expected ErrCorruptSnapshot for a truncated snapshot, got: %v", err)
+	}
+}
+
+// TestDirectoryHotSwap swaps indexes while readers are running and checks an
+// invalid snapshot leaves the current index in place.
+func TestDirectoryHotSwap(t *testing.T) {
+	var directory Directory
+	if directory.Index().Len() != 0 {
+		t.Fatalf("Expected the zero Directory to hold an empty index")
+	}
+
+	var snapshot bytes.Buffer
+	NewIndex(testCodes).WriteSnapshot(&snapshot)
+	if loadError := directory.LoadSnapshot(bytes.NewReader(snapshot.Bytes())); loadError != nil {
+		t.Fatalf("Failed to load snapshot: %v", loadError)
+	}
+
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for j := 0; j < 1000; j++ {
+				if !directory.Index().Valid("ZZBANKZZXXX") {
+					t.Errorf("ZZBANKZZXXX missing during swap")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		directory.Swap(NewIndex(testCodes[:1+i%len(testCodes)]))
+	}
+	readers.Wait()
+
+	directory.Swap(NewIndex(testCodes))
+	if loadError := directory.LoadSnapshot(bytes.NewReader([]byte("not a snapshot"))); loadError == nil {
+		t.Fatalf("Expected an error loading an invalid snapshot")
+	}
+	if directory.Index().Len() != len(testCodes) {
+		t.Errorf("Expected the previous index to stay in place, got %d codes", directory.Index().Len())
+	}
+}
+
+// TestDirectoryLoadExcel loads a vendor spreadsheet.
+func TestDirectoryLoadExcel(t *testing.T) {
+	workbook := excelize.NewFile()
+	rows := [][]interface{}{
+		{"COUNTRY ISO2 CODE", "SWIFT CODE", "NAME", "COUNTRY NAME"},
+		{"ZZ", "ZZBANKZZXXX", "ZELAND BANK", "ZELAND"},
+		{"ZZ", "ZZBANKZZ001", "ZELAND BANK", "ZELAND"},
+	}
+	for i, row := range rows {
+		cellName, _ := excelize.CoordinatesToCellName(1, i+1)
+		workbook.SetSheetRow("Sheet1", cellName, &row)
+	}
+	filePath := filepath.Join(t.TempDir(), "codes.xlsx")
+	if saveError := workbook.SaveAs(filePath); saveError != nil {
+		t.Fatalf("Failed to save workbook: %v", saveError)
+	}
+	workbook.Close()
+
+	var directory Directory
+	if _, loadError := directory.LoadExcel(filePath, sheet.Options{}); loadError != nil {
+		t.Fatalf("Failed to load workbook: %v", loadError)
+	}
+	branch, ok := directory.Index().Get("ZZBANKZZ001")
+	if !ok || branch.HqSwiftCode != "ZZBANKZZXXX" {
+		t.Errorf("Expected the branch linked to its head office, got %+v, %v", branch, ok)
+	}
+}
+
+// BenchmarkIndexGet looks up codes in a 100k entry index.
+func BenchmarkIndexGet(b *testing.B) {
+	codes := make([]models.SwiftCode, 100000)
+	for i := range codes {
+		codes[i] = models.SwiftCode{CountryISO2: "ZZ", SwiftCode: fmt.Sprintf("ZZ%06dXXX", i)}
+	}
+	index := NewIndex(codes)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !index.Valid(codes[i%len(codes)].SwiftCode) {
+			b.Fatal("code missing")
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package lookup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"swift-codes-project/models"
+)
+
+// SnapshotContentType is the MIME type of the binary snapshot format.
+const SnapshotContentType = "application/vnd.swift-codes.snapshot"
+
+// snapshotMagic opens every snapshot; the trailing digit is the format version.
+var snapshotMagic = []byte("SWIFTIX1")
+
+const (
+	snapshotEnd    byte = 0
+	snapshotRecord byte = 1
+	// maxSnapshotString guards against allocating huge buffers for a corrupt length.
+	maxSnapshotString = 1 << 16
+)
+
+// ErrCorruptSnapshot is returned when a snapshot is truncated, has the wrong
+// header, or fails its checksum.
+var ErrCorruptSnapshot = errors.New("corrupt snapshot")
+
+// SnapshotWriter streams codes in the snapshot format: the magic header, one
+// record per code, and a trailer holding the record count and a CRC-32 of
+// everything before it. Nothing is valid until Close has written the trailer.
+type SnapshotWriter struct {
+	writer   *bufio.Writer
+	checksum hash.Hash32
+	count    uint64
+	buffer   []byte
+	err      error
+}
+
+// NewSnapshotWriter writes the snapshot header to destination.
+func NewSnapshotWriter(destination io.Writer) (*SnapshotWriter, error) {
+	snapshotWriter := &SnapshotWriter{writer: bufio.NewWriter(destination), checksum: crc32.NewIEEE()}
+	snapshotWriter.write(snapshotMagic)
+	return snapshotWriter, snapshotWriter.err
+}
+
+// write sends data to the destination and the checksum, remembering the
+// first error.
+func (snapshotWriter *SnapshotWriter) write(data []byte) {
+	if snapshotWriter.err != nil {
+		return
+	}
+	snapshotWriter.checksum.Write(data)
+	_, snapshotWriter.err = snapshotWriter.writer.Write(data)
+}
+
+// WriteRow appends one code.
+func (snapshotWriter *SnapshotWriter) WriteRow(sc models.SwiftCode) error {
+	record := append(snapshotWriter.buffer[:0], snapshotRecord)
+	for _, field := range []string{
+		sc.CountryISO2, sc.SwiftCode, sc.CodeType, sc.Name, sc.Address,
+		sc.TownName, sc.CountryName, sc.TimeZone, sc.HqSwiftCode,
+	} {
+		record = binary.AppendUvarint(record, uint64(len(field)))
+		record = append(record, field...)
+	}
+	var flags byte
+	if sc.IsHeadquarter {
+		flags = 1
+	}
+	record = append(record, flags)
+	snapshotWriter.buffer = record
+
+	snapshotWriter.write(record)
+	snapshotWriter.count++
+	return snapshotWriter.err
+}
+
+// Close writes the trailer and flushes buffered output.
+func (snapshotWriter *SnapshotWriter) Close() error {
+	trailer := binary.AppendUvarint([]byte{snapshotEnd}, snapshotWriter.count)
+	snapshotWriter.write(trailer)
+	if snapshotWriter.err != nil {
+		return snapshotWriter.err
+	}
+	if err := binary.Write(snapshotWriter.writer, binary.BigEndian, snapshotWriter.checksum.Sum32()); err != nil {
+		return err
+	}
+	return snapshotWriter.writer.Flush()
+}
+
+// checksumReader feeds every byte read into a checksum.
+type checksumReader struct {
+	reader   *bufio.Reader
+	checksum hash.Hash32
+}
+
+func (checksummed *checksumReader) ReadByte() (byte, error) {
+	b, err := checksummed.reader.ReadByte()
+	if err == nil {
+		checksummed.checksum.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (checksummed *checksumReader) readFull(data []byte) error {
+	if _, err := io.ReadFull(checksummed.reader, data); err != nil {
+		return err
+	}
+	checksummed.checksum.Write(data)
+	return nil
+}
+
+func (checksummed *checksumReader) readString() (string, error) {
+	length, err := binary.ReadUvarint(checksummed)
+	if err != nil {
+		return "", err
+	}
+	if length > maxSnapshotString {
+		return "", fmt.Errorf("%w: field of %d bytes", ErrCorruptSnapshot, length)
+	}
+	data := make([]byte, length)
+	if err := checksummed.readFull(data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ReadSnapshot decodes every code of a snapshot, verifying its record count
+// and checksum.
+func ReadSnapshot(source io.Reader) ([]models.SwiftCode, error) {
+	checksummed := &checksumReader{reader: bufio.NewReader(source), checksum: crc32.NewIEEE()}
+	codes, err := readSnapshotRecords(checksummed)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+	}
+	return codes, err
+}
+
+func readSnapshotRecords(checksummed *checksumReader) ([]models.SwiftCode, error) {
+	header := make([]byte, len(snapshotMagic))
+	if err := checksummed.readFull(header); err != nil {
+		return nil, err
+	}
+	if string(header) != string(snapshotMagic) {
+		return nil, fmt.Errorf("%w: unknown header %q", ErrCorruptSnapshot, header)
+	}
+
+	var codes []models.SwiftCode
+	for {
+		marker, err := checksummed.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if marker == snapshotEnd {
+			break
+		}
+		if marker != snapshotRecord {
+			return nil, fmt.Errorf("%w: unknown record marker %d", ErrCorruptSnapshot, marker)
+		}
+
+		var sc models.SwiftCode
+		for _, field := range []*string{
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType, &sc.Name, &sc.Address,
+			&sc.TownName, &sc.CountryName, &sc.TimeZone, &sc.HqSwiftCode,
+		} {
+			if *field, err = checksummed.readString(); err != nil {
+				return nil, err
+			}
+		}
+		flags, err := checksummed.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		sc.IsHeadquarter = flags&1 != 0
+		codes = append(codes, sc)
+	}
+
+	count, err := binary.ReadUvarint(checksummed)
+	if err != nil {
+		return nil, err
+	}
+	expectedChecksum := checksummed.checksum.Sum32()
+	var storedChecksum uint32
+	if err := binary.Read(checksummed.reader, binary.BigEndian, &storedChecksum); err != nil {
+		return nil, err
+	}
+	if storedChecksum != expectedChecksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+	}
+	if count != uint64(len(codes)) {
+		return nil, fmt.Errorf("%w: expected %d records, found %d", ErrCorruptSnapshot, count, len(codes))
+	}
+	return codes, nil
+}
@@ -1,41 +1,342 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"swift-codes-project/backup"
+	"swift-codes-project/cache"
+	"swift-codes-project/changefeed"
 	"swift-codes-project/db"
+	"swift-codes-project/dbsnapshot"
+	"swift-codes-project/graphqlapi"
+	"swift-codes-project/grpcapi"
 	handler "swift-codes-project/handlers"
+	"swift-codes-project/memstore"
 	"swift-codes-project/parser"
 	"swift-codes-project/service"
-
-	"github.com/gorilla/mux"
+	"swift-codes-project/webhook"
+	"time"
 )
 
+const dataFile = "data/SWIFT_CODES.xlsx"
+
+// backend is what the HTTP, gRPC and GraphQL APIs are built from. Features a
+// backend cannot provide are left nil, and their endpoints answer 501.
+type backend struct {
+	lookups   handler.SwiftDataStore
+	graphQL   graphqlapi.Store
+	datasets  handler.DatasetStore
+	changes   *changefeed.Feed
+	webhooks  handler.WebhookStore
+	freshness handler.FreshnessStore
+	backups   handler.BackupStore
+	countries handler.CountryStore
+	// institutions serves GET /v1/institutions/{bankCode}.
+	institutions handler.InstitutionStore
+	// snapshotDir holds published snapshots, served under /snapshots/.
+	snapshotDir string
+}
+
 func main() {
-	// Initialize the SQLite database. The DSN specifies that the database is stored in a file.
-	database, err := db.InitDB("file:swift_codes.db?cache=shared&_fk=1")
-	if err != nil {
-		log.Fatalf("Could not initialize DB: %v", err)
+	readOnly := false
+	if configured := os.Getenv(db.ReadOnlyEnv); configured != "" {
+		parsed, err := strconv.ParseBool(configured)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", db.ReadOnlyEnv, err)
+		}
+		readOnly = parsed
+	}
+
+	var served backend
+	switch {
+	case strings.EqualFold(os.Getenv(db.DriverEnv), memstore.DriverName):
+		served = memoryBackend()
+	case os.Getenv(dbsnapshot.SourceEnv) != "":
+		readOnly = true
+		var closeDatabase func()
+		served, closeDatabase = replicaBackend()
+		defer closeDatabase()
+	default:
+		var closeDatabase func()
+		served, closeDatabase = databaseBackend(readOnly)
+		defer closeDatabase()
+	}
+	if readOnly {
+		log.Println("Read-only mode: write endpoints are disabled")
 	}
-	defer database.Close()
 
-	// parse and store data from the XLSX file.
-	err = parser.ParseExcelAndStore(database, "data/SWIFT_CODES.xlsx")
+	httpHandler := &handler.SwiftHTTPHandler{
+		DataStore:    served.lookups,
+		Datasets:     served.datasets,
+		Changes:      served.changes,
+		Webhooks:     served.webhooks,
+		Freshness:    served.freshness,
+		Backups:      served.backups,
+		Countries:    served.countries,
+		Institutions: served.institutions,
+		ReadOnly:     readOnly,
+		AdminToken:   os.Getenv(handler.AdminTokenEnv),
+	}
+
+	// serve the gRPC API on its own port
+	grpcServer := grpcapi.NewGRPCServer(&grpcapi.SwiftCodeServer{
+		DataStore: served.lookups,
+		Changes:   served.changes,
+		ReadOnly:  readOnly,
+		Countries: served.countries,
+	})
+	go func() {
+		listener, err := net.Listen("tcp", ":9090")
+		if err != nil {
+			log.Fatalf("gRPC listen failed: %v", err)
+		}
+		log.Println("gRPC server is starting on :9090...")
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+
+	graphQLHandler, err := graphqlapi.NewHandler(served.graphQL)
 	if err != nil {
-		log.Printf("Failed to parse/store Excel data: %v", err)
+		log.Fatalf("Could not build GraphQL schema: %v", err)
 	}
 
-	repo := &service.SwiftRepository{DB: database}
-	httpHandler := &handler.SwiftHTTPHandler{DataStore: repo}
 	// Set up the router for HTTP endpoints
-	router := mux.NewRouter()
-	router.HandleFunc("/v1/swift-codes/country/{iso2}", httpHandler.GetCountrySwiftCodes).Methods("GET")
-	router.HandleFunc("/v1/swift-codes/{code}", httpHandler.GetSwiftCode).Methods("GET")
-	router.HandleFunc("/v1/swift-codes", httpHandler.CreateSwiftCode).Methods("POST")
-	router.HandleFunc("/v1/swift-codes/{code}", httpHandler.DeleteSwiftCode).Methods("DELETE")
+	router := handler.NewRouter(httpHandler)
+	router.Handle("/graphql", graphQLHandler).Methods("GET", "POST")
+	router.Handle("/debug/vars", expvar.Handler()).Methods("GET")
+	if served.snapshotDir != "" {
+		// snapshots are only fetched by replicas, which send the admin token
+		snapshots := http.StripPrefix("/snapshots/", http.FileServer(http.Dir(served.snapshotDir)))
+		router.PathPrefix("/snapshots/").Handler(handler.RequireAdmin(httpHandler.AdminToken, snapshots)).Methods("GET")
+	}
 
 	log.Println("Server is starting on :8080...")
 	if err := http.ListenAndServe(":8080", router); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// memoryBackend serves the spreadsheet from memory, for demos. Writes are lost
+// on restart, and dataset history, the change log and webhooks are unavailable.
+func memoryBackend() backend {
+	store := &memstore.Store{}
+	codes, report, err := parser.ReadExcel(dataFile, parser.ImportOptions{CheckCountries: true})
+	if err != nil {
+		log.Printf("Failed to parse Excel data: %v", err)
+	}
+	for _, skipped := range report.SkippedRows {
+		log.Printf("Skipped row %d: %s", skipped.RowNumber, skipped.Reason)
+	}
+	if _, err := store.Load(codes); err != nil {
+		log.Printf("Failed to load Excel data: %v", err)
+	}
+	log.Printf("Serving %d swift codes from memory", store.Len())
+	return backend{lookups: store, graphQL: store, countries: store, institutions: store}
+}
+
+// databaseBackend opens SQLite in a local file unless SWIFT_DB_DRIVER and
+// SWIFT_DB_DSN point somewhere else, such as PostgreSQL, tuned by the
+// SWIFT_DB_* settings db.SettingsFromEnv reads, and starts the
+// background workers that need the database. A writer imports the spreadsheet
+// and sends reads to SWIFT_DB_READ_DSN, or for a SQLite file to a read-only
+// pool on that file; a read-only server serves the database as it finds it.
+func databaseBackend(readOnly bool) (backend, func()) {
+	dialect, dsn := db.SQLite, "file:swift_codes.db?_fk=1"
+	if driver := os.Getenv(db.DriverEnv); driver != "" {
+		configured, err := db.DialectByName(driver)
+		if err != nil {
+			log.Fatalf("Could not initialize DB: %v", err)
+		}
+		dialect = configured
+	}
+	if configured := os.Getenv(db.DSNEnv); configured != "" {
+		dsn = configured
+	}
+	settings, err := db.SettingsFromEnv()
+	if err != nil {
+		log.Fatalf("Could not initialize DB: %v", err)
+	}
+	open := settings.Open
+	if readOnly {
+		open = settings.OpenReadOnly
+	}
+	database, err := open(dialect, dsn)
+	if err != nil {
+		log.Fatalf("Could not initialize DB: %v", err)
+	}
+	closeDatabases := func() { database.Close() }
+
+	// the SQLite writer holds a single connection, so reads get a read-only
+	// pool of their own on the same file unless told to go elsewhere
+	readDSN := os.Getenv(db.ReadDSNEnv)
+	if readDSN == "" && dialect == db.SQLite && !strings.Contains(dsn, "mode=memory") && !strings.Contains(dsn, ":memory:") {
+		readDSN = dsn
+	}
+	var readDatabase *db.DB
+	if readDSN != "" && !readOnly {
+		readDatabase, err = settings.OpenReadOnly(dialect, readDSN)
+		if err != nil {
+			log.Fatalf("Could not initialize read DB: %v", err)
+		}
+		closeDatabases = func() {
+			readDatabase.Close()
+			database.Close()
+		}
+	}
+	repo, err := service.NewSwiftRepository(database, readDatabase)
+	if err != nil {
+		log.Fatalf("Could not prepare queries: %v", err)
+	}
+
+	if !readOnly {
+		// parse and store data from the XLSX file, recording it as a dataset
+		// version in the same transaction.
+		now := time.Now()
+		report, err := parser.ImportExcel(database, dataFile, parser.ImportOptions{
+			CheckCountries: true,
+			Finish: func(tx *db.Tx) error {
+				_, err := service.RecordDatasetVersionTx(tx, service.DatasetVersionName(dataFile, now), dataFile, now)
+				return err
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to parse/store Excel data: %v", err)
+		}
+		for _, skipped := range report.SkippedRows {
+			log.Printf("Skipped row %d: %s", skipped.RowNumber, skipped.Reason)
+		}
+	}
+
+	served := repositoryBackend(repo)
+
+	// deliver webhooks in the background for as long as the server runs; only
+	// the writer does, so replicas do not deliver the same event again
+	if !readOnly {
+		dispatcher := &webhook.Dispatcher{Store: repo}
+		go dispatcher.Run(context.Background())
+	}
+
+	// drop change log entries once they are older than the retention window
+	if !readOnly {
+		retention := changefeed.DefaultRetention
+		if value := os.Getenv(changefeed.RetentionEnv); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed < 0 {
+				log.Fatalf("Invalid %s: %q", changefeed.RetentionEnv, value)
+			}
+			retention = parsed
+		}
+		if retention > 0 {
+			go changefeed.Prune(context.Background(), repo, retention, time.Hour)
+		}
+	}
+
+	// back up on request through POST /v1/admin/backups
+	if dir := os.Getenv(backup.DirEnv); dir != "" {
+		served.backups = backupManager(database, dir)
+	}
+
+	// publish signed snapshots for replicas whenever the data changes
+	if dir := os.Getenv(dbsnapshot.DirEnv); dir != "" && !readOnly {
+		key, err := dbsnapshot.ReadPrivateKey(os.Getenv(dbsnapshot.KeyEnv))
+		if err != nil {
+			log.Fatalf("Could not read snapshot signing key: %v", err)
+		}
+		publisher := &dbsnapshot.Publisher{DB: database, Dir: dir, Key: key}
+		go publisher.Run(context.Background())
+		served.snapshotDir = dir
+	}
+	return served, closeDatabases
+}
+
+// backupManager configures backups of database into dir from the environment.
+func backupManager(database *db.DB, dir string) *backup.Manager {
+	if database.Dialect != db.SQLite {
+		log.Fatalf("%s needs SQLite; back up %s with its own tools", backup.DirEnv, database.Dialect.Name)
+	}
+	manager := &backup.Manager{DB: database, Dir: dir, Compression: os.Getenv(backup.CompressionEnv), Keep: 7, MinInterval: time.Hour}
+	if manager.Compression != "" {
+		if err := backup.CheckCompression(manager.Compression); err != nil {
+			log.Fatalf("Invalid %s: %v", backup.CompressionEnv, err)
+		}
+	}
+	if keep := os.Getenv(backup.KeepEnv); keep != "" {
+		parsed, err := strconv.Atoi(keep)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", backup.KeepEnv, err)
+		}
+		manager.Keep = parsed
+	}
+	if interval := os.Getenv(backup.MinIntervalEnv); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed < 0 {
+			log.Fatalf("Invalid %s: %q", backup.MinIntervalEnv, interval)
+		}
+		manager.MinInterval = parsed
+	}
+	return manager
+}
+
+// replicaBackend serves the newest snapshot published at
+// SWIFT_SNAPSHOT_SOURCE and swaps in each new one once it has been verified.
+func replicaBackend() (backend, func()) {
+	publicKey, err := dbsnapshot.ReadPublicKey(os.Getenv(dbsnapshot.PublicKeyEnv))
+	if err != nil {
+		log.Fatalf("Could not read snapshot public key: %v", err)
+	}
+	dir := os.Getenv(dbsnapshot.DirEnv)
+	if dir == "" {
+		dir = "snapshots"
+	}
+
+	// every read goes to the installed snapshot; there is no write pool
+	repo := &service.SwiftRepository{}
+	replica := &dbsnapshot.Replica{
+		Source:    dbsnapshot.NewSource(os.Getenv(dbsnapshot.SourceEnv), os.Getenv(dbsnapshot.SourceTokenEnv)),
+		PublicKey: publicKey,
+		Dir:       dir,
+		Install:   repo.SwapReadDB,
+	}
+	if _, err := replica.Sync(context.Background()); err != nil {
+		log.Fatalf("Could not install a snapshot: %v", err)
+	}
+	log.Printf("Serving snapshot %s", replica.Current().File)
+	go replica.Run(context.Background())
+
+	served := repositoryBackend(repo)
+	// webhook subscriptions live on the writer
+	served.webhooks = nil
+	return served, func() { repo.SwapReadDB(nil).Close() }
+}
+
+// repositoryBackend serves repo, with single-code and country lookups from
+// memory; the change log keeps the cache in step with imports and writes from
+// other processes.
+func repositoryBackend(repo *service.SwiftRepository) backend {
+	changes := &changefeed.Feed{Store: repo}
+	cachedRepo := &cache.Store{Next: repo, Freshness: repo}
+	latestChange, err := repo.LatestChangeSequence()
+	if err != nil {
+		log.Fatalf("Could not read change log: %v", err)
+	}
+	go cachedRepo.Watch(context.Background(), changes, latestChange)
+	expvar.Publish("swiftCodeCache", expvar.Func(func() interface{} { return cachedRepo.Stats() }))
+
+	return backend{
+		lookups:      cachedRepo,
+		graphQL:      repo,
+		datasets:     repo,
+		changes:      changes,
+		webhooks:     repo,
+		freshness:    cachedRepo,
+		countries:    repo,
+		institutions: repo,
+	}
+}
@@ -0,0 +1,298 @@
+// Package memstore keeps swift codes in memory, behind the same
+// handler.SwiftDataStore interface as the SQL repository. Nothing is persisted,
+// which suits tests and demo environments.
+package memstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"swift-codes-project/countries"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// DriverName selects the in-memory store where a database driver is expected,
+// e.g. SWIFT_DB_DRIVER=memory.
+const DriverName = "memory"
+
+// Store is a concurrency-safe in-memory SwiftDataStore. It links branches to
+// head offices through HqSwiftCode and reports service.ErrNotFound and
+// service.ErrAlreadyExists exactly as service.SwiftRepository does. The zero
+// value is an empty store ready for use.
+type Store struct {
+	mu    sync.RWMutex
+	codes map[string]models.SwiftCode
+	// branches and countries index codes by HqSwiftCode and CountryISO2.
+	branches  map[string]map[string]struct{}
+	countries map[string]map[string]struct{}
+}
+
+// Load adds rows to the store, as an import into an empty table would. It
+// stops at the first duplicate code and returns how many rows were added.
+func (store *Store) Load(rows []models.SwiftCode) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i, row := range rows {
+		if _, exists := store.codes[row.SwiftCode]; exists {
+			return i, service.ErrAlreadyExists
+		}
+		store.put(row)
+	}
+	return len(rows), nil
+}
+
+// Len returns the number of stored codes.
+func (store *Store) Len() int {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return len(store.codes)
+}
+
+// GetSwiftCode returns the row for requestedCode and, when it is a head
+// office, its branches ordered by swift code.
+func (store *Store) GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	row, ok := store.codes[requestedCode]
+	if !ok {
+		return models.SwiftCode{}, nil, service.ErrNotFound
+	}
+	if !row.IsHeadquarter {
+		return row, nil, nil
+	}
+	return row, store.rowsOf(store.branches[row.SwiftCode]), nil
+}
+
+// GetCountrySwiftCodes returns every row of the ISO-2 country, ordered by swift code.
+func (store *Store) GetCountrySwiftCodes(requestedISO2 string) ([]models.SwiftCode, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.rowsOf(store.countries[requestedISO2]), nil
+}
+
+// GetSwiftCodesByCode returns the rows whose swift code is in codes, ordered
+// by swift code. Codes with no row are simply absent from the result.
+func (store *Store) GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	matched := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		if _, ok := store.codes[code]; ok {
+			matched[code] = struct{}{}
+		}
+	}
+	return store.rowsOf(matched), nil
+}
+
+// GetBranchesByHeadOffice returns the branch rows of every head office in
+// hqCodes, ordered by swift code.
+func (store *Store) GetBranchesByHeadOffice(hqCodes []string) ([]models.SwiftCode, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	matched := make(map[string]struct{})
+	for _, hqCode := range hqCodes {
+		for code := range store.branches[hqCode] {
+			matched[code] = struct{}{}
+		}
+	}
+	return store.rowsOf(matched), nil
+}
+
+// CreateSwiftCode adds a row, or returns service.ErrAlreadyExists.
+func (store *Store) CreateSwiftCode(newEntry models.SwiftCode) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, exists := store.codes[newEntry.SwiftCode]; exists {
+		return service.ErrAlreadyExists
+	}
+	store.put(newEntry)
+	return nil
+}
+
+// UpdateSwiftCode replaces the row with the same swift code, or returns
+// service.ErrNotFound.
+func (store *Store) UpdateSwiftCode(changedEntry models.SwiftCode) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	previous, exists := store.codes[changedEntry.SwiftCode]
+	if !exists {
+		return service.ErrNotFound
+	}
+	store.remove(previous)
+	store.put(changedEntry)
+	return nil
+}
+
+// DeleteSwiftCode removes a row, or returns service.ErrNotFound. Branches of
+// a deleted head office are kept, as they are in the database.
+func (store *Store) DeleteSwiftCode(codeToDelete string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	previous, exists := store.codes[codeToDelete]
+	if !exists {
+		return service.ErrNotFound
+	}
+	store.remove(previous)
+	return nil
+}
+
+// SearchSwiftCodes returns up to limit rows, ordered by swift code, whose
+// swift code starts with query or whose bank or town name contains it,
+// ignoring case. iso2, when not empty, restricts the search to one country.
+// A negative limit means no limit, as in SQLite.
+func (store *Store) SearchSwiftCodes(query, requestedISO2 string, limit int) ([]models.SwiftCode, error) {
+	needle := strings.ToUpper(query)
+	var results []models.SwiftCode
+	for _, row := range store.sorted(requestedISO2) {
+		if limit >= 0 && len(results) >= limit {
+			break
+		}
+		if strings.HasPrefix(strings.ToUpper(row.SwiftCode), needle) ||
+			strings.Contains(strings.ToUpper(row.Name), needle) ||
+			strings.Contains(strings.ToUpper(row.TownName), needle) {
+			results = append(results, row)
+		}
+	}
+	return results, nil
+}
+
+// ExportSwiftCodes visits every row, optionally limited to one ISO-2 country,
+// in swift code order, stopping at the first error. It visits a copy taken
+// up front, so visit may call back into the store.
+func (store *Store) ExportSwiftCodes(requestedISO2 string, visit func(models.SwiftCode) error) error {
+	for _, row := range store.sorted(requestedISO2) {
+		if err := visit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCountries returns the embedded ISO 3166-1 countries with how many
+// stored codes each has, as the countries table would.
+func (store *Store) ListCountries() ([]models.Country, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	list := countries.All()
+	for i := range list {
+		list[i].SwiftCodeCount = len(store.countries[list[i].ISO2])
+	}
+	return list, nil
+}
+
+// GetCountry returns the embedded country whose ISO-2 code is iso2, or
+// service.ErrNotFound.
+func (store *Store) GetCountry(iso2 string) (models.Country, error) {
+	country, ok := countries.Lookup(iso2)
+	if !ok {
+		return country, service.ErrNotFound
+	}
+	return country, nil
+}
+
+// GetInstitution returns the institution whose codes start with bankCode,
+// named after a head office when it has one, and every office it has ordered
+// by country and swift code. It returns service.ErrNotFound when there are none.
+func (store *Store) GetInstitution(bankCode string) (models.Institution, []models.SwiftCode, error) {
+	store.mu.RLock()
+	var offices []models.SwiftCode
+	for code, row := range store.codes {
+		if len(code) >= 4 && strings.EqualFold(code[:4], bankCode) {
+			offices = append(offices, row)
+		}
+	}
+	store.mu.RUnlock()
+	if len(offices) == 0 {
+		return models.Institution{}, nil, service.ErrNotFound
+	}
+	sort.Slice(offices, func(i, j int) bool {
+		if offices[i].CountryISO2 != offices[j].CountryISO2 {
+			return offices[i].CountryISO2 < offices[j].CountryISO2
+		}
+		return offices[i].SwiftCode < offices[j].SwiftCode
+	})
+	institution := models.Institution{BankCode: bankCode, Name: offices[0].Name}
+	for _, office := range offices {
+		if office.IsHeadquarter {
+			institution.Name = office.Name
+			break
+		}
+	}
+	return institution, offices, nil
+}
+
+// sorted copies every row, or the rows of one country, ordered by swift code.
+func (store *Store) sorted(requestedISO2 string) []models.SwiftCode {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if requestedISO2 != "" {
+		return store.rowsOf(store.countries[requestedISO2])
+	}
+	rows := make([]models.SwiftCode, 0, len(store.codes))
+	for _, row := range store.codes {
+		rows = append(rows, row)
+	}
+	sortByCode(rows)
+	return rows
+}
+
+// rowsOf looks up every code of an index set; callers hold the lock. It
+// returns nil for an empty set, like a query that matched nothing.
+func (store *Store) rowsOf(codes map[string]struct{}) []models.SwiftCode {
+	if len(codes) == 0 {
+		return nil
+	}
+	rows := make([]models.SwiftCode, 0, len(codes))
+	for code := range codes {
+		rows = append(rows, store.codes[code])
+	}
+	sortByCode(rows)
+	return rows
+}
+
+// put stores row and indexes it; callers hold the write lock.
+func (store *Store) put(row models.SwiftCode) {
+	if store.codes == nil {
+		store.codes = make(map[string]models.SwiftCode)
+		store.branches = make(map[string]map[string]struct{})
+		store.countries = make(map[string]map[string]struct{})
+	}
+	store.codes[row.SwiftCode] = row
+	addToIndex(store.countries, row.CountryISO2, row.SwiftCode)
+	if row.HqSwiftCode != "" {
+		addToIndex(store.branches, row.HqSwiftCode, row.SwiftCode)
+	}
+}
+
+// remove drops row and its index entries; callers hold the write lock.
+func (store *Store) remove(row models.SwiftCode) {
+	delete(store.codes, row.SwiftCode)
+	removeFromIndex(store.countries, row.CountryISO2, row.SwiftCode)
+	removeFromIndex(store.branches, row.HqSwiftCode, row.SwiftCode)
+}
+
+func addToIndex(index map[string]map[string]struct{}, key, code string) {
+	codes, ok := index[key]
+	if !ok {
+		codes = make(map[string]struct{})
+		index[key] = codes
+	}
+	codes[code] = struct{}{}
+}
+
+func removeFromIndex(index map[string]map[string]struct{}, key, code string) {
+	codes, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(codes, code)
+	if len(codes) == 0 {
+		delete(index, key)
+	}
+}
+
+func sortByCode(rows []models.SwiftCode) {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].SwiftCode < rows[j].SwiftCode })
+}
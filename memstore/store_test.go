@@ -0,0 +1,77 @@
+package memstore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	handler "swift-codes-project/handlers"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+	"swift-codes-project/storetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) handler.SwiftDataStore {
+		return &Store{}
+	})
+}
+
+func TestLoadStopsAtDuplicate(t *testing.T) {
+	rows := []models.SwiftCode{
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", IsHeadquarter: true},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ001", HqSwiftCode: "ZZBANKZZXXX"},
+		{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX"},
+	}
+	var store Store
+	loaded, err := store.Load(rows)
+	if !errors.Is(err, service.ErrAlreadyExists) || loaded != 2 || store.Len() != 2 {
+		t.Errorf("Expected 2 rows loaded before the duplicate, got %d (%v), %d stored", loaded, err, store.Len())
+	}
+	branches, _ := store.GetBranchesByHeadOffice([]string{"ZZBANKZZXXX", "ZZUNKNOWXXX"})
+	if len(branches) != 1 || branches[0].SwiftCode != "ZZBANKZZ001" {
+		t.Errorf("Expected the loaded branch, got %+v", branches)
+	}
+	found, _ := store.GetSwiftCodesByCode([]string{"ZZBANKZZ001", "ZZBANKZZ999"})
+	if len(found) != 1 {
+		t.Errorf("Expected one of two codes to be found, got %+v", found)
+	}
+}
+
+// TestStoreIsSafeForConcurrentUse mixes writers and readers; run with -race.
+func TestStoreIsSafeForConcurrentUse(t *testing.T) {
+	var store Store
+	store.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", IsHeadquarter: true})
+
+	var group sync.WaitGroup
+	for writer := 0; writer < 4; writer++ {
+		group.Add(1)
+		go func(writer int) {
+			defer group.Done()
+			for i := 0; i < 100; i++ {
+				code := fmt.Sprintf("ZZBANKZ%d%03d", writer, i)
+				store.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: code, HqSwiftCode: "ZZBANKZZXXX"})
+				if i%2 == 0 {
+					store.DeleteSwiftCode(code)
+				}
+			}
+		}(writer)
+	}
+	for reader := 0; reader < 4; reader++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for i := 0; i < 100; i++ {
+				store.GetSwiftCode("ZZBANKZZXXX")
+				store.SearchSwiftCodes("ZZBANK", "ZZ", 10)
+				store.ExportSwiftCodes("", func(models.SwiftCode) error { return nil })
+			}
+		}()
+	}
+	group.Wait()
+
+	if _, branches, _ := store.GetSwiftCode("ZZBANKZZXXX"); len(branches) != 200 {
+		t.Errorf("Expected 200 surviving branches, got %d", len(branches))
+	}
+}
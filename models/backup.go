@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Backup Model
+type Backup struct {
+	Name        string    `json:"name"`
+	Compression string    `json:"compression"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
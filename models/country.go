@@ -0,0 +1,12 @@
+package models
+
+// Country Model, one ISO 3166-1 entry
+type Country struct {
+	ISO2         string `json:"iso2"`
+	ISO3         string `json:"iso3"`
+	Numeric      string `json:"numeric"`
+	Name         string `json:"name"`
+	OfficialName string `json:"officialName"`
+	// SwiftCodeCount is how many swift codes the country has, when listed.
+	SwiftCodeCount int `json:"swiftCodeCount"`
+}
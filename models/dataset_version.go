@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Dataset Version Model
+type DatasetVersion struct {
+	Version       int64     `json:"version"`
+	Name          string    `json:"name"`
+	Source        string    `json:"source"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	ImportedAt    time.Time `json:"importedAt"`
+	RowCount      int       `json:"rowCount"`
+}
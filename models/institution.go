@@ -0,0 +1,7 @@
+package models
+
+// Institution Model, a bank identified by the first four letters of its codes
+type Institution struct {
+	BankCode string `json:"bankCode"`
+	Name     string `json:"name"`
+}
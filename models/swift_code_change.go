@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Change types recorded in the change log.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// Swift Code Change Model. Code holds the row after the change, or the row as
+// it was before for deletes.
+type SwiftCodeChange struct {
+	Sequence  int64     `json:"sequence"`
+	Type      string    `json:"type"`
+	ChangedAt time.Time `json:"changedAt"`
+	Code      SwiftCode `json:"code"`
+}
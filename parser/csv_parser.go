@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+	"swift-codes-project/parser/sheet"
+)
+
+// ReadCSV maps a comma-separated file onto SwiftCode values without storing them.
+func ReadCSV(filePath string, options ImportOptions) ([]models.SwiftCode, ImportReport, error) {
+	return sheet.ReadCSV(filePath, options.sheetOptions())
+}
+
+// ImportCSV streams a comma-separated file with a header row into the
+// database, using the same column mapping and batching as ImportExcel.
+func ImportCSV(database *db.DB, filePath string, options ImportOptions) (ImportReport, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("unable to open csv file %v", err)
+	}
+	defer file.Close()
+
+	return importRows(database, sheet.NewCSVReader(file), options)
+}
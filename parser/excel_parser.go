@@ -1,72 +1,46 @@
 package parser
 
 import (
-	"database/sql"
-	"fmt"
-	"strings"
+	"log"
+	"swift-codes-project/db"
 	"swift-codes-project/models"
-
-	"github.com/xuri/excelize/v2"
+	"swift-codes-project/parser/sheet"
 )
 
 //Function to open excel and parse each row, convert to SwiftCode objects and store each entry in db
+//Rows naming an unknown or misnamed country are skipped
 
-func ParseExcelAndStore(db *sql.DB, filePath string) error {
-	f, err := excelize.OpenFile(filePath)
+func ParseExcelAndStore(database *db.DB, filePath string) error {
+	report, err := ImportExcel(database, filePath, ImportOptions{CheckCountries: true})
 	if err != nil {
-		return fmt.Errorf("unable to open excel file %v", err)
+		return err
 	}
-	defer f.Close()
-
-	//since data is on the first sheet
-	sheetName := f.GetSheetName(0)
-	rows, err := f.GetRows(sheetName)
+	for _, skipped := range report.SkippedRows {
+		log.Printf("Skipped row %d: %s", skipped.RowNumber, skipped.Reason)
+	}
+	return nil
+}
 
+// ImportExcel streams the configured sheet row by row, locating columns by
+// header name, and stores every complete row. Rows missing required values are
+// reported in the returned ImportReport instead of aborting the import.
+func ImportExcel(database *db.DB, filePath string, options ImportOptions) (ImportReport, error) {
+	nextRow, closeFile, err := sheet.OpenExcel(filePath, options.SheetName)
 	if err != nil {
-		return fmt.Errorf("unable to get rows %v", err)
-	}
-	if len(rows) < 2 {
-		return fmt.Errorf("not enough rows")
+		return ImportReport{}, err
 	}
+	defer closeFile()
+	return importRows(database, nextRow, options)
+}
 
-	for i, row := range rows {
-		if i == 0 {
-			continue
-		}
-
-		if len(row) < 8 {
-			continue
-		}
-
-		// Map the columns to the SwiftCode model fields.
-		codeEntry := models.SwiftCode{
-			CountryISO2: strings.ToUpper(row[0]),
-			SwiftCode:   row[1],
-			CodeType:    row[2],
-			Name:        row[3],
-			Address:     row[4],
-			TownName:    row[5],
-			CountryName: strings.ToUpper(row[6]),
-			TimeZone:    row[7],
-		}
-		isHQ := strings.HasSuffix(codeEntry.SwiftCode, "XXX")
-		hqCode := ""
-		if !isHQ {
-			hqCode = codeEntry.SwiftCode[:8] + "XXX"
-		}
-		codeEntry.IsHeadquarter = isHQ
-		codeEntry.HqSwiftCode = hqCode
-		// Insert the SwiftCode entry into the database.
-		if err := InsertSwiftCode(db, codeEntry); err != nil {
-			return fmt.Errorf("failed to insert data at row %d: %v", i, err)
-		}
-	}
-	return nil
+// ReadExcel maps the configured sheet onto SwiftCode values without storing
+// them, for callers such as the diff engine that only need to inspect a file.
+// Imported in the returned report counts the rows that were read.
+func ReadExcel(filePath string, options ImportOptions) ([]models.SwiftCode, ImportReport, error) {
+	return sheet.ReadExcel(filePath, options.sheetOptions())
 }
 
-// InsertSwiftCode is a placeholder function
-func InsertSwiftCode(db *sql.DB, sc models.SwiftCode) error {
-	const insertSQL = `
+const insertSwiftCodeSQL = `
     INSERT INTO swift_codes (
         country_iso2, swift_code, code_type, name, address,
         town_name, country_name, time_zone,
@@ -76,7 +50,36 @@ func InsertSwiftCode(db *sql.DB, sc models.SwiftCode) error {
         ?, ?, ?, ?, ?
     );`
 
-	_, err := db.Exec(insertSQL,
+// upsertSwiftCodeSQL is used by replacing imports. Rows whose values are
+// unchanged are left alone, so they do not show up in the change log.
+const upsertSwiftCodeSQL = `
+    INSERT INTO swift_codes (
+        country_iso2, swift_code, code_type, name, address,
+        town_name, country_name, time_zone,
+        is_headquarter, hq_swift_code
+    ) VALUES (
+        ?, ?, ?, ?, ?,
+        ?, ?, ?, ?, ?
+    )
+    ON CONFLICT (swift_code) DO UPDATE SET
+        country_iso2 = excluded.country_iso2, code_type = excluded.code_type,
+        name = excluded.name, address = excluded.address,
+        town_name = excluded.town_name, country_name = excluded.country_name,
+        time_zone = excluded.time_zone, is_headquarter = excluded.is_headquarter,
+        hq_swift_code = excluded.hq_swift_code
+    WHERE swift_codes.country_iso2 IS DISTINCT FROM excluded.country_iso2
+       OR swift_codes.code_type IS DISTINCT FROM excluded.code_type
+       OR swift_codes.name IS DISTINCT FROM excluded.name
+       OR swift_codes.address IS DISTINCT FROM excluded.address
+       OR swift_codes.town_name IS DISTINCT FROM excluded.town_name
+       OR swift_codes.country_name IS DISTINCT FROM excluded.country_name
+       OR swift_codes.time_zone IS DISTINCT FROM excluded.time_zone
+       OR swift_codes.is_headquarter IS DISTINCT FROM excluded.is_headquarter
+       OR swift_codes.hq_swift_code IS DISTINCT FROM excluded.hq_swift_code;`
+
+// swiftCodeArgs lists sc's fields in the column order of insertSwiftCodeSQL.
+func swiftCodeArgs(sc models.SwiftCode) []interface{} {
+	return []interface{}{
 		sc.CountryISO2,   // 1
 		sc.SwiftCode,     // 2
 		sc.CodeType,      // 3
@@ -85,8 +88,13 @@ func InsertSwiftCode(db *sql.DB, sc models.SwiftCode) error {
 		sc.TownName,      // 6
 		sc.CountryName,   // 7
 		sc.TimeZone,      // 8
-		sc.IsHeadquarter, // 9  <- new
-		sc.HqSwiftCode,   // 10 <- new
-	)
+		sc.IsHeadquarter, // 9
+		sc.HqSwiftCode,   // 10
+	}
+}
+
+// InsertSwiftCode stores a single row outside of any batch.
+func InsertSwiftCode(database *db.DB, sc models.SwiftCode) error {
+	_, err := database.Exec(insertSwiftCodeSQL, swiftCodeArgs(sc)...)
 	return err
 }
@@ -0,0 +1,378 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/parser/sheet"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTestWorkbook saves rows to a sheet named sheetName in a temporary XLSX file.
+func writeTestWorkbook(t *testing.T, sheetName string, rows [][]string) string {
+	t.Helper()
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	workbook.SetSheetName("Sheet1", sheetName)
+	for i, row := range rows {
+		cellName, _ := excelize.CoordinatesToCellName(1, i+1)
+		values := make([]interface{}, len(row))
+		for j, value := range row {
+			values[j] = value
+		}
+		if err := workbook.SetSheetRow(sheetName, cellName, &values); err != nil {
+			t.Fatalf("Failed to write row %d: %v", i+1, err)
+		}
+	}
+	filePath := filepath.Join(t.TempDir(), "codes.xlsx")
+	if err := workbook.SaveAs(filePath); err != nil {
+		t.Fatalf("Failed to save workbook: %v", err)
+	}
+	return filePath
+}
+
+// TestImportExcelMapsColumnsByHeader uses a reordered sheet with a "BIC" alias
+// and an extra column, and expects fields to land in the right place.
+func TestImportExcelMapsColumnsByHeader(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	filePath := writeTestWorkbook(t, "Directory", [][]string{
+		{"Name", "BIC", "Extra", "Country ISO2 Code", "Country Name", "Town Name"},
+		{"ZELAND NATIONAL BANK", "zzbankzz001", "ignored", "zz", "Zeland", "CAPITAL"},
+		{"SHORT ROW BANK", "ZZSHORTXXX"},
+		{"ZELAND NATIONAL BANK", "ZZBANKZZXXX", "", "ZZ", "ZELAND", "CAPITAL"},
+	})
+
+	report, importError := ImportExcel(testDatabase, filePath, ImportOptions{SheetName: "Directory"})
+	if importError != nil {
+		t.Fatalf("Expected no error importing workbook, got: %v", importError)
+	}
+	if report.Imported != 2 {
+		t.Errorf("Expected 2 imported rows, got %d", report.Imported)
+	}
+	if len(report.SkippedRows) != 1 || report.SkippedRows[0].RowNumber != 3 {
+		t.Fatalf("Expected row 3 to be reported as skipped, got %+v", report.SkippedRows)
+	}
+
+	var countryISO2, countryName, hqSwiftCode string
+	queryError := testDatabase.QueryRow(
+		`SELECT country_iso2, country_name, hq_swift_code FROM swift_codes WHERE swift_code = ?`,
+		"ZZBANKZZ001",
+	).Scan(&countryISO2, &countryName, &hqSwiftCode)
+	if queryError != nil {
+		t.Fatalf("Expected imported branch row, got: %v", queryError)
+	}
+	if countryISO2 != "ZZ" || countryName != "ZELAND" || hqSwiftCode != "ZZBANKZZXXX" {
+		t.Errorf("Unexpected mapped values: %q %q %q", countryISO2, countryName, hqSwiftCode)
+	}
+}
+
+// TestImportExcelRejectsMissingRequiredColumns expects the header check to fail
+// before any rows are inserted.
+func TestImportExcelRejectsMissingRequiredColumns(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	filePath := writeTestWorkbook(t, "Sheet1", [][]string{
+		{"NAME", "COUNTRY ISO2 CODE", "COUNTRY NAME"},
+		{"ZELAND NATIONAL BANK", "ZZ", "ZELAND"},
+	})
+
+	_, importError := ImportExcel(testDatabase, filePath, ImportOptions{})
+	if importError == nil || !strings.Contains(importError.Error(), sheet.ColumnSwiftCode) {
+		t.Fatalf("Expected missing swiftCode column error, got: %v", importError)
+	}
+}
+
+// TestImportCSVCommitsInBatches streams a CSV file with a small batch size and
+// checks that progress is reported once per committed batch.
+func TestImportCSVCommitsInBatches(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	csvContent := "SWIFT CODE,COUNTRY ISO2 CODE,NAME,COUNTRY NAME\n" +
+		"CSVBANKAXXX,AA,BANK A,AALAND\n" +
+		"CSVBANKA001,AA,BANK A,AALAND\n" +
+		"CSVBANKA002,AA\n" +
+		"CSVBANKBXXX,BB,BANK B,BBLAND\n"
+	filePath := filepath.Join(t.TempDir(), "codes.csv")
+	if writeError := os.WriteFile(filePath, []byte(csvContent), 0o644); writeError != nil {
+		t.Fatalf("Failed to write csv file: %v", writeError)
+	}
+
+	var progressUpdates []ImportProgress
+	report, importError := ImportCSV(testDatabase, filePath, ImportOptions{
+		BatchSize: 2,
+		Progress:  func(progress ImportProgress) { progressUpdates = append(progressUpdates, progress) },
+	})
+	if importError != nil {
+		t.Fatalf("Expected no error importing csv, got: %v", importError)
+	}
+	if report.Imported != 3 || len(report.SkippedRows) != 1 {
+		t.Errorf("Expected 3 imported and 1 skipped, got %d and %d", report.Imported, len(report.SkippedRows))
+	}
+	if len(progressUpdates) != 2 {
+		t.Fatalf("Expected 2 progress updates, got %d", len(progressUpdates))
+	}
+	finalProgress := progressUpdates[len(progressUpdates)-1]
+	if finalProgress.RowsRead != 4 || finalProgress.Imported != 3 || finalProgress.Skipped != 1 {
+		t.Errorf("Unexpected final progress: %+v", finalProgress)
+	}
+}
+
+// writeLargeWorkbook generates a sheet of rowCount branch rows using the
+// excelize stream writer, so the fixture itself is cheap to build.
+func writeLargeWorkbook(b *testing.B, rowCount int) string {
+	b.Helper()
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	streamWriter, err := workbook.NewStreamWriter("Sheet1")
+	if err != nil {
+		b.Fatalf("Failed to create stream writer: %v", err)
+	}
+	header := []interface{}{"COUNTRY ISO2 CODE", "SWIFT CODE", "CODE TYPE", "NAME", "ADDRESS", "TOWN NAME", "COUNTRY NAME", "TIME ZONE"}
+	if err := streamWriter.SetRow("A1", header); err != nil {
+		b.Fatalf("Failed to write header: %v", err)
+	}
+	for i := 0; i < rowCount; i++ {
+		cellName, _ := excelize.CoordinatesToCellName(1, i+2)
+		row := []interface{}{
+			"ZZ", fmt.Sprintf("ZZ%06dXXX", i), "BIC11", "BENCH BANK", "1 BENCH ROAD",
+			"CAPITAL", "ZELAND", "Europe/London",
+		}
+		if err := streamWriter.SetRow(cellName, row); err != nil {
+			b.Fatalf("Failed to write row %d: %v", i+2, err)
+		}
+	}
+	if err := streamWriter.Flush(); err != nil {
+		b.Fatalf("Failed to flush stream writer: %v", err)
+	}
+	filePath := filepath.Join(b.TempDir(), "large.xlsx")
+	if err := workbook.SaveAs(filePath); err != nil {
+		b.Fatalf("Failed to save workbook: %v", err)
+	}
+	return filePath
+}
+
+// BenchmarkImportExcelLargeFile imports a generated 20k row sheet into a fresh
+// database on every iteration.
+func BenchmarkImportExcelLargeFile(b *testing.B) {
+	const rowCount = 20000
+	filePath := writeLargeWorkbook(b, rowCount)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		benchDatabase, initError := db.InitDB(fmt.Sprintf("file:bench%d?mode=memory&cache=shared&_fk=1", i))
+		if initError != nil {
+			b.Fatalf("Failed to initialize in-memory database: %v", initError)
+		}
+		b.StartTimer()
+
+		report, importError := ImportExcel(benchDatabase, filePath, ImportOptions{BatchSize: 1000})
+		if importError != nil {
+			b.Fatalf("Expected no error importing workbook, got: %v", importError)
+		}
+		if report.Imported != rowCount {
+			b.Fatalf("Expected %d imported rows, got %d", rowCount, report.Imported)
+		}
+
+		b.StopTimer()
+		benchDatabase.Close()
+		b.StartTimer()
+	}
+}
+
+// TestImportCSVReplaceOnlyLogsRealChanges re-imports a file with one modified,
+// one removed and one unchanged code and checks the change log only records
+// the modification and the removal.
+func TestImportCSVReplaceOnlyLogsRealChanges(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	writeCSV := func(content string) string {
+		filePath := filepath.Join(t.TempDir(), "codes.csv")
+		if writeError := os.WriteFile(filePath, []byte(content), 0o644); writeError != nil {
+			t.Fatalf("Failed to write csv file: %v", writeError)
+		}
+		return filePath
+	}
+	const header = "SWIFT CODE,COUNTRY ISO2 CODE,NAME,COUNTRY NAME,ADDRESS\n"
+
+	firstFile := writeCSV(header +
+		"RPLBANKAXXX,AA,BANK A,AALAND,1 OLD ROAD\n" +
+		"RPLBANKA001,AA,BANK A,AALAND,2 OLD ROAD\n" +
+		"RPLBANKBXXX,BB,BANK B,BBLAND,3 OLD ROAD\n")
+	if _, importError := ImportCSV(testDatabase, firstFile, ImportOptions{Replace: true}); importError != nil {
+		t.Fatalf("Unexpected error on first import: %v", importError)
+	}
+	var firstImportEnd int64
+	testDatabase.QueryRow(`SELECT MAX(sequence) FROM swift_code_changes;`).Scan(&firstImportEnd)
+
+	secondFile := writeCSV(header +
+		"RPLBANKAXXX,AA,BANK A,AALAND,9 NEW ROAD\n" +
+		"RPLBANKBXXX,BB,BANK B,BBLAND,3 OLD ROAD\n")
+	if _, importError := ImportCSV(testDatabase, secondFile, ImportOptions{Replace: true}); importError != nil {
+		t.Fatalf("Unexpected error on second import: %v", importError)
+	}
+
+	rows, queryError := testDatabase.Query(
+		`SELECT change_type, swift_code FROM swift_code_changes WHERE sequence > ? ORDER BY sequence;`,
+		firstImportEnd,
+	)
+	if queryError != nil {
+		t.Fatalf("Failed to read change log: %v", queryError)
+	}
+	defer rows.Close()
+	var loggedChanges []string
+	for rows.Next() {
+		var changeType, swiftCode string
+		rows.Scan(&changeType, &swiftCode)
+		loggedChanges = append(loggedChanges, changeType+" "+swiftCode)
+	}
+	expectedChanges := []string{"updated RPLBANKAXXX", "deleted RPLBANKA001"}
+	if strings.Join(loggedChanges, ",") != strings.Join(expectedChanges, ",") {
+		t.Errorf("Expected changes %v, got %v", expectedChanges, loggedChanges)
+	}
+}
+
+// TestImportReplaceIsAllOrNothing imports a file over an existing table with
+// a Finish step that fails, and expects the table to be left exactly as it was
+// even though the file spans several batches.
+func TestImportReplaceIsAllOrNothing(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	const header = "SWIFT CODE,COUNTRY ISO2 CODE,NAME,COUNTRY NAME,ADDRESS\n"
+	firstFile := filepath.Join(t.TempDir(), "first.csv")
+	os.WriteFile(firstFile, []byte(header+"OLDBANKAXXX,AA,BANK A,AALAND,1 OLD ROAD\n"), 0o644)
+	if _, importError := ImportCSV(testDatabase, firstFile, ImportOptions{Replace: true}); importError != nil {
+		t.Fatalf("Unexpected error on first import: %v", importError)
+	}
+
+	var content strings.Builder
+	content.WriteString(header)
+	for i := 0; i < 25; i++ {
+		fmt.Fprintf(&content, "NEWBANK%02dXXX,BB,BANK B,BBLAND,%d NEW ROAD\n", i, i)
+	}
+	secondFile := filepath.Join(t.TempDir(), "second.csv")
+	os.WriteFile(secondFile, []byte(content.String()), 0o644)
+	_, importError := ImportCSV(testDatabase, secondFile, ImportOptions{
+		Replace:   true,
+		BatchSize: 10,
+		Finish:    func(tx *db.Tx) error { return fmt.Errorf("version rejected") },
+	})
+	if importError == nil {
+		t.Fatalf("Expected the failing Finish step to fail the import")
+	}
+
+	var storedCodes []string
+	rows, _ := testDatabase.Query(`SELECT swift_code FROM swift_codes;`)
+	for rows.Next() {
+		var code string
+		rows.Scan(&code)
+		storedCodes = append(storedCodes, code)
+	}
+	rows.Close()
+	if len(storedCodes) != 1 || storedCodes[0] != "OLDBANKAXXX" {
+		t.Errorf("Expected the table to still hold only the first file, got %v", storedCodes)
+	}
+}
+
+// TestImportCheckCountriesSkipsUnknownCountries imports one row per kind of
+// country and expects only the valid one to be stored, both into the table
+// and when reading the file alone.
+func TestImportCheckCountriesSkipsUnknownCountries(t *testing.T) {
+	testDatabase, initError := db.InitDB("file:" + filepath.Join(t.TempDir(), "countries.db") + "?_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	filePath := filepath.Join(t.TempDir(), "codes.csv")
+	content := "SWIFT CODE,COUNTRY ISO2 CODE,NAME,COUNTRY NAME\n" +
+		"PLBANKPLXXX,PL,BANK A,POLAND\n" +
+		"ZZBANKZZXXX,ZZ,BANK B,ZELAND\n" +
+		"DEBANKDEXXX,DE,BANK C,AUSTRIA\n"
+	if writeError := os.WriteFile(filePath, []byte(content), 0o644); writeError != nil {
+		t.Fatalf("Failed to write csv file: %v", writeError)
+	}
+
+	report, importError := ImportCSV(testDatabase, filePath, ImportOptions{CheckCountries: true})
+	if importError != nil {
+		t.Fatalf("Unexpected import error: %v", importError)
+	}
+	if report.Imported != 1 || len(report.SkippedRows) != 2 {
+		t.Fatalf("Expected 1 imported and 2 skipped rows, got %+v", report)
+	}
+	if report.SkippedRows[0].RowNumber != 3 || !strings.Contains(report.SkippedRows[0].Reason, "unknown country") ||
+		report.SkippedRows[1].RowNumber != 4 || !strings.Contains(report.SkippedRows[1].Reason, "does not match DE") {
+		t.Errorf("Unexpected skipped rows %+v", report.SkippedRows)
+	}
+
+	codes, readReport, readError := ReadCSV(filePath, ImportOptions{CheckCountries: true})
+	if readError != nil || len(codes) != 1 || codes[0].CountryISO2 != "PL" || len(readReport.SkippedRows) != 2 {
+		t.Errorf("Expected reading to keep only PL, got %+v, %+v, %v", codes, readReport, readError)
+	}
+}
+
+// TestImportPopulatesInstitutions groups imported offices by bank code.
+func TestImportPopulatesInstitutions(t *testing.T) {
+	testDatabase, initError := db.InitDB("file:" + filepath.Join(t.TempDir(), "institutions.db") + "?_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize database: %v", initError)
+	}
+	defer testDatabase.Close()
+
+	filePath := filepath.Join(t.TempDir(), "codes.csv")
+	content := "SWIFT CODE,COUNTRY ISO2 CODE,NAME,COUNTRY NAME\n" +
+		"ABCDPLPWKRK,PL,ABC BANK KRAKOW,POLAND\n" +
+		"ABCDPLPWXXX,PL,ABC BANK,POLAND\n" +
+		"ABCDDEFFXXX,DE,ABC BANK,GERMANY\n" +
+		"WXYZPLPWXXX,PL,OTHER BANK,POLAND\n"
+	if writeError := os.WriteFile(filePath, []byte(content), 0o644); writeError != nil {
+		t.Fatalf("Failed to write csv file: %v", writeError)
+	}
+	if _, importError := ImportCSV(testDatabase, filePath, ImportOptions{}); importError != nil {
+		t.Fatalf("Unexpected import error: %v", importError)
+	}
+
+	institutions := make(map[string]string)
+	rows, queryError := testDatabase.Query(`SELECT bank_code, name FROM institutions;`)
+	if queryError != nil {
+		t.Fatalf("Failed to query institutions: %v", queryError)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bankCode, name string
+		rows.Scan(&bankCode, &name)
+		institutions[bankCode] = name
+	}
+	if len(institutions) != 2 || institutions["ABCD"] != "ABC BANK" || institutions["WXYZ"] != "OTHER BANK" {
+		t.Errorf("Expected ABCD and WXYZ named after their head offices, got %v", institutions)
+	}
+}
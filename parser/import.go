@@ -0,0 +1,280 @@
+package parser
+
+import (
+	"database/sql"
+	"fmt"
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+	"swift-codes-project/parser/sheet"
+)
+
+// DefaultBatchSize is the number of rows committed per transaction when
+// ImportOptions.BatchSize is not set.
+const DefaultBatchSize = 500
+
+// ImportOptions controls how a spreadsheet is read.
+type ImportOptions struct {
+	// SheetName selects the sheet to import; the first sheet is used when empty.
+	// It is ignored for CSV files.
+	SheetName string
+	// ColumnAliases maps column identifiers to accepted header names.
+	// sheet.DefaultColumnAliases is used when nil.
+	ColumnAliases map[string][]string
+	// BatchSize is the number of rows inserted per transaction. Imports that
+	// run in a single transaction only use it to pace Progress.
+	BatchSize int
+	// Progress, when set, is called after every batch.
+	Progress func(ImportProgress)
+	// Replace makes the table hold exactly the imported file: existing rows are
+	// updated in place when they changed, and rows missing from the file are
+	// deleted. The import runs in a single transaction, so a failure leaves the
+	// table as it was and readers never see it half loaded.
+	//
+	// Clearing the table and inserting every row again would give the same
+	// result, but the change log records every write, so each import would
+	// then report the whole directory as deleted and created again. Feed
+	// readers, webhook subscribers and the cache would all see changes that
+	// did not happen.
+	Replace bool
+	// Finish, when set, runs inside the import's transaction after every row
+	// has been written, so that work such as recording a dataset version
+	// commits or rolls back together with the rows. Setting it makes the
+	// import a single transaction.
+	Finish func(tx *db.Tx) error
+	// CheckCountries skips rows whose country ISO2 code is not in the
+	// countries table, or whose country name is not that country's. Reads
+	// that store nothing check against the embedded ISO 3166-1 list instead.
+	CheckCountries bool
+}
+
+// ImportProgress is passed to ImportOptions.Progress as an import advances.
+type ImportProgress struct {
+	RowsRead int
+	Imported int
+	Skipped  int
+}
+
+// SkippedRow describes a data row that could not be imported.
+type SkippedRow = sheet.SkippedRow
+
+// ImportReport summarizes the outcome of an import.
+type ImportReport = sheet.Report
+
+// sheetOptions are the options that apply to reading the file itself.
+func (options ImportOptions) sheetOptions() sheet.Options {
+	return sheet.Options{
+		SheetName:      options.SheetName,
+		ColumnAliases:  options.ColumnAliases,
+		CheckCountries: options.CheckCountries,
+	}
+}
+
+// importRows inserts the rows of a sheet in transactions of options.BatchSize
+// rows so memory use stays bounded regardless of the size of the source.
+// Replace and Finish imports keep every batch in one transaction instead.
+func importRows(database *db.DB, nextRow sheet.RowReader, options ImportOptions) (ImportReport, error) {
+	var report ImportReport
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	batch := newInsertBatch(database)
+	singleTransaction := options.Replace || options.Finish != nil
+	var seenCodes map[string]bool
+	if options.Replace {
+		batch.query = upsertSwiftCodeSQL
+		seenCodes = make(map[string]bool)
+	}
+	defer batch.rollback()
+	var knownCountries map[string]models.Country
+	if options.CheckCountries {
+		loaded, err := loadCountries(database)
+		if err != nil {
+			return report, fmt.Errorf("failed to load countries: %v", err)
+		}
+		knownCountries = loaded
+	}
+
+	endBatch := func(rowNumber int) error {
+		written := batch.size
+		if !singleTransaction {
+			if err := batch.commit(); err != nil {
+				return fmt.Errorf("failed to commit rows up to %d: %v", rowNumber, err)
+			}
+		}
+		batch.size = 0
+		report.Imported += written
+		if options.Progress != nil {
+			options.Progress(ImportProgress{
+				RowsRead: rowNumber - 1,
+				Imported: report.Imported,
+				Skipped:  len(report.SkippedRows),
+			})
+		}
+		return nil
+	}
+
+	lastRow, err := sheet.Walk(nextRow, options.ColumnAliases, &report, func(rowNumber int, codeEntry models.SwiftCode) error {
+		if knownCountries != nil {
+			if reason := sheet.CountryMismatch(knownCountries, codeEntry); reason != "" {
+				report.SkippedRows = append(report.SkippedRows, SkippedRow{RowNumber: rowNumber, Reason: reason})
+				return nil
+			}
+		}
+		if seenCodes != nil {
+			seenCodes[codeEntry.SwiftCode] = true
+		}
+		// Insert the SwiftCode entry into the database.
+		if err := batch.insert(codeEntry); err != nil {
+			return fmt.Errorf("failed to insert data at row %d: %v", rowNumber, err)
+		}
+		if batch.size >= batchSize {
+			return endBatch(rowNumber)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	if err := endBatch(lastRow); err != nil {
+		return report, err
+	}
+	if !singleTransaction {
+		return report, nil
+	}
+
+	if err := batch.open(); err != nil {
+		return report, err
+	}
+	if seenCodes != nil {
+		if err := deleteUnseenCodes(batch.tx, seenCodes); err != nil {
+			return report, fmt.Errorf("failed to remove codes missing from the file: %v", err)
+		}
+	}
+	if options.Finish != nil {
+		if err := options.Finish(batch.tx); err != nil {
+			return report, err
+		}
+	}
+	if err := batch.commit(); err != nil {
+		return report, fmt.Errorf("failed to commit the import: %v", err)
+	}
+	return report, nil
+}
+
+// loadCountries reads the countries table, keyed by ISO2 code.
+func loadCountries(database *db.DB) (map[string]models.Country, error) {
+	rows, err := database.Query(`SELECT iso2, iso3, numeric_code, name, official_name FROM countries;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	known := make(map[string]models.Country)
+	for rows.Next() {
+		var country models.Country
+		if err := rows.Scan(&country.ISO2, &country.ISO3, &country.Numeric, &country.Name, &country.OfficialName); err != nil {
+			return nil, err
+		}
+		known[country.ISO2] = country
+	}
+	return known, rows.Err()
+}
+
+// deleteUnseenCodes removes every stored code that is not in seenCodes.
+func deleteUnseenCodes(tx *db.Tx, seenCodes map[string]bool) error {
+	rows, err := tx.Query(`SELECT swift_code FROM swift_codes;`)
+	if err != nil {
+		return err
+	}
+	var unseenCodes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			rows.Close()
+			return err
+		}
+		if !seenCodes[code] {
+			unseenCodes = append(unseenCodes, code)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(unseenCodes) == 0 {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`DELETE FROM swift_codes WHERE swift_code = ?;`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, code := range unseenCodes {
+		if _, err := stmt.Exec(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertBatch groups inserts into a single transaction that is opened lazily
+// on the first insert, or by open, and closed by commit.
+type insertBatch struct {
+	database *db.DB
+	query    string // statement run for every row
+	tx       *db.Tx
+	stmt     *sql.Stmt
+	size     int
+}
+
+func newInsertBatch(database *db.DB) *insertBatch {
+	return &insertBatch{database: database, query: insertSwiftCodeSQL}
+}
+
+// open begins the batch's transaction unless it is already open.
+func (batch *insertBatch) open() error {
+	if batch.tx != nil {
+		return nil
+	}
+	tx, err := batch.database.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(batch.query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	batch.tx, batch.stmt = tx, stmt
+	return nil
+}
+
+func (batch *insertBatch) insert(sc models.SwiftCode) error {
+	if err := batch.open(); err != nil {
+		return err
+	}
+	if _, err := batch.stmt.Exec(swiftCodeArgs(sc)...); err != nil {
+		return err
+	}
+	batch.size++
+	return nil
+}
+
+func (batch *insertBatch) commit() error {
+	if batch.tx == nil {
+		return nil
+	}
+	batch.stmt.Close()
+	err := batch.tx.Commit()
+	batch.tx, batch.stmt, batch.size = nil, nil, 0
+	return err
+}
+
+// rollback discards any uncommitted rows; it is a no-op after commit.
+func (batch *insertBatch) rollback() {
+	if batch.tx == nil {
+		return
+	}
+	batch.stmt.Close()
+	batch.tx.Rollback()
+	batch.tx, batch.stmt, batch.size = nil, nil, 0
+}
@@ -0,0 +1,113 @@
+package sheet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column identifiers used as keys in Options.ColumnAliases.
+const (
+	ColumnCountryISO2 = "countryISO2"
+	ColumnSwiftCode   = "swiftCode"
+	ColumnCodeType    = "codeType"
+	ColumnName        = "name"
+	ColumnAddress     = "address"
+	ColumnTownName    = "townName"
+	ColumnCountryName = "countryName"
+	ColumnTimeZone    = "timeZone"
+)
+
+// requiredColumns must be present in the header row, and every data row must
+// have a value for them, otherwise the row is reported as skipped.
+var requiredColumns = []string{
+	ColumnCountryISO2,
+	ColumnSwiftCode,
+	ColumnName,
+	ColumnCountryName,
+}
+
+// allColumns lists every column the importer knows how to map.
+var allColumns = []string{
+	ColumnCountryISO2,
+	ColumnSwiftCode,
+	ColumnCodeType,
+	ColumnName,
+	ColumnAddress,
+	ColumnTownName,
+	ColumnCountryName,
+	ColumnTimeZone,
+}
+
+// DefaultColumnAliases maps each column to the header names it may appear under.
+// Header matching ignores case and repeated whitespace.
+var DefaultColumnAliases = map[string][]string{
+	ColumnCountryISO2: {"COUNTRY ISO2 CODE", "COUNTRY ISO2", "ISO2", "COUNTRY CODE"},
+	ColumnSwiftCode:   {"SWIFT CODE", "SWIFTCODE", "BIC", "BIC CODE", "SWIFT/BIC"},
+	ColumnCodeType:    {"CODE TYPE", "TYPE"},
+	ColumnName:        {"NAME", "BANK NAME", "INSTITUTION NAME"},
+	ColumnAddress:     {"ADDRESS"},
+	ColumnTownName:    {"TOWN NAME", "TOWN", "CITY"},
+	ColumnCountryName: {"COUNTRY NAME", "COUNTRY"},
+	ColumnTimeZone:    {"TIME ZONE", "TIMEZONE"},
+}
+
+// columnIndex holds the position of each mapped column within a row.
+type columnIndex map[string]int
+
+// normalizeHeader upper-cases a header cell and collapses its whitespace.
+func normalizeHeader(header string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(header)), " ")
+}
+
+// mapColumns finds every known column in the header row using the given aliases
+// and returns an error naming any required column that could not be found.
+func mapColumns(headerRow []string, aliases map[string][]string) (columnIndex, error) {
+	positions := make(map[string]int, len(headerRow))
+	for i, header := range headerRow {
+		key := normalizeHeader(header)
+		if _, seen := positions[key]; !seen && key != "" {
+			positions[key] = i
+		}
+	}
+
+	index := columnIndex{}
+	for _, column := range allColumns {
+		for _, alias := range aliases[column] {
+			if pos, ok := positions[normalizeHeader(alias)]; ok {
+				index[column] = pos
+				break
+			}
+		}
+	}
+
+	var missing []string
+	for _, column := range requiredColumns {
+		if _, ok := index[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+	return index, nil
+}
+
+// value returns the trimmed cell for column, or "" when the column is unmapped
+// or the row is too short to contain it.
+func (index columnIndex) value(row []string, column string) string {
+	pos, ok := index[column]
+	if !ok || pos >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[pos])
+}
+
+// missingRequired returns the first required column with no value in row.
+func (index columnIndex) missingRequired(row []string) (string, bool) {
+	for _, column := range requiredColumns {
+		if index.value(row, column) == "" {
+			return column, true
+		}
+	}
+	return "", false
+}
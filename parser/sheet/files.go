@@ -0,0 +1,78 @@
+package sheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	"swift-codes-project/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReadExcel maps the configured sheet of an XLSX file onto SwiftCode values.
+func ReadExcel(filePath string, options Options) ([]models.SwiftCode, Report, error) {
+	nextRow, closeFile, err := OpenExcel(filePath, options.SheetName)
+	if err != nil {
+		return nil, Report{}, err
+	}
+	defer closeFile()
+	return Read(nextRow, options)
+}
+
+// ReadCSV maps a comma-separated file onto SwiftCode values.
+func ReadCSV(filePath string, options Options) ([]models.SwiftCode, Report, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, Report{}, fmt.Errorf("unable to open csv file %v", err)
+	}
+	defer file.Close()
+	return Read(NewCSVReader(file), options)
+}
+
+// OpenExcel opens sheetName, or the first sheet when empty, for streaming.
+// The returned close function releases both the row iterator and the file.
+func OpenExcel(filePath, sheetName string) (RowReader, func(), error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open excel file %v", err)
+	}
+
+	//data is on the first sheet unless told otherwise
+	if sheetName == "" {
+		sheetName = f.GetSheetName(0)
+	} else if index, err := f.GetSheetIndex(sheetName); err != nil || index < 0 {
+		f.Close()
+		return nil, nil, fmt.Errorf("sheet %q not found", sheetName)
+	}
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("unable to get rows %v", err)
+	}
+
+	nextRow := func() ([]string, error) {
+		if !rows.Next() {
+			if err := rows.Error(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		return rows.Columns()
+	}
+	closeFile := func() {
+		rows.Close()
+		f.Close()
+	}
+	return nextRow, closeFile, nil
+}
+
+// NewCSVReader reads the rows of a comma-separated source.
+func NewCSVReader(source io.Reader) RowReader {
+	reader := csv.NewReader(source)
+	// vendor files do not always pad short rows, so allow a varying field count
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return reader.Read
+}
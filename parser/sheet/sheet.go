@@ -0,0 +1,181 @@
+// Package sheet maps the rows of vendor files, XLSX or CSV, onto SwiftCode
+// values. It stores nothing and depends on no database, so the lookup package
+// can read vendor files without pulling in the SQL drivers; the parser
+// package imports what it reads here.
+package sheet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"swift-codes-project/countries"
+	"swift-codes-project/models"
+	"swift-codes-project/timezones"
+)
+
+// Options controls how a file is read.
+type Options struct {
+	// SheetName selects the sheet to read; the first sheet is used when empty.
+	// It is ignored for CSV files.
+	SheetName string
+	// ColumnAliases maps column identifiers to accepted header names.
+	// DefaultColumnAliases is used when nil.
+	ColumnAliases map[string][]string
+	// CheckCountries skips rows whose country ISO2 code is not in the
+	// embedded ISO 3166-1 list, or whose country name is not that country's.
+	CheckCountries bool
+}
+
+// SkippedRow describes a data row that could not be mapped.
+type SkippedRow struct {
+	RowNumber int // 1-based, as shown in the spreadsheet
+	Reason    string
+}
+
+// Report summarizes the outcome of reading, or importing, a file.
+type Report struct {
+	Imported    int
+	SkippedRows []SkippedRow
+}
+
+// RowReader returns the next row of a sheet, or io.EOF once it is exhausted.
+type RowReader func() ([]string, error)
+
+// Walk maps the first row as the header and passes every following row
+// that can be mapped to visit. Unmappable rows are appended to
+// report.SkippedRows. It returns the number of the last row read.
+func Walk(
+	nextRow RowReader,
+	aliases map[string][]string,
+	report *Report,
+	visit func(rowNumber int, codeEntry models.SwiftCode) error,
+) (int, error) {
+	headerRow, err := nextRow()
+	if errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("not enough rows")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to get rows %v", err)
+	}
+
+	if aliases == nil {
+		aliases = DefaultColumnAliases
+	}
+	columns, err := mapColumns(headerRow, aliases)
+	if err != nil {
+		return 1, fmt.Errorf("invalid header row: %v", err)
+	}
+
+	rowNumber := 1
+	for {
+		row, err := nextRow()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rowNumber, fmt.Errorf("unable to read row %d: %v", rowNumber+1, err)
+		}
+		rowNumber++
+
+		codeEntry, skipReason := rowToSwiftCode(columns, row)
+		if skipReason != "" {
+			report.SkippedRows = append(report.SkippedRows, SkippedRow{RowNumber: rowNumber, Reason: skipReason})
+			continue
+		}
+		if err := visit(rowNumber, codeEntry); err != nil {
+			return rowNumber, err
+		}
+	}
+	if rowNumber < 2 {
+		return rowNumber, fmt.Errorf("not enough rows")
+	}
+	return rowNumber, nil
+}
+
+// Read collects every mappable row of a sheet in memory. Imported in the
+// returned report counts the rows that were read.
+func Read(nextRow RowReader, options Options) ([]models.SwiftCode, Report, error) {
+	var report Report
+	var codes []models.SwiftCode
+	var knownCountries map[string]models.Country
+	if options.CheckCountries {
+		knownCountries = make(map[string]models.Country)
+		for _, country := range countries.All() {
+			knownCountries[country.ISO2] = country
+		}
+	}
+	_, err := Walk(nextRow, options.ColumnAliases, &report, func(rowNumber int, codeEntry models.SwiftCode) error {
+		if knownCountries != nil {
+			if reason := CountryMismatch(knownCountries, codeEntry); reason != "" {
+				report.SkippedRows = append(report.SkippedRows, SkippedRow{RowNumber: rowNumber, Reason: reason})
+				return nil
+			}
+		}
+		codes = append(codes, codeEntry)
+		report.Imported++
+		return nil
+	})
+	return codes, report, err
+}
+
+// CountryMismatch returns why codeEntry's country is not one of known, or "".
+func CountryMismatch(known map[string]models.Country, codeEntry models.SwiftCode) string {
+	country, ok := known[codeEntry.CountryISO2]
+	if !ok {
+		return fmt.Sprintf("unknown country ISO2 code %q", codeEntry.CountryISO2)
+	}
+	if countries.CheckName(country, codeEntry.CountryName) != nil {
+		return fmt.Sprintf("country name %q does not match %s (%s)", codeEntry.CountryName, country.ISO2, country.Name)
+	}
+	return ""
+}
+
+// rowToSwiftCode maps a data row onto the SwiftCode model. A non-empty reason
+// is returned when the row cannot be imported.
+func rowToSwiftCode(columns columnIndex, row []string) (models.SwiftCode, string) {
+	if isBlankRow(row) {
+		return models.SwiftCode{}, "empty row"
+	}
+	if column, missing := columns.missingRequired(row); missing {
+		return models.SwiftCode{}, fmt.Sprintf("missing value for %s", column)
+	}
+
+	// Map the columns to the SwiftCode model fields.
+	codeEntry := models.SwiftCode{
+		CountryISO2: strings.ToUpper(columns.value(row, ColumnCountryISO2)),
+		SwiftCode:   strings.ToUpper(columns.value(row, ColumnSwiftCode)),
+		CodeType:    columns.value(row, ColumnCodeType),
+		Name:        columns.value(row, ColumnName),
+		Address:     columns.value(row, ColumnAddress),
+		TownName:    columns.value(row, ColumnTownName),
+		CountryName: strings.ToUpper(columns.value(row, ColumnCountryName)),
+		TimeZone:    columns.value(row, ColumnTimeZone),
+	}
+	if len(codeEntry.SwiftCode) < 8 {
+		return models.SwiftCode{}, fmt.Sprintf("swift code %q is too short", codeEntry.SwiftCode)
+	}
+	timeZone, err := timezones.Normalize(codeEntry.TimeZone)
+	if err != nil {
+		return models.SwiftCode{}, err.Error()
+	}
+	codeEntry.TimeZone = timeZone
+	isHQ := strings.HasSuffix(codeEntry.SwiftCode, "XXX")
+	hqCode := ""
+	if !isHQ {
+		hqCode = codeEntry.SwiftCode[:8] + "XXX"
+	}
+	codeEntry.IsHeadquarter = isHQ
+	codeEntry.HqSwiftCode = hqCode
+	return codeEntry, ""
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
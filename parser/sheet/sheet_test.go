@@ -0,0 +1,31 @@
+package sheet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadNormalizesTimeZones maps zones to IANA names and skips unknown ones.
+func TestReadNormalizesTimeZones(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "codes.csv")
+	content := "SWIFT CODE,COUNTRY ISO2 CODE,NAME,COUNTRY NAME,TIME ZONE\n" +
+		"PLBANKPLXXX,PL,BANK A,POLAND,europe/warsaw\n" +
+		"MTBANKMTXXX,MT,BANK B,MALTA,UTC+01:00\n" +
+		"ZZBANKZZXXX,ZZ,BANK C,ZELAND,Europe/Zeland\n"
+	if writeError := os.WriteFile(filePath, []byte(content), 0o644); writeError != nil {
+		t.Fatalf("Failed to write csv file: %v", writeError)
+	}
+	codes, report, readError := ReadCSV(filePath, Options{})
+	if readError != nil {
+		t.Fatalf("Unexpected read error: %v", readError)
+	}
+	if len(codes) != 2 || codes[0].TimeZone != "Europe/Warsaw" || codes[1].TimeZone != "Etc/GMT-1" {
+		t.Errorf("Expected Europe/Warsaw and Etc/GMT-1, got %+v", codes)
+	}
+	if len(report.SkippedRows) != 1 || report.SkippedRows[0].RowNumber != 4 ||
+		!strings.Contains(report.SkippedRows[0].Reason, `unknown time zone "Europe/Zeland"`) {
+		t.Errorf("Expected row 4 skipped for its time zone, got %+v", report.SkippedRows)
+	}
+}
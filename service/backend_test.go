@@ -0,0 +1,23 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/db/dbtest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(dbtest.Main(m))
+}
+
+// forEachBackend runs test as a subtest against a fresh database of every
+// dialect dbtest offers: always SQLite, and PostgreSQL when configured.
+func forEachBackend(t *testing.T, test func(t *testing.T, testDatabase *db.DB)) {
+	for _, backend := range dbtest.Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			test(t, backend.Open(t))
+		})
+	}
+}
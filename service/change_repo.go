@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+)
+
+// changedAtLayout is how the change log triggers format changed_at.
+const changedAtLayout = "2006-01-02 15:04:05.999"
+
+// ListChanges returns up to limit change log entries with a sequence number
+// greater than since, oldest first.
+func (repo *SwiftRepository) ListChanges(since int64, limit int) ([]models.SwiftCodeChange, error) {
+	const changesSQL = `
+		SELECT sequence, change_type, changed_at,
+		       country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_code_changes
+		 WHERE sequence > ?
+		 ORDER BY sequence
+		 LIMIT ?;
+	`
+
+	rows, err := repo.reader().Query(changesSQL, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.SwiftCodeChange
+	for rows.Next() {
+		var change models.SwiftCodeChange
+		sc := &change.Code
+		if err := rows.Scan(
+			&change.Sequence, &change.Type, &change.ChangedAt,
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType,
+			&sc.Name, &sc.Address, &sc.TownName,
+			&sc.CountryName, &sc.TimeZone,
+			&sc.IsHeadquarter, &sc.HqSwiftCode,
+		); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// LatestChangeSequence returns the sequence number of the newest change log
+// entry, or 0 when nothing has been recorded yet.
+func (repo *SwiftRepository) LatestChangeSequence() (int64, error) {
+	var sequence int64
+	err := repo.reader().QueryRow(`SELECT COALESCE(MAX(sequence), 0) FROM swift_code_changes;`).Scan(&sequence)
+	return sequence, err
+}
+
+// PruneChanges deletes change log entries recorded before cutoff and returns
+// how many were removed. The newest entry is always kept, so
+// LatestChangeSequence never goes backwards.
+func (repo *SwiftRepository) PruneChanges(cutoff time.Time) (int64, error) {
+	var before any = cutoff.UTC()
+	if repo.DB.Dialect == db.SQLite {
+		// the SQLite triggers store changed_at as text
+		before = cutoff.UTC().Format("2006-01-02 15:04:05.000")
+	}
+	result, err := repo.DB.Exec(`
+		DELETE FROM swift_code_changes
+		 WHERE changed_at < ?
+		   AND sequence < (SELECT MAX(sequence) FROM swift_code_changes);`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SwiftCodeLastModified returns when code, or any branch filed under it, last
+// appeared in the change log. It is the zero time when nothing was recorded.
+// Once PruneChanges has removed every entry of a code, the oldest entry still
+// kept stands in for them: it is later than anything pruned, so the code has
+// certainly not changed since.
+func (repo *SwiftRepository) SwiftCodeLastModified(code string) (time.Time, error) {
+	return repo.lastChangedAt(`
+		SELECT COALESCE(MAX(changed_at), (`+oldestChangeSQL+`)) FROM swift_code_changes
+		 WHERE swift_code = ? OR hq_swift_code = ?;`, code, code)
+}
+
+// CountryLastModified returns when any code in the country last appeared in
+// the change log, deletes included, falling back on the oldest entry kept as
+// SwiftCodeLastModified does.
+func (repo *SwiftRepository) CountryLastModified(requestedISO2 string) (time.Time, error) {
+	return repo.lastChangedAt(`
+		SELECT COALESCE(MAX(changed_at), (`+oldestChangeSQL+`)) FROM swift_code_changes
+		 WHERE country_iso2 = ?;`, requestedISO2)
+}
+
+// oldestChangeSQL selects the time of the oldest change log entry, found
+// through the primary key rather than by scanning changed_at.
+const oldestChangeSQL = `SELECT changed_at FROM swift_code_changes ORDER BY sequence LIMIT 1`
+
+// lastChangedAt runs a MAX(changed_at) query. SQLite hands aggregates back as
+// text, so its value is parsed here; PostgreSQL returns a timestamp.
+func (repo *SwiftRepository) lastChangedAt(query string, args ...any) (time.Time, error) {
+	var changedAt any
+	if err := repo.reader().QueryRow(query, args...).Scan(&changedAt); err != nil {
+		return time.Time{}, err
+	}
+	switch value := changedAt.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return value.UTC(), nil
+	case string:
+		return time.ParseInLocation(changedAtLayout, value, time.UTC)
+	case []byte:
+		return time.ParseInLocation(changedAtLayout, string(value), time.UTC)
+	}
+	return time.Time{}, fmt.Errorf("unexpected changed_at value %T", changedAt)
+}
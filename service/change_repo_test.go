@@ -0,0 +1,167 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+)
+
+// TestChangeLogRecordsEveryWrite checks that creates, updates and deletes all
+// land in the change log in order, with deletes keeping the removed row.
+func TestChangeLogRecordsEveryWrite(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+
+		repository := &SwiftRepository{DB: testDatabase}
+
+		entry := models.SwiftCode{
+			CountryISO2: "ZZ", SwiftCode: "ZZBANKXXX", Name: "ZELAND NATIONAL BANK",
+			Address: "1 MAIN PLAZA", CountryName: "ZELAND", IsHeadquarter: true,
+		}
+		if insertError := repository.CreateSwiftCode(entry); insertError != nil {
+			t.Fatalf("Unexpected error inserting code: %v", insertError)
+		}
+		testDatabase.Exec(`UPDATE swift_codes SET address = '9 NEW SQUARE' WHERE swift_code = 'ZZBANKXXX';`)
+		repository.DeleteSwiftCode("ZZBANKXXX")
+
+		changes, queryError := repository.ListChanges(0, 10)
+		if queryError != nil {
+			t.Fatalf("Expected no error listing changes, got: %v", queryError)
+		}
+		expectedTypes := []string{models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted}
+		if len(changes) != len(expectedTypes) {
+			t.Fatalf("Expected %d changes, got %d", len(expectedTypes), len(changes))
+		}
+		for i, change := range changes {
+			if change.Type != expectedTypes[i] {
+				t.Errorf("Change %d: expected type %s, got %s", i, expectedTypes[i], change.Type)
+			}
+			if i > 0 && change.Sequence <= changes[i-1].Sequence {
+				t.Errorf("Change %d: sequence %d is not increasing", i, change.Sequence)
+			}
+		}
+		if changes[2].Code.Address != "9 NEW SQUARE" {
+			t.Errorf("Expected deleted row to keep its last address, got '%s'", changes[2].Code.Address)
+		}
+
+		laterChanges, _ := repository.ListChanges(changes[0].Sequence, 10)
+		if len(laterChanges) != 2 {
+			t.Errorf("Expected 2 changes after the first, got %d", len(laterChanges))
+		}
+		latestSequence, _ := repository.LatestChangeSequence()
+		if latestSequence != changes[2].Sequence {
+			t.Errorf("Expected latest sequence %d, got %d", changes[2].Sequence, latestSequence)
+		}
+	})
+}
+
+// TestLastModifiedFollowsTheChangeLog checks that deleting a branch moves
+// its head office's and its country's modification time forward.
+func TestLastModifiedFollowsTheChangeLog(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+
+		repository := &SwiftRepository{DB: testDatabase}
+		if unknown, queryError := repository.CountryLastModified("ZZ"); queryError != nil || !unknown.IsZero() {
+			t.Fatalf("Expected the zero time for an empty change log, got %v, %v", unknown, queryError)
+		}
+
+		repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", Name: "HQ", IsHeadquarter: true})
+		repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ001", Name: "BRANCH", HqSwiftCode: "ZZBANKZZXXX"})
+		created, queryError := repository.SwiftCodeLastModified("ZZBANKZZXXX")
+		if queryError != nil || created.IsZero() {
+			t.Fatalf("Expected a modification time after inserts, got %v, %v", created, queryError)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		if deleteError := repository.DeleteSwiftCode("ZZBANKZZ001"); deleteError != nil {
+			t.Fatalf("Failed to delete branch: %v", deleteError)
+		}
+		afterDelete, _ := repository.SwiftCodeLastModified("ZZBANKZZXXX")
+		if !afterDelete.After(created) {
+			t.Errorf("Expected the branch delete to advance the head office's time, %v is not after %v", afterDelete, created)
+		}
+		if country, _ := repository.CountryLastModified("ZZ"); !country.Equal(afterDelete) {
+			t.Errorf("Expected the country's time %v to equal %v", country, afterDelete)
+		}
+	})
+}
+
+// TestChangeLogSequencesBecomeVisibleInOrder holds one writer's transaction
+// open while a second writer commits, and expects a reader paging with
+// sequence > cursor to never step over the first writer's change.
+func TestChangeLogSequencesBecomeVisibleInOrder(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+		if testDatabase.Dialect != db.Postgres {
+			t.Skip("SQLite only has one writer at a time")
+		}
+		repository := &SwiftRepository{DB: testDatabase}
+		insertSQL := `INSERT INTO swift_codes (swift_code, country_iso2, name) VALUES (?, 'ZZ', 'ZELAND NATIONAL BANK');`
+
+		slowWriter, beginError := testDatabase.Begin()
+		if beginError != nil {
+			t.Fatalf("Unexpected error starting transaction: %v", beginError)
+		}
+		defer slowWriter.Rollback()
+		if _, insertError := slowWriter.Exec(insertSQL, "ZZBANKXXX"); insertError != nil {
+			t.Fatalf("Unexpected error inserting code: %v", insertError)
+		}
+
+		fastWriterDone := make(chan error, 1)
+		go func() {
+			_, insertError := testDatabase.Exec(insertSQL, "ZZBANK001")
+			fastWriterDone <- insertError
+		}()
+		select {
+		case <-fastWriterDone:
+			t.Fatalf("Expected the second writer to wait for the first to commit")
+		case <-time.After(200 * time.Millisecond):
+		}
+		if changes, _ := repository.ListChanges(0, 10); len(changes) != 0 {
+			t.Fatalf("Expected no visible changes while both writers are open, got %d", len(changes))
+		}
+
+		if commitError := slowWriter.Commit(); commitError != nil {
+			t.Fatalf("Unexpected error committing: %v", commitError)
+		}
+		if insertError := <-fastWriterDone; insertError != nil {
+			t.Fatalf("Unexpected error from second writer: %v", insertError)
+		}
+		changes, _ := repository.ListChanges(0, 10)
+		if len(changes) != 2 || changes[0].Code.SwiftCode != "ZZBANKXXX" || changes[1].Code.SwiftCode != "ZZBANK001" {
+			t.Errorf("Expected the first writer's change to come first, got %+v", changes)
+		}
+	})
+}
+
+// TestPruneChangesKeepsTheNewestEntry ages every change log entry past the
+// cutoff and expects all but the newest to be removed, and a pruned code to
+// keep a Last-Modified time.
+func TestPruneChangesKeepsTheNewestEntry(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+		repository := &SwiftRepository{DB: testDatabase}
+		for _, code := range []string{"ZZBANKXXX", "ZZBANK001", "ZZBANK002"} {
+			repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: code, Name: "ZELAND NATIONAL BANK", CountryName: "ZELAND"})
+		}
+		latestSequence, _ := repository.LatestChangeSequence()
+		testDatabase.Exec(`UPDATE swift_code_changes SET changed_at = '2000-01-01 00:00:00.000';`)
+
+		removed, pruneError := repository.PruneChanges(time.Now())
+		if pruneError != nil {
+			t.Fatalf("Unexpected error pruning: %v", pruneError)
+		}
+		if removed != 2 {
+			t.Errorf("Expected 2 entries removed, got %d", removed)
+		}
+		if sequence, _ := repository.LatestChangeSequence(); sequence != latestSequence {
+			t.Errorf("Expected latest sequence to stay %d, got %d", latestSequence, sequence)
+		}
+		if pruned, _ := repository.SwiftCodeLastModified("ZZBANKXXX"); !pruned.Equal(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected a pruned code to report the oldest entry kept, got %v", pruned)
+		}
+
+		if removed, _ := repository.PruneChanges(time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)); removed != 0 {
+			t.Errorf("Expected nothing older than 1999 to be removed, got %d", removed)
+		}
+	})
+}
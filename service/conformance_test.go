@@ -0,0 +1,22 @@
+package service_test
+
+import (
+	"testing"
+
+	"swift-codes-project/db/dbtest"
+	handler "swift-codes-project/handlers"
+	"swift-codes-project/service"
+	"swift-codes-project/storetest"
+)
+
+// TestSwiftRepositoryConformance runs the SwiftDataStore suite against the
+// repository on every database dbtest offers.
+func TestSwiftRepositoryConformance(t *testing.T) {
+	for _, backend := range dbtest.Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) handler.SwiftDataStore {
+				return &service.SwiftRepository{DB: backend.Open(t)}
+			})
+		})
+	}
+}
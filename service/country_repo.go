@@ -0,0 +1,54 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"swift-codes-project/models"
+)
+
+// ListCountries returns every country in the countries table, ordered by
+// ISO-2 code, with how many swift codes each has. Countries without any are
+// listed with a count of 0.
+func (repo *SwiftRepository) ListCountries() ([]models.Country, error) {
+	rows, err := repo.reader().Query(`
+		SELECT c.iso2, c.iso3, c.numeric_code, c.name, c.official_name,
+		       COUNT(s.swift_code)
+		  FROM countries c
+		  LEFT JOIN swift_codes s ON s.country_iso2 = c.iso2
+		 GROUP BY c.iso2, c.iso3, c.numeric_code, c.name, c.official_name
+		 ORDER BY c.iso2;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.Country
+	for rows.Next() {
+		var country models.Country
+		if err := rows.Scan(
+			&country.ISO2, &country.ISO3, &country.Numeric, &country.Name,
+			&country.OfficialName, &country.SwiftCodeCount,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, country)
+	}
+	return results, rows.Err()
+}
+
+// GetCountry returns the country whose ISO-2 code is iso2, or ErrNotFound.
+// SwiftCodeCount is left at 0.
+func (repo *SwiftRepository) GetCountry(iso2 string) (models.Country, error) {
+	var country models.Country
+	err := repo.reader().QueryRow(`
+		SELECT iso2, iso3, numeric_code, name, official_name
+		  FROM countries
+		 WHERE iso2 = ?;
+	`, iso2).Scan(&country.ISO2, &country.ISO3, &country.Numeric, &country.Name, &country.OfficialName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return country, ErrNotFound
+	}
+	return country, err
+}
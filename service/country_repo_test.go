@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+)
+
+func TestListCountriesCountsSwiftCodes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+		repository := &SwiftRepository{DB: testDatabase}
+		for _, code := range []string{"PLBANKPLXXX", "PLBANKPL001", "MTBANKMTXXX"} {
+			iso2, countryName := code[6:8], "POLAND"
+			if iso2 == "MT" {
+				countryName = "MALTA"
+			}
+			if err := repository.CreateSwiftCode(models.SwiftCode{CountryISO2: iso2, SwiftCode: code, CountryName: countryName}); err != nil {
+				t.Fatalf("Failed to create %s: %v", code, err)
+			}
+		}
+
+		list, err := repository.ListCountries()
+		if err != nil {
+			t.Fatalf("ListCountries failed: %v", err)
+		}
+		if len(list) != 249 {
+			t.Fatalf("Expected the seeded 249 countries, got %d", len(list))
+		}
+		counts := make(map[string]int)
+		for _, country := range list {
+			counts[country.ISO2] = country.SwiftCodeCount
+		}
+		if counts["PL"] != 2 || counts["MT"] != 1 || counts["DE"] != 0 {
+			t.Errorf("Expected PL 2, MT 1 and DE 0, got %d, %d and %d", counts["PL"], counts["MT"], counts["DE"])
+		}
+
+		poland, err := repository.GetCountry("PL")
+		if err != nil || poland.ISO3 != "POL" || poland.OfficialName != "Republic of Poland" {
+			t.Errorf("Unexpected country for PL: %+v, %v", poland, err)
+		}
+		if _, err := repository.GetCountry("ZZ"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for ZZ, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+)
+
+// ErrDatasetVersionNotFound is returned when no dataset version matches a
+// version reference or point in time.
+var ErrDatasetVersionNotFound = errors.New("dataset version not found")
+
+// sameRowSQL matches a history row h against a live row s on every column.
+const sameRowSQL = `
+	s.swift_code = h.swift_code
+	AND s.country_iso2 IS NOT DISTINCT FROM h.country_iso2
+	AND s.code_type IS NOT DISTINCT FROM h.code_type
+	AND s.name IS NOT DISTINCT FROM h.name
+	AND s.address IS NOT DISTINCT FROM h.address
+	AND s.town_name IS NOT DISTINCT FROM h.town_name
+	AND s.country_name IS NOT DISTINCT FROM h.country_name
+	AND s.time_zone IS NOT DISTINCT FROM h.time_zone
+	AND s.is_headquarter IS NOT DISTINCT FROM h.is_headquarter
+	AND s.hq_swift_code IS NOT DISTINCT FROM h.hq_swift_code`
+
+// ErrInvalidVersionName is returned when a version name could be mistaken
+// for a version number.
+var ErrInvalidVersionName = errors.New("dataset version names must not be numeric")
+
+// DatasetVersionName builds the default version name for an import of source.
+// The import time is kept to the nanosecond, so imports of the same file in
+// quick succession still get distinct names.
+func DatasetVersionName(source string, importedAt time.Time) string {
+	return filepath.Base(source) + "@" + importedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// RecordDatasetVersion snapshots the current swift_codes table as a new named
+// version effective from effectiveFrom. Only rows that changed since the
+// previous version are written: history rows that no longer match the live
+// table are closed, and new or modified rows are opened at this version.
+func (repo *SwiftRepository) RecordDatasetVersion(name, source string, effectiveFrom time.Time) (models.DatasetVersion, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return models.DatasetVersion{}, err
+	}
+	defer tx.Rollback()
+
+	version, err := RecordDatasetVersionTx(tx, name, source, effectiveFrom)
+	if err != nil {
+		return version, err
+	}
+	return version, tx.Commit()
+}
+
+// RecordDatasetVersionTx is RecordDatasetVersion run inside tx, which lets an
+// import and the version it creates commit together.
+func RecordDatasetVersionTx(tx *db.Tx, name, source string, effectiveFrom time.Time) (models.DatasetVersion, error) {
+	version := models.DatasetVersion{
+		Name:          name,
+		Source:        source,
+		EffectiveFrom: effectiveFrom.UTC(),
+		ImportedAt:    time.Now().UTC(),
+	}
+	if _, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return version, ErrInvalidVersionName
+	}
+
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM swift_codes;`).Scan(&version.RowCount); err != nil {
+		return version, err
+	}
+	if err := tx.QueryRow(`
+		INSERT INTO dataset_versions (name, source, effective_from, imported_at, row_count)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING version;`,
+		version.Name, version.Source, version.EffectiveFrom, version.ImportedAt, version.RowCount,
+	).Scan(&version.Version); err != nil {
+		return version, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE swift_code_history AS h
+		   SET valid_to = ?
+		 WHERE valid_to IS NULL
+		   AND NOT EXISTS (SELECT 1 FROM swift_codes AS s WHERE `+sameRowSQL+`);`,
+		version.Version,
+	); err != nil {
+		return version, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO swift_code_history (
+			country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone,
+			is_headquarter, hq_swift_code, valid_from
+		)
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code, CAST(? AS BIGINT)
+		  FROM swift_codes AS s
+		 WHERE NOT EXISTS (
+			SELECT 1 FROM swift_code_history AS h
+			 WHERE h.valid_to IS NULL AND h.swift_code = s.swift_code
+		 );`,
+		version.Version,
+	); err != nil {
+		return version, err
+	}
+	return version, nil
+}
+
+const datasetVersionColumns = `version, name, COALESCE(source, ''), effective_from, imported_at, row_count`
+
+func scanDatasetVersion(row interface{ Scan(...interface{}) error }) (models.DatasetVersion, error) {
+	var version models.DatasetVersion
+	err := row.Scan(
+		&version.Version, &version.Name, &version.Source,
+		&version.EffectiveFrom, &version.ImportedAt, &version.RowCount,
+	)
+	return version, err
+}
+
+// ListDatasetVersions returns every recorded version, oldest first.
+func (repo *SwiftRepository) ListDatasetVersions() ([]models.DatasetVersion, error) {
+	rows, err := repo.reader().Query(`SELECT ` + datasetVersionColumns + ` FROM dataset_versions ORDER BY version;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.DatasetVersion
+	for rows.Next() {
+		version, err := scanDatasetVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// GetDatasetVersion resolves a version reference, which may be either the
+// numeric version or its name. Names are never numeric, so the two cannot
+// collide.
+func (repo *SwiftRepository) GetDatasetVersion(reference string) (models.DatasetVersion, error) {
+	query := `SELECT ` + datasetVersionColumns + ` FROM dataset_versions WHERE name = ?;`
+	var argument interface{} = reference
+	if number, err := strconv.ParseInt(reference, 10, 64); err == nil {
+		query = `SELECT ` + datasetVersionColumns + ` FROM dataset_versions WHERE version = ?;`
+		argument = number
+	}
+	version, err := scanDatasetVersion(repo.reader().QueryRow(query, argument))
+	if errors.Is(err, sql.ErrNoRows) {
+		return version, ErrDatasetVersionNotFound
+	}
+	return version, err
+}
+
+// GetDatasetVersionAsOf returns the version that was in effect at asOf: the
+// most recently imported version whose effective date is not after asOf.
+func (repo *SwiftRepository) GetDatasetVersionAsOf(asOf time.Time) (models.DatasetVersion, error) {
+	const asOfSQL = `
+		SELECT ` + datasetVersionColumns + `
+		  FROM dataset_versions
+		 WHERE effective_from <= ?
+		 ORDER BY effective_from DESC, version DESC
+		 LIMIT 1;
+	`
+	version, err := scanDatasetVersion(repo.reader().QueryRow(asOfSQL, asOf.UTC()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return version, ErrDatasetVersionNotFound
+	}
+	return version, err
+}
+
+// inVersionSQL restricts history rows to those that were part of version ?.
+const inVersionSQL = `valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)`
+
+// GetSwiftCodeAtVersion is GetSwiftCode answered from the historical snapshot
+// of the given version instead of the live table.
+func (repo *SwiftRepository) GetSwiftCodeAtVersion(requestedCode string, version int64) (models.SwiftCode, []models.SwiftCode, error) {
+	const findByCodeSQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_code_history
+		 WHERE swift_code = ? AND ` + inVersionSQL + `;
+	`
+
+	var headOffice models.SwiftCode
+	err := repo.reader().QueryRow(findByCodeSQL, requestedCode, version, version).Scan(
+		&headOffice.CountryISO2, &headOffice.SwiftCode, &headOffice.CodeType,
+		&headOffice.Name, &headOffice.Address, &headOffice.TownName,
+		&headOffice.CountryName, &headOffice.TimeZone,
+		&headOffice.IsHeadquarter, &headOffice.HqSwiftCode,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return headOffice, nil, ErrNotFound
+		}
+		return headOffice, nil, err
+	}
+	if !headOffice.IsHeadquarter {
+		return headOffice, nil, nil
+	}
+
+	const findBranchesSQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_code_history
+		 WHERE hq_swift_code = ? AND ` + inVersionSQL + `;
+	`
+	branches, err := repo.queryHistory(findBranchesSQL, headOffice.SwiftCode, version, version)
+	return headOffice, branches, err
+}
+
+// GetCountrySwiftCodesAtVersion is GetCountrySwiftCodes answered from the
+// historical snapshot of the given version.
+func (repo *SwiftRepository) GetCountrySwiftCodesAtVersion(iso2 string, version int64) ([]models.SwiftCode, error) {
+	const byCountrySQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_code_history
+		 WHERE country_iso2 = ? AND ` + inVersionSQL + `;
+	`
+	return repo.queryHistory(byCountrySQL, iso2, version, version)
+}
+
+// ListSwiftCodesAtVersion returns every row of the given version's snapshot.
+func (repo *SwiftRepository) ListSwiftCodesAtVersion(version int64) ([]models.SwiftCode, error) {
+	const snapshotSQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_code_history
+		 WHERE ` + inVersionSQL + `
+		 ORDER BY swift_code;
+	`
+	return repo.queryHistory(snapshotSQL, version, version)
+}
+
+func (repo *SwiftRepository) queryHistory(query string, args ...interface{}) ([]models.SwiftCode, error) {
+	rows, err := repo.reader().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SwiftCode
+	for rows.Next() {
+		var sc models.SwiftCode
+		if err := rows.Scan(
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType,
+			&sc.Name, &sc.Address, &sc.TownName,
+			&sc.CountryName, &sc.TimeZone,
+			&sc.IsHeadquarter, &sc.HqSwiftCode,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, sc)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+)
+
+// TestDatasetVersionsAnswerFromHistoricalSnapshot records two versions with a
+// modified, a removed and an added code in between, and checks each version
+// still answers with the data it was imported with.
+func TestDatasetVersionsAnswerFromHistoricalSnapshot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+
+		repository := &SwiftRepository{DB: testDatabase}
+
+		headOfficeEntry := models.SwiftCode{
+			CountryISO2: "ZZ", SwiftCode: "ZZBANKXXX", Name: "ZELAND NATIONAL BANK",
+			Address: "1 MAIN PLAZA", CountryName: "ZELAND", IsHeadquarter: true,
+		}
+		branchEntry := models.SwiftCode{
+			CountryISO2: "ZZ", SwiftCode: "ZZBANK001", Name: "ZELAND NATIONAL BANK",
+			Address: "2 SECOND AVE", CountryName: "ZELAND", HqSwiftCode: "ZZBANKXXX",
+		}
+		repository.CreateSwiftCode(headOfficeEntry)
+		repository.CreateSwiftCode(branchEntry)
+
+		september := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+		firstVersion, recordError := repository.RecordDatasetVersion("september", "test", september)
+		if recordError != nil {
+			t.Fatalf("Unexpected error recording first version: %v", recordError)
+		}
+
+		// October: the head office moved, the branch closed and a new one opened.
+		testDatabase.Exec(`UPDATE swift_codes SET address = '9 NEW SQUARE' WHERE swift_code = 'ZZBANKXXX';`)
+		repository.DeleteSwiftCode("ZZBANK001")
+		newBranchEntry := branchEntry
+		newBranchEntry.SwiftCode = "ZZBANK002"
+		repository.CreateSwiftCode(newBranchEntry)
+
+		october := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		secondVersion, recordError := repository.RecordDatasetVersion("october", "test", october)
+		if recordError != nil {
+			t.Fatalf("Unexpected error recording second version: %v", recordError)
+		}
+
+		headOffice, branches, queryError := repository.GetSwiftCodeAtVersion("ZZBANKXXX", firstVersion.Version)
+		if queryError != nil {
+			t.Fatalf("Expected head office in first version, got: %v", queryError)
+		}
+		if headOffice.Address != "1 MAIN PLAZA" || len(branches) != 1 || branches[0].SwiftCode != "ZZBANK001" {
+			t.Errorf("Unexpected first version data: %+v %+v", headOffice, branches)
+		}
+
+		headOffice, branches, queryError = repository.GetSwiftCodeAtVersion("ZZBANKXXX", secondVersion.Version)
+		if queryError != nil {
+			t.Fatalf("Expected head office in second version, got: %v", queryError)
+		}
+		if headOffice.Address != "9 NEW SQUARE" || len(branches) != 1 || branches[0].SwiftCode != "ZZBANK002" {
+			t.Errorf("Unexpected second version data: %+v %+v", headOffice, branches)
+		}
+
+		if _, _, queryError := repository.GetSwiftCodeAtVersion("ZZBANK001", secondVersion.Version); queryError == nil {
+			t.Errorf("Expected closed branch to be missing from second version")
+		}
+
+		asOfVersion, resolveError := repository.GetDatasetVersionAsOf(time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC))
+		if resolveError != nil || asOfVersion.Version != firstVersion.Version {
+			t.Errorf("Expected mid-September to resolve to first version, got %+v (%v)", asOfVersion, resolveError)
+		}
+		if _, resolveError := repository.GetDatasetVersionAsOf(time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)); resolveError != ErrDatasetVersionNotFound {
+			t.Errorf("Expected ErrDatasetVersionNotFound before first version, got %v", resolveError)
+		}
+
+		namedVersion, resolveError := repository.GetDatasetVersion("october")
+		if resolveError != nil || namedVersion.Version != secondVersion.Version || namedVersion.RowCount != 2 {
+			t.Errorf("Expected 'october' to resolve to second version with 2 rows, got %+v (%v)", namedVersion, resolveError)
+		}
+
+		if _, recordError := repository.RecordDatasetVersion("1", "test", october); recordError != ErrInvalidVersionName {
+			t.Errorf("Expected a numeric name to be refused, got %v", recordError)
+		}
+		importedAt := time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC)
+		if DatasetVersionName("codes.xlsx", importedAt) == DatasetVersionName("codes.xlsx", importedAt.Add(time.Millisecond)) {
+			t.Errorf("Expected imports a millisecond apart to get distinct names")
+		}
+	})
+}
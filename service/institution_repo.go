@@ -0,0 +1,59 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"swift-codes-project/models"
+)
+
+// GetInstitution returns the institution whose bank code, the first four
+// letters of its swift codes, is bankCode, with every head office and branch
+// it has worldwide ordered by country and swift code. It returns ErrNotFound
+// when the institution has no codes.
+func (repo *SwiftRepository) GetInstitution(bankCode string) (models.Institution, []models.SwiftCode, error) {
+	var institution models.Institution
+	err := repo.reader().QueryRow(`
+		SELECT bank_code, name FROM institutions WHERE bank_code = ?;
+	`, bankCode).Scan(&institution.BankCode, &institution.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return institution, nil, ErrNotFound
+	}
+	if err != nil {
+		return institution, nil, err
+	}
+
+	rows, err := repo.reader().Query(`
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_codes
+		 WHERE upper(substr(swift_code, 1, 4)) = ?
+		 ORDER BY country_iso2, swift_code;
+	`, bankCode)
+	if err != nil {
+		return institution, nil, err
+	}
+	defer rows.Close()
+
+	var offices []models.SwiftCode
+	for rows.Next() {
+		var sc models.SwiftCode
+		if err := rows.Scan(
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType,
+			&sc.Name, &sc.Address, &sc.TownName,
+			&sc.CountryName, &sc.TimeZone,
+			&sc.IsHeadquarter, &sc.HqSwiftCode,
+		); err != nil {
+			return institution, nil, err
+		}
+		offices = append(offices, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return institution, nil, err
+	}
+	if len(offices) == 0 {
+		return institution, nil, ErrNotFound
+	}
+	return institution, offices, nil
+}
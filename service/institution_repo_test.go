@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+)
+
+// TestGetInstitutionAcrossCountries follows one bank through writes: the
+// institutions table is kept by triggers and named after the head office.
+func TestGetInstitutionAcrossCountries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+		repository := &SwiftRepository{DB: testDatabase}
+		for _, entry := range []models.SwiftCode{
+			{CountryISO2: "PL", SwiftCode: "ABCDPLPW001", CountryName: "POLAND", Name: "ABC BANK WARSAW BRANCH", HqSwiftCode: "ABCDPLPWXXX"},
+			{CountryISO2: "PL", SwiftCode: "ABCDPLPWXXX", CountryName: "POLAND", Name: "ABC BANK", IsHeadquarter: true},
+			{CountryISO2: "DE", SwiftCode: "ABCDDEFFXXX", CountryName: "GERMANY", Name: "ABC BANK", Address: "FRANKFURT", IsHeadquarter: true},
+			{CountryISO2: "PL", SwiftCode: "WXYZPLPWXXX", CountryName: "POLAND", Name: "OTHER BANK", IsHeadquarter: true},
+		} {
+			if err := repository.CreateSwiftCode(entry); err != nil {
+				t.Fatalf("Failed to create %s: %v", entry.SwiftCode, err)
+			}
+		}
+
+		institution, offices, err := repository.GetInstitution("ABCD")
+		if err != nil {
+			t.Fatalf("GetInstitution failed: %v", err)
+		}
+		if institution.BankCode != "ABCD" {
+			t.Errorf("Expected bank code ABCD, got %q", institution.BankCode)
+		}
+		if institution.Name != "ABC BANK" {
+			t.Errorf("Expected the head office name, got %q", institution.Name)
+		}
+		var codes []string
+		for _, office := range offices {
+			codes = append(codes, office.SwiftCode)
+		}
+		expected := []string{"ABCDDEFFXXX", "ABCDPLPW001", "ABCDPLPWXXX"}
+		if len(codes) != len(expected) {
+			t.Fatalf("Expected offices %v, got %v", expected, codes)
+		}
+		for i := range expected {
+			if codes[i] != expected[i] {
+				t.Fatalf("Expected offices %v in country order, got %v", expected, codes)
+			}
+		}
+
+		if _, _, err := repository.GetInstitution("NONE"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for an unknown bank, got %v", err)
+		}
+		if err := repository.DeleteSwiftCode("WXYZPLPWXXX"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, _, err := repository.GetInstitution("WXYZ"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound once every office is gone, got %v", err)
+		}
+	})
+}
+
+// TestInstitutionsPreferHeadOfficeName lets a branch name a bank only until a
+// head office is written.
+func TestInstitutionsPreferHeadOfficeName(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+		repository := &SwiftRepository{DB: testDatabase}
+		if err := repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "PL", SwiftCode: "ABCDPLPW001", CountryName: "POLAND", Name: "BRANCH"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		institution, _, err := repository.GetInstitution("ABCD")
+		if err != nil || institution.Name != "BRANCH" {
+			t.Fatalf("Expected a branch to name a bank without head office, got %+v, %v", institution, err)
+		}
+		if err := repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "PL", SwiftCode: "ABCDPLPWXXX", CountryName: "POLAND", Name: "HEAD OFFICE", IsHeadquarter: true}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "PL", SwiftCode: "ABCDPLPW002", CountryName: "POLAND", Name: "LATER BRANCH"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		institution, _, err = repository.GetInstitution("ABCD")
+		if err != nil || institution.Name != "HEAD OFFICE" {
+			t.Errorf("Expected the head office to name the bank, got %+v, %v", institution, err)
+		}
+	})
+}
+
+// TestInstitutionsFollowDeletes deletes a bank's head office, which hands the
+// name to its branch, and then the branch, which removes the bank.
+func TestInstitutionsFollowDeletes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
+		repository := &SwiftRepository{DB: testDatabase}
+		for _, sc := range []models.SwiftCode{
+			{CountryISO2: "PL", SwiftCode: "ABCDPLPWXXX", CountryName: "POLAND", Name: "HEAD OFFICE", IsHeadquarter: true},
+			{CountryISO2: "PL", SwiftCode: "ABCDPLPW001", CountryName: "POLAND", Name: "BRANCH", HqSwiftCode: "ABCDPLPWXXX"},
+		} {
+			if err := repository.CreateSwiftCode(sc); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+		}
+
+		if err := repository.DeleteSwiftCode("ABCDPLPWXXX"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		institution, _, err := repository.GetInstitution("ABCD")
+		if err != nil || institution.Name != "BRANCH" {
+			t.Fatalf("Expected the branch to name the bank once its head office is gone, got %+v, %v", institution, err)
+		}
+
+		if err := repository.DeleteSwiftCode("ABCDPLPW001"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		var remaining int
+		testDatabase.QueryRow(`SELECT COUNT(*) FROM institutions;`).Scan(&remaining)
+		if _, _, err := repository.GetInstitution("ABCD"); !errors.Is(err, ErrNotFound) || remaining != 0 {
+			t.Errorf("Expected the bank to go with its last code, got %v with %d institutions left", err, remaining)
+		}
+	})
+}
@@ -3,28 +3,134 @@ package service
 import (
 	"database/sql"
 	"errors"
+	"strings"
+	"swift-codes-project/db"
 	"swift-codes-project/models"
+	"sync/atomic"
 )
 
+// Errors returned by the repository, so callers such as the HTTP and gRPC
+// layers can map them to their own status codes.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// SwiftRepository stores swift codes in SQLite or PostgreSQL, whichever
+// dialect DB was opened with. A replica that only reads may leave DB nil and
+// install its databases with SwapReadDB.
 type SwiftRepository struct {
-	DB *sql.DB
+	DB *db.DB
+	// ReadDB, when set, serves swift code, change log and dataset reads, e.g.
+	// from a replica, so they may lag writes made through DB. Webhook state
+	// always goes through DB, since the dispatcher reads what it just wrote.
+	ReadDB *db.DB
+	// swappedReadDB replaces ReadDB once SwapReadDB has been called.
+	swappedReadDB atomic.Pointer[db.DB]
 }
 
-// GetSwiftCode returns:
-// the exact row whose swift_code = requestedCode
-// if that row is a head‑office, all its branch rows
-
-func (repo *SwiftRepository) GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error) {
-	const findByCodeSQL = `
+// The queries behind lookups and single-row writes, kept at package level so
+// NewSwiftRepository can prepare them; db.DB only reuses a prepared statement
+// for the identical text.
+const (
+	findByCodeSQL = `
 		SELECT country_iso2, swift_code, code_type, name, address,
 		       town_name, country_name, time_zone,
 		       is_headquarter, hq_swift_code
 		  FROM swift_codes
 		 WHERE swift_code = ?;
 	`
+	findBranchesSQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_codes
+		 WHERE hq_swift_code = ?;
+	`
+	byCountrySQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_codes
+		 WHERE country_iso2 = ?;
+	`
+	insertSQL = `
+		INSERT INTO swift_codes (
+			country_iso2, swift_code, code_type, name, address,
+			town_name, country_name, time_zone,
+			is_headquarter, hq_swift_code
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+	updateSQL = `
+		UPDATE swift_codes
+		   SET country_iso2 = ?, code_type = ?, name = ?, address = ?,
+		       town_name = ?, country_name = ?, time_zone = ?,
+		       is_headquarter = ?, hq_swift_code = ?
+		 WHERE swift_code = ?;
+	`
+	deleteSQL = `DELETE FROM swift_codes WHERE swift_code = ?;`
+)
+
+var (
+	lookupQueries = []string{findByCodeSQL, findBranchesSQL, byCountrySQL}
+	writeQueries  = []string{insertSQL, updateSQL, deleteSQL}
+)
+
+// NewSwiftRepository returns a repository over database, reading from
+// readDatabase when it is not nil, with the lookup and write statements
+// prepared once up front. Either may be nil, as on a replica that installs
+// its databases with SwapReadDB. A repository built as a struct literal works
+// the same, only without prepared statements.
+func NewSwiftRepository(database, readDatabase *db.DB) (*SwiftRepository, error) {
+	repo := &SwiftRepository{DB: database, ReadDB: readDatabase}
+	if database != nil {
+		if err := database.PrepareCached(writeQueries...); err != nil {
+			return nil, err
+		}
+	}
+	if reader := repo.reader(); reader != nil {
+		if err := reader.PrepareCached(lookupQueries...); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
 
+// reader returns the pool reads go to.
+func (repo *SwiftRepository) reader() *db.DB {
+	if swapped := repo.swappedReadDB.Load(); swapped != nil {
+		return swapped
+	}
+	if repo.ReadDB != nil {
+		return repo.ReadDB
+	}
+	return repo.DB
+}
+
+// SwapReadDB sends reads to next from now on, e.g. a newer snapshot on a
+// replica, and returns the pool they went to before. Queries already running
+// finish on the old pool, so callers should give them time before closing it.
+func (repo *SwiftRepository) SwapReadDB(next *db.DB) *db.DB {
+	if next != nil {
+		// a statement that fails to prepare just runs unprepared
+		_ = next.PrepareCached(lookupQueries...)
+	}
+	if previous := repo.swappedReadDB.Swap(next); previous != nil {
+		return previous
+	}
+	if repo.ReadDB != nil {
+		return repo.ReadDB
+	}
+	return repo.DB
+}
+
+// GetSwiftCode returns:
+// the exact row whose swift_code = requestedCode
+// if that row is a head‑office, all its branch rows
+
+func (repo *SwiftRepository) GetSwiftCode(requestedCode string) (models.SwiftCode, []models.SwiftCode, error) {
 	var headOffice models.SwiftCode
-	err := repo.DB.QueryRow(findByCodeSQL, requestedCode).Scan(
+	err := repo.reader().QueryRow(findByCodeSQL, requestedCode).Scan(
 		&headOffice.CountryISO2, &headOffice.SwiftCode, &headOffice.CodeType,
 		&headOffice.Name, &headOffice.Address, &headOffice.TownName,
 		&headOffice.CountryName, &headOffice.TimeZone,
@@ -32,7 +138,7 @@ func (repo *SwiftRepository) GetSwiftCode(requestedCode string) (models.SwiftCod
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return headOffice, nil, errors.New("not found")
+			return headOffice, nil, ErrNotFound
 		}
 		return headOffice, nil, err
 	}
@@ -42,14 +148,7 @@ func (repo *SwiftRepository) GetSwiftCode(requestedCode string) (models.SwiftCod
 		return headOffice, nil, nil // it’s just a branch – done.
 	}
 
-	const findBranchesSQL = `
-		SELECT country_iso2, swift_code, code_type, name, address,
-		       town_name, country_name, time_zone,
-		       is_headquarter, hq_swift_code
-		  FROM swift_codes
-		 WHERE hq_swift_code = ?;
-	`
-	rows, err := repo.DB.Query(findBranchesSQL, headOffice.SwiftCode)
+	rows, err := repo.reader().Query(findBranchesSQL, headOffice.SwiftCode)
 	if err != nil {
 		return headOffice, nil, err
 	}
@@ -73,21 +172,47 @@ func (repo *SwiftRepository) GetSwiftCode(requestedCode string) (models.SwiftCod
 
 // GetCountrySwiftCodes returns all rows for the given ISO‑2 country code.
 func (repo *SwiftRepository) GetCountrySwiftCodes(iso2 string) ([]models.SwiftCode, error) {
-	const byCountrySQL = `
+	rows, err := repo.reader().Query(byCountrySQL, iso2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SwiftCode
+	for rows.Next() {
+		var sc models.SwiftCode
+		if err := rows.Scan(
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType,
+			&sc.Name, &sc.Address, &sc.TownName,
+			&sc.CountryName, &sc.TimeZone,
+			&sc.IsHeadquarter, &sc.HqSwiftCode,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, sc)
+	}
+	return results, nil
+}
+
+// ExportSwiftCodes streams every row, optionally limited to one ISO‑2 country,
+// to visit in swift_code order. Rows are scanned one at a time so the whole
+// directory is never held in memory; iteration stops at the first error.
+func (repo *SwiftRepository) ExportSwiftCodes(iso2 string, visit func(models.SwiftCode) error) error {
+	const exportSQL = `
 		SELECT country_iso2, swift_code, code_type, name, address,
 		       town_name, country_name, time_zone,
 		       is_headquarter, hq_swift_code
 		  FROM swift_codes
-		 WHERE country_iso2 = ?;
+		 WHERE ? = '' OR country_iso2 = ?
+		 ORDER BY swift_code;
 	`
 
-	rows, err := repo.DB.Query(byCountrySQL, iso2)
+	rows, err := repo.reader().Query(exportSQL, iso2, iso2)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var results []models.SwiftCode
 	for rows.Next() {
 		var sc models.SwiftCode
 		if err := rows.Scan(
@@ -96,34 +221,150 @@ func (repo *SwiftRepository) GetCountrySwiftCodes(iso2 string) ([]models.SwiftCo
 			&sc.CountryName, &sc.TimeZone,
 			&sc.IsHeadquarter, &sc.HqSwiftCode,
 		); err != nil {
-			return nil, err
+			return err
+		}
+		if err := visit(sc); err != nil {
+			return err
 		}
-		results = append(results, sc)
 	}
-	return results, nil
+	return rows.Err()
 }
 
-// CreateSwiftCode inserts a brand‑new row. It returns an error if the PK clashes
-// (duplicate swift_code) or if the SQL fails.
+// CreateSwiftCode inserts a brand‑new row. It returns ErrAlreadyExists if the PK
+// clashes (duplicate swift_code), or the SQL error if it fails otherwise.
 func (repo *SwiftRepository) CreateSwiftCode(sc models.SwiftCode) error {
-	const insertSQL = `
-		INSERT INTO swift_codes (
-			country_iso2, swift_code, code_type, name, address,
-			town_name, country_name, time_zone,
-			is_headquarter, hq_swift_code
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
-	`
 	_, err := repo.DB.Exec(insertSQL,
 		sc.CountryISO2, sc.SwiftCode, sc.CodeType, sc.Name, sc.Address,
 		sc.TownName, sc.CountryName, sc.TimeZone,
 		sc.IsHeadquarter, sc.HqSwiftCode,
 	)
+	if db.IsUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// UpdateSwiftCode replaces every column of the row whose swift_code matches
+// sc.SwiftCode. It returns ErrNotFound when there is no such row.
+func (repo *SwiftRepository) UpdateSwiftCode(sc models.SwiftCode) error {
+	result, err := repo.DB.Exec(updateSQL,
+		sc.CountryISO2, sc.CodeType, sc.Name, sc.Address,
+		sc.TownName, sc.CountryName, sc.TimeZone,
+		sc.IsHeadquarter, sc.HqSwiftCode, sc.SwiftCode,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
 	return err
 }
 
-// DeleteSwiftCode removes the row whose swift_code = codeToDelete.
+// DeleteSwiftCode removes the row whose swift_code = codeToDelete. It returns
+// ErrNotFound when there is no such row.
 func (repo *SwiftRepository) DeleteSwiftCode(codeToDelete string) error {
-	const deleteSQL = `DELETE FROM swift_codes WHERE swift_code = ?;`
-	_, err := repo.DB.Exec(deleteSQL, codeToDelete)
+	result, err := repo.DB.Exec(deleteSQL, codeToDelete)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
 	return err
 }
+
+// SearchSwiftCodes returns up to limit rows whose swift code starts with query
+// or whose bank or town name contains it, ignoring case. iso2, when not empty,
+// restricts the search to one country. Columns are upper-cased rather than
+// relying on LIKE, which ignores case in SQLite but not in PostgreSQL.
+func (repo *SwiftRepository) SearchSwiftCodes(query, iso2 string, limit int) ([]models.SwiftCode, error) {
+	const searchSQL = `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_codes
+		 WHERE (UPPER(swift_code) LIKE ? ESCAPE '\' OR UPPER(name) LIKE ? ESCAPE '\' OR UPPER(town_name) LIKE ? ESCAPE '\')
+		   AND (? = '' OR country_iso2 = ?)
+		 ORDER BY swift_code
+		 LIMIT ?;
+	`
+	escaped := likeEscaper.Replace(strings.ToUpper(query))
+	rows, err := repo.reader().Query(searchSQL,
+		escaped+"%", "%"+escaped+"%", "%"+escaped+"%", iso2, iso2, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SwiftCode
+	for rows.Next() {
+		var sc models.SwiftCode
+		if err := rows.Scan(
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType,
+			&sc.Name, &sc.Address, &sc.TownName,
+			&sc.CountryName, &sc.TimeZone,
+			&sc.IsHeadquarter, &sc.HqSwiftCode,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, sc)
+	}
+	return results, rows.Err()
+}
+
+// likeEscaper escapes LIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetSwiftCodesByCode returns the rows whose swift code is in codes, in one
+// query. Codes with no row are simply absent from the result.
+func (repo *SwiftRepository) GetSwiftCodesByCode(codes []string) ([]models.SwiftCode, error) {
+	return repo.querySwiftCodesIn("swift_code", codes)
+}
+
+// GetBranchesByHeadOffice returns the branch rows of every head office in
+// hqCodes, in one query, ordered by swift code.
+func (repo *SwiftRepository) GetBranchesByHeadOffice(hqCodes []string) ([]models.SwiftCode, error) {
+	return repo.querySwiftCodesIn("hq_swift_code", hqCodes)
+}
+
+// querySwiftCodesIn selects the rows whose column matches any of values.
+func (repo *SwiftRepository) querySwiftCodesIn(column string, values []string) ([]models.SwiftCode, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	query := `
+		SELECT country_iso2, swift_code, code_type, name, address,
+		       town_name, country_name, time_zone,
+		       is_headquarter, hq_swift_code
+		  FROM swift_codes
+		 WHERE ` + column + ` IN (` + placeholders + `)
+		 ORDER BY swift_code;
+	`
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	rows, err := repo.reader().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SwiftCode
+	for rows.Next() {
+		var sc models.SwiftCode
+		if err := rows.Scan(
+			&sc.CountryISO2, &sc.SwiftCode, &sc.CodeType,
+			&sc.Name, &sc.Address, &sc.TownName,
+			&sc.CountryName, &sc.TimeZone,
+			&sc.IsHeadquarter, &sc.HqSwiftCode,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, sc)
+	}
+	return results, rows.Err()
+}
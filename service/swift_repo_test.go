@@ -1,129 +1,253 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 
 	"swift-codes-project/db"
+	"swift-codes-project/db/dbtest"
 	"swift-codes-project/models"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func TestGetSwiftCodeReturnsHeadOfficeAndBranches(t *testing.T) {
-	testDatabse, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	forEachBackend(t, func(t *testing.T, testDatabse *db.DB) {
+		repository := &SwiftRepository{DB: testDatabse}
 
-	if initError != nil {
-		t.Fatalf("Failed to initialize in memory databse: %v", initError)
-	}
-	defer testDatabse.Close()
-
-	repository := &SwiftRepository{DB: testDatabse}
-
-	headOfficeEntry := models.SwiftCode{
-		CountryISO2:   "ZZ",
-		SwiftCode:     "ZZBANKXXX",
-		CodeType:      "BANK",
-		Name:          "ZELAND NATIONAL BANK",
-		Address:       "1 MAIN PLAZA",
-		TownName:      "CAPITAL",
-		CountryName:   "ZELAND",
-		TimeZone:      "UTC+00:00",
-		IsHeadquarter: true,
-		HqSwiftCode:   "",
-	}
-	firstBranchEntry := models.SwiftCode{
-		CountryISO2:   "ZZ",
-		SwiftCode:     "ZZBANK001",
-		CodeType:      "BRANCH",
-		Name:          "ZELAND NATIONAL BANK",
-		Address:       "2 SECOND AVE",
-		TownName:      "CAPITAL",
-		CountryName:   "ZELAND",
-		TimeZone:      "UTC+00:00",
-		IsHeadquarter: false,
-		HqSwiftCode:   "ZZBANKXXX",
-	}
-	secondBranchEntry := models.SwiftCode{
-		CountryISO2:   "ZZ",
-		SwiftCode:     "ZZBANK002",
-		CodeType:      "BRANCH",
-		Name:          "ZELAND NATIONAL BANK",
-		Address:       "3 THIRD STREET",
-		TownName:      "CAPITAL",
-		CountryName:   "ZELAND",
-		TimeZone:      "UTC+00:00",
-		IsHeadquarter: false,
-		HqSwiftCode:   "ZZBANKXXX",
-	}
-	if insertError := repository.CreateSwiftCode(headOfficeEntry); insertError != nil {
-		t.Fatalf("Unexpected error inserting head office: %v", insertError)
-	}
-	if insertError := repository.CreateSwiftCode(firstBranchEntry); insertError != nil {
-		t.Fatalf("Unexpected error inserting first branch: %v", insertError)
-	}
-	if insertError := repository.CreateSwiftCode(secondBranchEntry); insertError != nil {
-		t.Fatalf("Unexpected error inserting second branch: %v", insertError)
-	}
+		headOfficeEntry := models.SwiftCode{
+			CountryISO2:   "ZZ",
+			SwiftCode:     "ZZBANKXXX",
+			CodeType:      "BANK",
+			Name:          "ZELAND NATIONAL BANK",
+			Address:       "1 MAIN PLAZA",
+			TownName:      "CAPITAL",
+			CountryName:   "ZELAND",
+			TimeZone:      "UTC+00:00",
+			IsHeadquarter: true,
+			HqSwiftCode:   "",
+		}
+		firstBranchEntry := models.SwiftCode{
+			CountryISO2:   "ZZ",
+			SwiftCode:     "ZZBANK001",
+			CodeType:      "BRANCH",
+			Name:          "ZELAND NATIONAL BANK",
+			Address:       "2 SECOND AVE",
+			TownName:      "CAPITAL",
+			CountryName:   "ZELAND",
+			TimeZone:      "UTC+00:00",
+			IsHeadquarter: false,
+			HqSwiftCode:   "ZZBANKXXX",
+		}
+		secondBranchEntry := models.SwiftCode{
+			CountryISO2:   "ZZ",
+			SwiftCode:     "ZZBANK002",
+			CodeType:      "BRANCH",
+			Name:          "ZELAND NATIONAL BANK",
+			Address:       "3 THIRD STREET",
+			TownName:      "CAPITAL",
+			CountryName:   "ZELAND",
+			TimeZone:      "UTC+00:00",
+			IsHeadquarter: false,
+			HqSwiftCode:   "ZZBANKXXX",
+		}
+		if insertError := repository.CreateSwiftCode(headOfficeEntry); insertError != nil {
+			t.Fatalf("Unexpected error inserting head office: %v", insertError)
+		}
+		if insertError := repository.CreateSwiftCode(firstBranchEntry); insertError != nil {
+			t.Fatalf("Unexpected error inserting first branch: %v", insertError)
+		}
+		if insertError := repository.CreateSwiftCode(secondBranchEntry); insertError != nil {
+			t.Fatalf("Unexpected error inserting second branch: %v", insertError)
+		}
 
-	returnedHeadOffice, returnedBranches, queryError := repository.GetSwiftCode("ZZBANKXXX")
-	if queryError != nil {
-		t.Fatalf("Expected no error querying head office, got: %v", queryError)
-	}
-	if !returnedHeadOffice.IsHeadquarter {
-		t.Errorf("Expected IsHeadquarter=True for head office, got false")
-	}
-	if len(returnedBranches) != 2 {
-		t.Errorf("Expected branches 2 instead got %d", len(returnedBranches))
-	}
+		returnedHeadOffice, returnedBranches, queryError := repository.GetSwiftCode("ZZBANKXXX")
+		if queryError != nil {
+			t.Fatalf("Expected no error querying head office, got: %v", queryError)
+		}
+		if !returnedHeadOffice.IsHeadquarter {
+			t.Errorf("Expected IsHeadquarter=True for head office, got false")
+		}
+		if len(returnedBranches) != 2 {
+			t.Errorf("Expected branches 2 instead got %d", len(returnedBranches))
+		}
+	})
 }
 
 // TestGetCountrySwiftCodesFiltersCorrectly seeds two countries and asserts
 // that only the requested ISO‑2 code is returned.
 func TestGetCountrySwiftCodesFiltersCorrectly(t *testing.T) {
-	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
-	if initError != nil {
-		t.Fatalf("Failed to initialize in-memory database: %v", initError)
-	}
-	defer testDatabase.Close()
-
-	repository := &SwiftRepository{DB: testDatabase}
-
-	entryForCountryAA := models.SwiftCode{
-		CountryISO2:   "AA",
-		SwiftCode:     "AABANKXXX",
-		CodeType:      "BANK",
-		Name:          "BANK AA",
-		Address:       "1 AA STREET",
-		TownName:      "AA TOWN",
-		CountryName:   "AALAND",
-		TimeZone:      "UTC+01:00",
-		IsHeadquarter: true,
-		HqSwiftCode:   "",
-	}
-	entryForCountryBB := models.SwiftCode{
-		CountryISO2:   "BB",
-		SwiftCode:     "BBBANKXXX",
-		CodeType:      "BANK",
-		Name:          "BANK BB",
-		Address:       "1 BB STREET",
-		TownName:      "BB TOWN",
-		CountryName:   "BBLAND",
-		TimeZone:      "UTC+02:00",
-		IsHeadquarter: true,
-		HqSwiftCode:   "",
-	}
+	forEachBackend(t, func(t *testing.T, testDatabase *db.DB) {
 
-	repository.CreateSwiftCode(entryForCountryAA)
-	repository.CreateSwiftCode(entryForCountryBB)
+		repository := &SwiftRepository{DB: testDatabase}
 
-	codesForCountryAA, queryError := repository.GetCountrySwiftCodes("AA")
-	if queryError != nil {
-		t.Fatalf("Expected no error querying country AA, got: %v", queryError)
-	}
-	if len(codesForCountryAA) != 1 {
-		t.Errorf("Expected 1 code for country AA, got %d", len(codesForCountryAA))
+		entryForCountryAA := models.SwiftCode{
+			CountryISO2:   "AA",
+			SwiftCode:     "AABANKXXX",
+			CodeType:      "BANK",
+			Name:          "BANK AA",
+			Address:       "1 AA STREET",
+			TownName:      "AA TOWN",
+			CountryName:   "AALAND",
+			TimeZone:      "UTC+01:00",
+			IsHeadquarter: true,
+			HqSwiftCode:   "",
+		}
+		entryForCountryBB := models.SwiftCode{
+			CountryISO2:   "BB",
+			SwiftCode:     "BBBANKXXX",
+			CodeType:      "BANK",
+			Name:          "BANK BB",
+			Address:       "1 BB STREET",
+			TownName:      "BB TOWN",
+			CountryName:   "BBLAND",
+			TimeZone:      "UTC+02:00",
+			IsHeadquarter: true,
+			HqSwiftCode:   "",
+		}
+
+		repository.CreateSwiftCode(entryForCountryAA)
+		repository.CreateSwiftCode(entryForCountryBB)
+
+		codesForCountryAA, queryError := repository.GetCountrySwiftCodes("AA")
+		if queryError != nil {
+			t.Fatalf("Expected no error querying country AA, got: %v", queryError)
+		}
+		if len(codesForCountryAA) != 1 {
+			t.Errorf("Expected 1 code for country AA, got %d", len(codesForCountryAA))
+		}
+		if codesForCountryAA[0].CountryISO2 != "AA" {
+			t.Errorf("Expected CountryISO2 'AA', got '%s'", codesForCountryAA[0].CountryISO2)
+		}
+	})
+}
+
+// TestReadsGoToTheReadPool gives the repository two separate databases, so
+// which one a method touched shows in what it returns.
+func TestReadsGoToTheReadPool(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, writeDatabase *db.DB) {
+		readDatabase := dbtest.OpenSQLite(t)
+		repository := &SwiftRepository{DB: writeDatabase, ReadDB: readDatabase}
+
+		written := models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", CodeType: "BIC11",
+			Name: "ZELAND NATIONAL BANK", CountryName: "ZELAND", IsHeadquarter: true}
+		if err := repository.CreateSwiftCode(written); err != nil {
+			t.Fatalf("Expected the write to succeed, got %v", err)
+		}
+		if _, _, err := repository.GetSwiftCode(written.SwiftCode); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected the read pool not to have the row, got %v", err)
+		}
+		if _, _, err := (&SwiftRepository{DB: writeDatabase}).GetSwiftCode(written.SwiftCode); err != nil {
+			t.Errorf("Expected the row in the write pool, got %v", err)
+		}
+
+		replicated := written
+		replicated.SwiftCode = "ZZBANKZZ001"
+		if err := (&SwiftRepository{DB: readDatabase}).CreateSwiftCode(replicated); err != nil {
+			t.Fatalf("Failed to seed the read pool: %v", err)
+		}
+		if rows, err := repository.GetCountrySwiftCodes("ZZ"); err != nil || len(rows) != 1 || rows[0].SwiftCode != replicated.SwiftCode {
+			t.Errorf("Expected the country list from the read pool, got %+v %v", rows, err)
+		}
+	})
+}
+
+// benchmarkConfigurations compares the connection setup the server used to
+// have, a shared cache with a rollback journal, the default two idle
+// connections and no prepared statements, against the current defaults.
+var benchmarkConfigurations = []struct {
+	name     string
+	settings db.Settings
+	dsn      string
+	prepared bool
+}{
+	{"baseline", db.Settings{JournalMode: "DELETE", Synchronous: "FULL", MaxIdleConns: 2}, "?cache=shared&_fk=1", false},
+	{"tuned", db.DefaultSettings, "?_fk=1", true},
+}
+
+// openBenchmarkRepository seeds a file database with 50 countries of one head
+// office and 19 branches each.
+func openBenchmarkRepository(b *testing.B, settings db.Settings, dsn string, prepared bool) *SwiftRepository {
+	b.Helper()
+	benchDatabase, err := settings.Open(db.SQLite, "file:"+filepath.Join(b.TempDir(), "bench.db")+dsn)
+	if err != nil {
+		b.Fatalf("Failed to open database: %v", err)
+	}
+	b.Cleanup(func() { benchDatabase.Close() })
+	repository := &SwiftRepository{DB: benchDatabase}
+	if prepared {
+		if repository, err = NewSwiftRepository(benchDatabase, nil); err != nil {
+			b.Fatalf("Failed to prepare repository: %v", err)
+		}
+	}
+	tx, err := benchDatabase.Begin()
+	if err != nil {
+		b.Fatalf("Failed to begin seeding: %v", err)
+	}
+	for country := 0; country < 50; country++ {
+		iso2 := benchmarkCountry(country)
+		for branch := 0; branch < 20; branch++ {
+			code, hqCode := fmt.Sprintf("%sBANK%03d", iso2, branch), iso2+"BANKXXX"
+			if branch == 0 {
+				code, hqCode = hqCode, ""
+			}
+			if _, err := tx.Exec(insertSQL, iso2, code, "BIC11", "BENCH BANK", "1 BENCH ROAD",
+				"CAPITAL", "BENCHLAND", "Europe/London", branch == 0, hqCode); err != nil {
+				b.Fatalf("Failed to seed %s: %v", code, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatalf("Failed to commit seed data: %v", err)
+	}
+	return repository
+}
+
+func benchmarkCountry(i int) string {
+	return string(rune('A'+i/26)) + string(rune('A'+i%26))
+}
+
+// BenchmarkGetSwiftCodeParallel looks up head offices, with their branches,
+// from every GOMAXPROCS goroutine at once.
+func BenchmarkGetSwiftCodeParallel(b *testing.B) {
+	for _, configuration := range benchmarkConfigurations {
+		b.Run(configuration.name, func(b *testing.B) {
+			repository := openBenchmarkRepository(b, configuration.settings, configuration.dsn, configuration.prepared)
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					code := benchmarkCountry(int(next.Add(1)%50)) + "BANKXXX"
+					if _, branches, err := repository.GetSwiftCode(code); err != nil || len(branches) != 19 {
+						b.Fatalf("GetSwiftCode(%s): %d branches, %v", code, len(branches), err)
+					}
+				}
+			})
+		})
 	}
-	if codesForCountryAA[0].CountryISO2 != "AA" {
-		t.Errorf("Expected CountryISO2 'AA', got '%s'", codesForCountryAA[0].CountryISO2)
+}
+
+// BenchmarkGetCountrySwiftCodesParallel lists whole countries from every
+// GOMAXPROCS goroutine at once.
+func BenchmarkGetCountrySwiftCodesParallel(b *testing.B) {
+	for _, configuration := range benchmarkConfigurations {
+		b.Run(configuration.name, func(b *testing.B) {
+			repository := openBenchmarkRepository(b, configuration.settings, configuration.dsn, configuration.prepared)
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					iso2 := benchmarkCountry(int(next.Add(1) % 50))
+					if codes, err := repository.GetCountrySwiftCodes(iso2); err != nil || len(codes) != 20 {
+						b.Fatalf("GetCountrySwiftCodes(%s): %d codes, %v", iso2, len(codes), err)
+					}
+				}
+			})
+		})
 	}
 }
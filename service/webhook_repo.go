@@ -0,0 +1,192 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"swift-codes-project/models"
+)
+
+// ErrWebhookSubscriptionNotFound is returned when a subscription id is unknown.
+var ErrWebhookSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// CreateWebhookSubscription stores a new subscription and returns it with its id.
+func (repo *SwiftRepository) CreateWebhookSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	subscription.CreatedAt = time.Now().UTC()
+	err := repo.DB.QueryRow(`
+		INSERT INTO webhook_subscriptions (url, secret, event_types, country_iso2, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id;`,
+		subscription.URL, subscription.Secret, strings.Join(subscription.EventTypes, ","),
+		subscription.CountryISO2, subscription.CreatedAt,
+	).Scan(&subscription.ID)
+	return subscription, err
+}
+
+// ListWebhookSubscriptions returns every subscription, including its secret.
+func (repo *SwiftRepository) ListWebhookSubscriptions() ([]models.WebhookSubscription, error) {
+	rows, err := repo.DB.Query(`
+		SELECT id, url, secret, event_types, country_iso2, created_at
+		  FROM webhook_subscriptions
+		 ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		var subscription models.WebhookSubscription
+		var eventTypes string
+		if err := rows.Scan(
+			&subscription.ID, &subscription.URL, &subscription.Secret,
+			&eventTypes, &subscription.CountryISO2, &subscription.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		subscription.EventTypes = strings.Split(eventTypes, ",")
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// DeleteWebhookSubscription removes a subscription and its deliveries.
+func (repo *SwiftRepository) DeleteWebhookSubscription(id int64) error {
+	result, err := repo.DB.Exec(`DELETE FROM webhook_subscriptions WHERE id = ?;`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWebhookSubscriptionNotFound
+	}
+	return err
+}
+
+const webhookDeliveryColumns = `
+	id, subscription_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func (repo *SwiftRepository) queryWebhookDeliveries(query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload string
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(
+			&delivery.ID, &delivery.SubscriptionID, &delivery.EventType, &payload,
+			&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt,
+			&delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &deliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		delivery.Payload = []byte(payload)
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// ListWebhookDeliveries returns the most recent deliveries of a subscription, newest first.
+func (repo *SwiftRepository) ListWebhookDeliveries(subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	var exists bool
+	if err := repo.DB.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = ?);`, subscriptionID,
+	).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+	return repo.queryWebhookDeliveries(`
+		SELECT `+webhookDeliveryColumns+`
+		  FROM webhook_deliveries
+		 WHERE subscription_id = ?
+		 ORDER BY id DESC
+		 LIMIT ?;`, subscriptionID, limit)
+}
+
+// DueWebhookDeliveries returns pending deliveries whose next attempt is due at
+// now, oldest first. A delivery is held back while an earlier one of its
+// subscription waits for a retry, so each receiver gets its events in order.
+func (repo *SwiftRepository) DueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return repo.queryWebhookDeliveries(`
+		SELECT `+webhookDeliveryColumns+`
+		  FROM webhook_deliveries AS d
+		 WHERE status = ? AND next_attempt_at <= ?
+		   AND NOT EXISTS (
+			SELECT 1 FROM webhook_deliveries AS earlier
+			 WHERE earlier.subscription_id = d.subscription_id
+			   AND earlier.status = ? AND earlier.id < d.id
+			   AND earlier.next_attempt_at > ?
+		 )
+		 ORDER BY id
+		 LIMIT ?;`, models.DeliveryPending, now.UTC(), models.DeliveryPending, now.UTC(), limit)
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt.
+func (repo *SwiftRepository) UpdateWebhookDelivery(delivery models.WebhookDelivery) error {
+	var deliveredAt interface{}
+	if delivery.DeliveredAt != nil {
+		deliveredAt = delivery.DeliveredAt.UTC()
+	}
+	_, err := repo.DB.Exec(`
+		UPDATE webhook_deliveries
+		   SET status = ?, attempts = ?, next_attempt_at = ?,
+		       last_status_code = ?, last_error = ?, delivered_at = ?
+		 WHERE id = ?;`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(),
+		delivery.LastStatusCode, delivery.LastError, deliveredAt, delivery.ID,
+	)
+	return err
+}
+
+// GetWebhookCursor returns how far an event source has been read. found is
+// false when the source has never been read.
+func (repo *SwiftRepository) GetWebhookCursor(source string) (position int64, found bool, err error) {
+	err = repo.DB.QueryRow(`SELECT position FROM webhook_cursors WHERE source = ?;`, source).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return position, err == nil, err
+}
+
+// EnqueueWebhookDeliveries writes deliveries to the outbox and moves the
+// source's cursor to position in the same transaction, so an event is never
+// marked as read without its deliveries being stored.
+func (repo *SwiftRepository) EnqueueWebhookDeliveries(source string, position int64, deliveries []models.WebhookDelivery) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, delivery := range deliveries {
+		if _, err := tx.Exec(`
+			INSERT INTO webhook_deliveries (
+				subscription_id, event_type, payload, status, next_attempt_at, created_at
+			) VALUES (?, ?, ?, ?, ?, ?);`,
+			delivery.SubscriptionID, delivery.EventType, string(delivery.Payload),
+			models.DeliveryPending, delivery.NextAttemptAt.UTC(), delivery.CreatedAt.UTC(),
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO webhook_cursors (source, position) VALUES (?, ?)
+		ON CONFLICT (source) DO UPDATE SET position = excluded.position;`,
+		source, position,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
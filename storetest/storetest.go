@@ -0,0 +1,233 @@
+// Package storetest is a conformance suite for SwiftDataStore
+// implementations. Every implementation runs the same cases, so a store backed
+// by SQLite, PostgreSQL or anything else behaves the way the handlers expect.
+package storetest
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	handler "swift-codes-project/handlers"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// fixture is a head office with two branches in ZZ and one head office in YY.
+var fixture = []models.SwiftCode{
+	{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZXXX", CodeType: "BIC11", Name: "ZELAND NATIONAL BANK",
+		Address: "1 MAIN PLAZA", TownName: "CAPITAL", CountryName: "ZELAND", TimeZone: "Europe/London",
+		IsHeadquarter: true},
+	{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ001", CodeType: "BIC11", Name: "ZELAND NATIONAL BANK",
+		Address: "2 SECOND AVE", TownName: "PORT TOWN", CountryName: "ZELAND", TimeZone: "Europe/London",
+		HqSwiftCode: "ZZBANKZZXXX"},
+	{CountryISO2: "ZZ", SwiftCode: "ZZBANKZZ002", CodeType: "BIC11", Name: "ZELAND NATIONAL BANK",
+		Address: "3 THIRD STREET", TownName: "CAPITAL", CountryName: "ZELAND", TimeZone: "Europe/London",
+		HqSwiftCode: "ZZBANKZZXXX"},
+	{CountryISO2: "YY", SwiftCode: "YYCOOPYYXXX", CodeType: "BIC11", Name: "YONDER 100% COOPERATIVE",
+		Address: "7 HARBOUR ROAD", TownName: "YONDER CITY", CountryName: "YONDERLAND", TimeZone: "Asia/Tokyo",
+		IsHeadquarter: true},
+}
+
+// Run executes the suite. newStore must return an empty store for each case.
+func Run(t *testing.T, newStore func(t *testing.T) handler.SwiftDataStore) {
+	seeded := func(t *testing.T) handler.SwiftDataStore {
+		t.Helper()
+		store := newStore(t)
+		for _, row := range fixture {
+			if err := store.CreateSwiftCode(row); err != nil {
+				t.Fatalf("Failed to seed %s: %v", row.SwiftCode, err)
+			}
+		}
+		return store
+	}
+
+	t.Run("GetHeadOfficeWithBranches", func(t *testing.T) {
+		headOffice, branches, err := seeded(t).GetSwiftCode("ZZBANKZZXXX")
+		if err != nil {
+			t.Fatalf("Expected the head office, got %v", err)
+		}
+		if headOffice != fixture[0] {
+			t.Errorf("Head office changed on the way through: %+v", headOffice)
+		}
+		sortByCode(branches)
+		if len(branches) != 2 || branches[0] != fixture[1] || branches[1] != fixture[2] {
+			t.Errorf("Expected both branches, got %+v", branches)
+		}
+	})
+
+	t.Run("GetBranchHasNoBranches", func(t *testing.T) {
+		branch, branches, err := seeded(t).GetSwiftCode("ZZBANKZZ001")
+		if err != nil || branch != fixture[1] || len(branches) != 0 {
+			t.Errorf("Expected the branch alone, got %+v %+v %v", branch, branches, err)
+		}
+	})
+
+	t.Run("GetUnknownCode", func(t *testing.T) {
+		if _, _, err := seeded(t).GetSwiftCode("ZZBANKZZ999"); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("GetCountryFiltersByISO2", func(t *testing.T) {
+		store := seeded(t)
+		rows, err := store.GetCountrySwiftCodes("ZZ")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		sortByCode(rows)
+		if len(rows) != 3 || rows[0] != fixture[1] || rows[2] != fixture[0] {
+			t.Errorf("Expected the three ZZ codes, got %+v", rows)
+		}
+		if rows, err := store.GetCountrySwiftCodes("XX"); err != nil || len(rows) != 0 {
+			t.Errorf("Expected no rows for an unknown country, got %+v %v", rows, err)
+		}
+	})
+
+	t.Run("GetByCodeSkipsUnknownCodes", func(t *testing.T) {
+		rows, err := seeded(t).GetSwiftCodesByCode([]string{"YYCOOPYYXXX", "ZZBANKZZ999", "ZZBANKZZ001"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		sortByCode(rows)
+		if len(rows) != 2 || rows[0] != fixture[3] || rows[1] != fixture[1] {
+			t.Errorf("Expected the two known codes, got %+v", rows)
+		}
+	})
+
+	t.Run("CreateDuplicate", func(t *testing.T) {
+		duplicate := fixture[0]
+		duplicate.Name = "IMPOSTER BANK"
+		store := seeded(t)
+		if err := store.CreateSwiftCode(duplicate); !errors.Is(err, service.ErrAlreadyExists) {
+			t.Errorf("Expected ErrAlreadyExists, got %v", err)
+		}
+		if headOffice, _, _ := store.GetSwiftCode(duplicate.SwiftCode); headOffice != fixture[0] {
+			t.Errorf("Expected the original row to survive, got %+v", headOffice)
+		}
+	})
+
+	t.Run("UpdateReplacesEveryColumn", func(t *testing.T) {
+		store := seeded(t)
+		moved := fixture[1]
+		moved.Address, moved.TownName, moved.HqSwiftCode = "9 NEW SQUARE", "NEW TOWN", ""
+		if err := store.UpdateSwiftCode(moved); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if branch, _, _ := store.GetSwiftCode(moved.SwiftCode); branch != moved {
+			t.Errorf("Expected the updated row, got %+v", branch)
+		}
+		if _, branches, _ := store.GetSwiftCode("ZZBANKZZXXX"); len(branches) != 1 {
+			t.Errorf("Expected the unlinked branch to leave its head office, got %+v", branches)
+		}
+		unknown := fixture[1]
+		unknown.SwiftCode = "ZZBANKZZ999"
+		if err := store.UpdateSwiftCode(unknown); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound updating an unknown code, got %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := seeded(t)
+		if err := store.DeleteSwiftCode("ZZBANKZZ002"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, _, err := store.GetSwiftCode("ZZBANKZZ002"); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("Expected the deleted code to be gone, got %v", err)
+		}
+		if _, branches, _ := store.GetSwiftCode("ZZBANKZZXXX"); len(branches) != 1 {
+			t.Errorf("Expected one branch left, got %+v", branches)
+		}
+		if err := store.DeleteSwiftCode("ZZBANKZZ002"); !errors.Is(err, service.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		store := seeded(t)
+		testCases := []struct {
+			name, query, iso2 string
+			limit             int
+			expected          []string
+		}{
+			{"code prefix", "zzbankzz0", "", 10, []string{"ZZBANKZZ001", "ZZBANKZZ002"}},
+			{"name substring", "national", "", 10, []string{"ZZBANKZZ001", "ZZBANKZZ002", "ZZBANKZZXXX"}},
+			{"town substring", "port", "", 10, []string{"ZZBANKZZ001"}},
+			{"country filter", "bank", "YY", 10, nil},
+			{"limit", "zz", "", 2, []string{"ZZBANKZZ001", "ZZBANKZZ002"}},
+			{"wildcards are literal", "100%", "", 10, []string{"YYCOOPYYXXX"}},
+			{"underscore is literal", "Z_BANK", "", 10, nil},
+			{"code is a prefix only", "BANKZZ", "", 10, nil},
+		}
+		for _, testCase := range testCases {
+			rows, err := store.SearchSwiftCodes(testCase.query, testCase.iso2, testCase.limit)
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", testCase.name, err)
+			}
+			if got := codesOf(rows); !equalCodes(got, testCase.expected) {
+				t.Errorf("%s: expected %v, got %v", testCase.name, testCase.expected, got)
+			}
+		}
+	})
+
+	t.Run("ExportIsOrderedAndFiltered", func(t *testing.T) {
+		store := seeded(t)
+		var visited []models.SwiftCode
+		err := store.ExportSwiftCodes("", func(row models.SwiftCode) error {
+			visited = append(visited, row)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := []string{"YYCOOPYYXXX", "ZZBANKZZ001", "ZZBANKZZ002", "ZZBANKZZXXX"}
+		if got := codesOf(visited); !equalCodes(got, expected) {
+			t.Errorf("Expected %v in order, got %v", expected, got)
+		}
+
+		visited = nil
+		store.ExportSwiftCodes("YY", func(row models.SwiftCode) error {
+			visited = append(visited, row)
+			return nil
+		})
+		if len(visited) != 1 || visited[0] != fixture[3] {
+			t.Errorf("Expected only the YY code, got %+v", visited)
+		}
+	})
+
+	t.Run("ExportStopsAtVisitError", func(t *testing.T) {
+		stop := errors.New("stop")
+		visits := 0
+		err := seeded(t).ExportSwiftCodes("", func(models.SwiftCode) error {
+			visits++
+			return stop
+		})
+		if !errors.Is(err, stop) || visits != 1 {
+			t.Errorf("Expected the visit error after one row, got %v after %d", err, visits)
+		}
+	})
+}
+
+func sortByCode(rows []models.SwiftCode) {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].SwiftCode < rows[j].SwiftCode })
+}
+
+func codesOf(rows []models.SwiftCode) []string {
+	var codes []string
+	for _, row := range rows {
+		codes = append(codes, row.SwiftCode)
+	}
+	return codes
+}
+
+func equalCodes(got, expected []string) bool {
+	if len(got) != len(expected) {
+		return false
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+// Package timezones maps the free-text time zones of vendor files onto IANA
+// zone names and reports an office's local time in them.
+package timezones
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// zones must load the same way on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// zones lists every IANA zone and link name. It was generated from the
+// tzdata.zi of tzdata 2025b.
+//
+//go:embed zones.txt
+var zones []byte
+
+// ErrUnknownZone is returned by Normalize for names that are not IANA zones.
+var ErrUnknownZone = errors.New("unknown time zone")
+
+var (
+	parseOnce sync.Once
+	byFolded  map[string]string // lower-cased name to its IANA spelling
+)
+
+func parse() {
+	byFolded = make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(zones))
+	for scanner.Scan() {
+		name := scanner.Text()
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		byFolded[strings.ToLower(name)] = name
+	}
+}
+
+// utcOffset matches fixed offsets such as UTC+01:00, GMT-5 or +0200.
+var utcOffset = regexp.MustCompile(`^(?:UTC|GMT)?\s*([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// Normalize returns the IANA name of zone. Names are matched ignoring case,
+// with spaces read as underscores; links such as Europe/Kiev are kept rather
+// than replaced by their target. UTC, GMT and Z become UTC, and whole-hour
+// offsets such as UTC+01:00 become the matching Etc/GMT zone, Etc/GMT-1. An
+// empty zone stays empty. Anything else is ErrUnknownZone.
+func Normalize(zone string) (string, error) {
+	parseOnce.Do(parse)
+	trimmed := strings.TrimSpace(zone)
+	switch strings.ToUpper(trimmed) {
+	case "":
+		return "", nil
+	case "UTC", "GMT", "Z":
+		return "UTC", nil
+	}
+	if name, ok := byFolded[strings.ToLower(strings.ReplaceAll(trimmed, " ", "_"))]; ok {
+		return name, nil
+	}
+	if match := utcOffset.FindStringSubmatch(strings.ToUpper(trimmed)); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		if match[3] != "" && match[3] != "00" {
+			return "", fmt.Errorf("%w %q: IANA has no zone for a fractional offset", ErrUnknownZone, zone)
+		}
+		if hours == 0 {
+			return "UTC", nil
+		}
+		// Etc/GMT zones use the POSIX sign: Etc/GMT-1 is an hour ahead of UTC
+		sign := "-"
+		if match[1] == "-" {
+			sign = "+"
+		}
+		if name, ok := byFolded[strings.ToLower("Etc/GMT"+sign+strconv.Itoa(hours))]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownZone, zone)
+}
+
+// LocalTime is the time at an office.
+type LocalTime struct {
+	Time time.Time
+	// UTCOffset is the zone's current offset, formatted as +02:00.
+	UTCOffset string
+	// BusinessHours reports whether Time falls between 09:00 and 17:00,
+	// Monday to Friday. Public holidays are not known.
+	BusinessHours bool
+}
+
+// Local returns now in zone, which must be an IANA name such as Normalize
+// returns.
+func Local(zone string, now time.Time) (LocalTime, error) {
+	if zone == "" {
+		return LocalTime{}, fmt.Errorf("%w %q", ErrUnknownZone, zone)
+	}
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return LocalTime{}, fmt.Errorf("%w %q", ErrUnknownZone, zone)
+	}
+	local := now.In(location)
+	weekday := local.Weekday()
+	return LocalTime{
+		Time:          local,
+		UTCOffset:     local.Format("-07:00"),
+		BusinessHours: weekday != time.Saturday && weekday != time.Sunday && local.Hour() >= 9 && local.Hour() < 17,
+	}, nil
+}
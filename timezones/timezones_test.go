@@ -0,0 +1,83 @@
+package timezones
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestEmbeddedNamesLoad keeps the name list and Go's tzdata in step: every
+// name Normalize returns must be loadable.
+func TestEmbeddedNamesLoad(t *testing.T) {
+	parseOnce.Do(parse)
+	if len(byFolded) < 500 {
+		t.Fatalf("Expected the full IANA name list, got %d names", len(byFolded))
+	}
+	for _, name := range byFolded {
+		if _, err := time.LoadLocation(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for input, expected := range map[string]string{
+		"Europe/Warsaw":           "Europe/Warsaw",
+		"  europe/warsaw ":        "Europe/Warsaw",
+		"AMERICA/PORT OF SPAIN":   "America/Port_of_Spain",
+		"Europe/Kiev":             "Europe/Kiev",
+		"utc":                     "UTC",
+		"GMT":                     "UTC",
+		"UTC+00:00":               "UTC",
+		"UTC+01:00":               "Etc/GMT-1",
+		"UTC-05:00":               "Etc/GMT+5",
+		"GMT+14":                  "Etc/GMT-14",
+		"+0200":                   "Etc/GMT-2",
+		"":                        "",
+		"America/Argentina/Salta": "America/Argentina/Salta",
+	} {
+		normalized, err := Normalize(input)
+		if err != nil || normalized != expected {
+			t.Errorf("Normalize(%q) = %q, %v; expected %q", input, normalized, err, expected)
+		}
+	}
+	for _, unknown := range []string{"Europe/Zeland", "CEST", "UTC+05:30", "UTC+15:00", "Warsaw"} {
+		if normalized, err := Normalize(unknown); !errors.Is(err, ErrUnknownZone) {
+			t.Errorf("Normalize(%q) = %q, %v; expected ErrUnknownZone", unknown, normalized, err)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	// a Monday in Warsaw summer time
+	now := time.Date(2026, time.June, 15, 8, 30, 0, 0, time.UTC)
+	for _, testCase := range []struct {
+		zone          string
+		localTime     string
+		offset        string
+		businessHours bool
+	}{
+		{"Europe/Warsaw", "2026-06-15T10:30:00+02:00", "+02:00", true},
+		{"America/Montevideo", "2026-06-15T05:30:00-03:00", "-03:00", false},
+		{"Pacific/Easter", "2026-06-15T02:30:00-06:00", "-06:00", false},
+		{"Asia/Kolkata", "2026-06-15T14:00:00+05:30", "+05:30", true},
+		{"UTC", "2026-06-15T08:30:00Z", "+00:00", false},
+	} {
+		local, err := Local(testCase.zone, now)
+		if err != nil {
+			t.Fatalf("%s: %v", testCase.zone, err)
+		}
+		if got := local.Time.Format(time.RFC3339); got != testCase.localTime || local.UTCOffset != testCase.offset || local.BusinessHours != testCase.businessHours {
+			t.Errorf("%s: got %s %s %v", testCase.zone, got, local.UTCOffset, local.BusinessHours)
+		}
+	}
+
+	// Saturday noon is outside business hours
+	saturday, _ := Local("Europe/Warsaw", time.Date(2026, time.June, 20, 10, 0, 0, 0, time.UTC))
+	if saturday.BusinessHours {
+		t.Errorf("Expected Saturday to be outside business hours")
+	}
+	if _, err := Local("", now); !errors.Is(err, ErrUnknownZone) {
+		t.Errorf("Expected ErrUnknownZone without a zone, got %v", err)
+	}
+}
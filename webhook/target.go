@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for receivers that are not on the public
+// internet: loopback, private, link-local (which holds the 169.254.169.254
+// cloud metadata service), carrier-grade NAT, multicast and unspecified
+// addresses. Anyone able to subscribe could otherwise make the server post
+// to its own network.
+var ErrForbiddenTarget = errors.New("webhook target is not a public address")
+
+// carrierGradeNAT is 100.64.0.0/10, which also holds some providers'
+// metadata services.
+var carrierGradeNAT = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPublic(address netip.Addr) bool {
+	address = address.Unmap()
+	return address.IsValid() &&
+		!address.IsLoopback() &&
+		!address.IsPrivate() &&
+		!address.IsLinkLocalUnicast() &&
+		!address.IsLinkLocalMulticast() &&
+		!address.IsInterfaceLocalMulticast() &&
+		!address.IsMulticast() &&
+		!address.IsUnspecified() &&
+		!carrierGradeNAT.Contains(address)
+}
+
+// CheckTarget refuses a subscription URL whose host is, or currently
+// resolves to, an address that is not public. A host that does not resolve
+// yet is accepted; NewClient checks every address again when it connects.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := target.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	if address, err := netip.ParseAddr(host); err == nil {
+		if !isPublic(address) {
+			return fmt.Errorf("%w: %s", ErrForbiddenTarget, address)
+		}
+		return nil
+	}
+	lookupContext, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupNetIP(lookupContext, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, address := range addresses {
+		if !isPublic(address) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenTarget, host, address)
+		}
+	}
+	return nil
+}
+
+// refuseNonPublic is a net.Dialer Control function. It runs on the address
+// about to be dialled, after DNS resolution, so a name that is changed to
+// point inside the network after CheckTarget passed is still refused.
+func refuseNonPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, ip)
+	}
+	return nil
+}
+
+// NewClient returns the client a Dispatcher uses when Client is nil. It only
+// connects to public addresses, ignores proxy settings so that check applies
+// to the receiver itself, does not follow redirects, and gives up on a
+// delivery after 10 seconds.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: refuseNonPublic}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,370 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"swift-codes-project/models"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Headers set on every delivery. SignatureHeader carries
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	EventHeader     = "X-Swift-Event"
+	DeliveryHeader  = "X-Swift-Delivery"
+	TimestampHeader = "X-Swift-Timestamp"
+	SignatureHeader = "X-Swift-Signature"
+)
+
+// Event sources whose read positions are kept in the cursor table.
+const (
+	changesSource  = "changes"
+	datasetsSource = "datasets"
+)
+
+// EventTypes lists every event a subscription may ask for.
+var EventTypes = []string{
+	models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted, models.EventImportCompleted,
+}
+
+// Store is the persistence the dispatcher needs; SwiftRepository implements it.
+type Store interface {
+	ListChanges(since int64, limit int) ([]models.SwiftCodeChange, error)
+	LatestChangeSequence() (int64, error)
+	ListDatasetVersions() ([]models.DatasetVersion, error)
+	ListWebhookSubscriptions() ([]models.WebhookSubscription, error)
+	GetWebhookCursor(source string) (int64, bool, error)
+	EnqueueWebhookDeliveries(source string, position int64, deliveries []models.WebhookDelivery) error
+	DueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateWebhookDelivery(delivery models.WebhookDelivery) error
+}
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	Type       string                 `json:"type"`
+	Sequence   int64                  `json:"sequence,omitempty"`
+	OccurredAt time.Time              `json:"occurredAt"`
+	Code       *models.SwiftCode      `json:"code,omitempty"`
+	Dataset    *models.DatasetVersion `json:"dataset,omitempty"`
+}
+
+// Sign returns the SignatureHeader value for body sent at timestamp (Unix seconds).
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature; receivers can use it directly.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte) bool {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signatureHeader))
+}
+
+// Dispatcher turns change log entries and completed imports into outbox rows,
+// then delivers due rows, retrying failures with exponential backoff.
+// Subscriptions are delivered to concurrently, so a slow receiver only delays
+// its own events. Each gets its events in order: while a delivery waits for
+// a retry, the later ones of its subscription wait with it, until it is
+// delivered or given up after MaxAttempts.
+type Dispatcher struct {
+	Store Store
+	// Client sends deliveries; NewClient when nil.
+	Client       *http.Client
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	MaxAttempts  int
+	// Concurrency caps how many subscriptions are delivered to at once; 8
+	// when zero.
+	Concurrency int
+	Now         func() time.Time
+
+	defaultClient sync.Once
+}
+
+func (dispatcher *Dispatcher) client() *http.Client {
+	dispatcher.defaultClient.Do(func() {
+		if dispatcher.Client == nil {
+			dispatcher.Client = NewClient()
+		}
+	})
+	return dispatcher.Client
+}
+
+func (dispatcher *Dispatcher) now() time.Time {
+	if dispatcher.Now != nil {
+		return dispatcher.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// backoff returns the delay before the next attempt after attempts failures.
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	base, limit := dispatcher.BaseBackoff, dispatcher.MaxBackoff
+	if base <= 0 {
+		base = 10 * time.Second
+	}
+	if limit <= 0 {
+		limit = time.Hour
+	}
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// Run dispatches until ctx is done, logging rather than stopping on errors.
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	interval := dispatcher.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := dispatcher.RunOnce(ctx); err != nil {
+			log.Printf("Webhook dispatch failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce enqueues events that happened since the last call and attempts every
+// delivery that is due.
+func (dispatcher *Dispatcher) RunOnce(ctx context.Context) error {
+	subscriptions, err := dispatcher.Store.ListWebhookSubscriptions()
+	if err != nil {
+		return err
+	}
+	if err := dispatcher.enqueueChanges(subscriptions); err != nil {
+		return fmt.Errorf("enqueue changes: %w", err)
+	}
+	if err := dispatcher.enqueueImports(subscriptions); err != nil {
+		return fmt.Errorf("enqueue imports: %w", err)
+	}
+	return dispatcher.deliverDue(ctx, subscriptions)
+}
+
+// enqueueChanges reads the change log from the stored cursor. On first run the
+// cursor starts at the newest change, so history is not replayed.
+func (dispatcher *Dispatcher) enqueueChanges(subscriptions []models.WebhookSubscription) error {
+	since, found, err := dispatcher.Store.GetWebhookCursor(changesSource)
+	if err != nil {
+		return err
+	}
+	if !found {
+		latest, err := dispatcher.Store.LatestChangeSequence()
+		if err != nil {
+			return err
+		}
+		return dispatcher.Store.EnqueueWebhookDeliveries(changesSource, latest, nil)
+	}
+
+	changes, err := dispatcher.Store.ListChanges(since, 500)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+	var deliveries []models.WebhookDelivery
+	for _, change := range changes {
+		code := change.Code
+		event := Event{Type: change.Type, Sequence: change.Sequence, OccurredAt: change.ChangedAt, Code: &code}
+		matched, err := dispatcher.deliveriesFor(subscriptions, event, code.CountryISO2)
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, matched...)
+	}
+	return dispatcher.Store.EnqueueWebhookDeliveries(changesSource, changes[len(changes)-1].Sequence, deliveries)
+}
+
+// enqueueImports sends import-completed for dataset versions newer than the cursor.
+func (dispatcher *Dispatcher) enqueueImports(subscriptions []models.WebhookSubscription) error {
+	since, found, err := dispatcher.Store.GetWebhookCursor(datasetsSource)
+	if err != nil {
+		return err
+	}
+	versions, err := dispatcher.Store.ListDatasetVersions()
+	if err != nil {
+		return err
+	}
+	var latest int64
+	if len(versions) > 0 {
+		latest = versions[len(versions)-1].Version
+	}
+	if !found {
+		return dispatcher.Store.EnqueueWebhookDeliveries(datasetsSource, latest, nil)
+	}
+	if latest <= since {
+		return nil
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, version := range versions {
+		if version.Version <= since {
+			continue
+		}
+		dataset := version
+		event := Event{Type: models.EventImportCompleted, OccurredAt: version.ImportedAt, Dataset: &dataset}
+		matched, err := dispatcher.deliveriesFor(subscriptions, event, "")
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, matched...)
+	}
+	return dispatcher.Store.EnqueueWebhookDeliveries(datasetsSource, latest, deliveries)
+}
+
+// deliveriesFor builds an outbox row for every subscription interested in
+// event. countryISO2 is empty for events that are not about a single code,
+// which reach subscribers regardless of their country filter.
+func (dispatcher *Dispatcher) deliveriesFor(
+	subscriptions []models.WebhookSubscription,
+	event Event,
+	countryISO2 string,
+) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscribesTo(subscription, event.Type) {
+			continue
+		}
+		if countryISO2 != "" && subscription.CountryISO2 != "" && subscription.CountryISO2 != countryISO2 {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return nil, err
+			}
+		}
+		now := dispatcher.now()
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+	return deliveries, nil
+}
+
+func subscribesTo(subscription models.WebhookSubscription, eventType string) bool {
+	for _, subscribed := range subscription.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (dispatcher *Dispatcher) deliverDue(ctx context.Context, subscriptions []models.WebhookSubscription) error {
+	deliveries, err := dispatcher.Store.DueWebhookDeliveries(dispatcher.now(), 100)
+	if err != nil {
+		return err
+	}
+	subscriptionsByID := make(map[int64]models.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		subscriptionsByID[subscription.ID] = subscription
+	}
+	dueBySubscription := make(map[int64][]models.WebhookDelivery)
+	for _, delivery := range deliveries {
+		if _, ok := subscriptionsByID[delivery.SubscriptionID]; !ok {
+			// subscribed after RunOnce listed subscriptions; picked up next run
+			continue
+		}
+		dueBySubscription[delivery.SubscriptionID] = append(dueBySubscription[delivery.SubscriptionID], delivery)
+	}
+
+	concurrency := dispatcher.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for subscriptionID, due := range dueBySubscription {
+		subscription := subscriptionsByID[subscriptionID]
+		group.Go(func() error {
+			for _, delivery := range due {
+				dispatcher.attempt(ctx, subscription, &delivery)
+				if err := dispatcher.Store.UpdateWebhookDelivery(delivery); err != nil {
+					return err
+				}
+				if delivery.Status == models.DeliveryPending {
+					// later events wait for this one's retry
+					break
+				}
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// attempt posts one delivery and records the outcome on it.
+func (dispatcher *Dispatcher) attempt(ctx context.Context, subscription models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	client := dispatcher.client()
+	now := dispatcher.now()
+	delivery.Attempts++
+	delivery.LastStatusCode = 0
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err == nil {
+		timestamp := now.Unix()
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(EventHeader, delivery.EventType)
+		request.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+		request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		request.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+		var response *http.Response
+		if response, err = client.Do(request); err == nil {
+			io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+			response.Body.Close()
+			delivery.LastStatusCode = response.StatusCode
+			if response.StatusCode < 200 || response.StatusCode > 299 {
+				err = fmt.Errorf("receiver responded %s", response.Status)
+			}
+		}
+	}
+
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+	delivery.LastError = err.Error()
+	maxAttempts := dispatcher.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = models.DeliveryFailed
+		return
+	}
+	delivery.NextAttemptAt = now.Add(dispatcher.backoff(delivery.Attempts))
+}
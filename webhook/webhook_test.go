@@ -0,0 +1,287 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"swift-codes-project/db"
+	"swift-codes-project/models"
+	"swift-codes-project/service"
+)
+
+// receivedDelivery is what the test receiver saw for one request.
+type receivedDelivery struct {
+	eventType string
+	validSig  bool
+	event     Event
+}
+
+// TestDispatcherRetriesAndSignsDeliveries subscribes to ZZ creations, makes the
+// receiver fail once, and checks the retry is signed and delivered after the
+// backoff has elapsed.
+func TestDispatcherRetriesAndSignsDeliveries(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+	repository := &service.SwiftRepository{DB: testDatabase}
+
+	const secret = "test-secret"
+	var mutex sync.Mutex
+	var received []receivedDelivery
+	failNext := true
+	receiver := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+		body, _ := io.ReadAll(incomingRequest.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if failNext {
+			failNext = false
+			responseWriter.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var event Event
+		json.Unmarshal(body, &event)
+		received = append(received, receivedDelivery{
+			eventType: incomingRequest.Header.Get(EventHeader),
+			validSig: Verify(secret, incomingRequest.Header.Get(TimestampHeader),
+				incomingRequest.Header.Get(SignatureHeader), body),
+			event: event,
+		})
+	}))
+	defer receiver.Close()
+
+	subscription, subscribeError := repository.CreateWebhookSubscription(models.WebhookSubscription{
+		URL:         receiver.URL,
+		Secret:      secret,
+		EventTypes:  []string{models.ChangeCreated, models.EventImportCompleted},
+		CountryISO2: "ZZ",
+	})
+	if subscribeError != nil {
+		t.Fatalf("Unexpected error subscribing: %v", subscribeError)
+	}
+
+	clock := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	// the receiver listens on loopback, which the default client refuses
+	dispatcher := &Dispatcher{
+		Store:       repository,
+		Client:      receiver.Client(),
+		BaseBackoff: time.Minute,
+		Now:         func() time.Time { return clock },
+	}
+	ctx := context.Background()
+
+	// the first run only positions the cursors
+	if runError := dispatcher.RunOnce(ctx); runError != nil {
+		t.Fatalf("Unexpected error on first run: %v", runError)
+	}
+	repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKXXX", IsHeadquarter: true})
+	repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "AA", SwiftCode: "AABANKXXX", IsHeadquarter: true})
+
+	if runError := dispatcher.RunOnce(ctx); runError != nil {
+		t.Fatalf("Unexpected error on second run: %v", runError)
+	}
+	deliveries, _ := repository.ListWebhookDeliveries(subscription.ID, 10)
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery for country ZZ, got %d", len(deliveries))
+	}
+	if deliveries[0].Status != models.DeliveryPending || deliveries[0].Attempts != 1 || deliveries[0].LastStatusCode != 500 {
+		t.Fatalf("Expected a pending delivery after one failed attempt, got %+v", deliveries[0])
+	}
+	if !deliveries[0].NextAttemptAt.Equal(clock.Add(time.Minute)) {
+		t.Errorf("Expected retry after one minute, got %v", deliveries[0].NextAttemptAt)
+	}
+
+	// not due yet: nothing is sent
+	dispatcher.RunOnce(ctx)
+	if len(received) != 0 {
+		t.Fatalf("Expected no delivery before the backoff elapsed, got %d", len(received))
+	}
+
+	clock = clock.Add(time.Minute)
+	if runError := dispatcher.RunOnce(ctx); runError != nil {
+		t.Fatalf("Unexpected error on retry run: %v", runError)
+	}
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 received delivery, got %d", len(received))
+	}
+	if received[0].eventType != models.ChangeCreated || !received[0].validSig || received[0].event.Code.SwiftCode != "ZZBANKXXX" {
+		t.Errorf("Unexpected delivery: %+v", received[0])
+	}
+	deliveries, _ = repository.ListWebhookDeliveries(subscription.ID, 10)
+	if deliveries[0].Status != models.DeliveryDelivered || deliveries[0].DeliveredAt == nil {
+		t.Errorf("Expected delivery to be marked delivered, got %+v", deliveries[0])
+	}
+
+	repository.RecordDatasetVersion("october", "test", clock)
+	dispatcher.RunOnce(ctx)
+	if len(received) != 2 || received[1].eventType != models.EventImportCompleted || received[1].event.Dataset.Name != "october" {
+		t.Errorf("Expected an import-completed delivery, got %+v", received)
+	}
+}
+
+// TestDispatcherDeliversInOrder fails the first of a subscription's events
+// and expects the later ones to wait for its retry rather than overtake it.
+func TestDispatcherDeliversInOrder(t *testing.T) {
+	testDatabase, initError := db.InitDB("file:" + t.Name() + "?mode=memory&cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+	repository := &service.SwiftRepository{DB: testDatabase}
+
+	var mutex sync.Mutex
+	var received []string
+	failNext := true
+	receiver := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, incomingRequest *http.Request) {
+		var event Event
+		json.NewDecoder(incomingRequest.Body).Decode(&event)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if failNext {
+			failNext = false
+			responseWriter.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, event.Code.SwiftCode)
+	}))
+	defer receiver.Close()
+	repository.CreateWebhookSubscription(models.WebhookSubscription{
+		URL: receiver.URL, Secret: "test-secret", EventTypes: []string{models.ChangeCreated},
+	})
+
+	clock := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	dispatcher := &Dispatcher{
+		Store:       repository,
+		Client:      receiver.Client(),
+		BaseBackoff: time.Minute,
+		Now:         func() time.Time { return clock },
+	}
+	ctx := context.Background()
+	dispatcher.RunOnce(ctx)
+
+	repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANK1XXX", IsHeadquarter: true})
+	repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANK2XXX", IsHeadquarter: true})
+	dispatcher.RunOnce(ctx)
+	repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANK3XXX", IsHeadquarter: true})
+	dispatcher.RunOnce(ctx)
+	if len(received) != 0 {
+		t.Fatalf("Expected later events to wait for the failed one, got %v", received)
+	}
+
+	clock = clock.Add(time.Minute)
+	if runError := dispatcher.RunOnce(ctx); runError != nil {
+		t.Fatalf("Unexpected error on retry run: %v", runError)
+	}
+	expected := []string{"ZZBANK1XXX", "ZZBANK2XXX", "ZZBANK3XXX"}
+	if strings.Join(received, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v in order, got %v", expected, received)
+	}
+}
+
+// TestBackoffDoublesUpToMaximum checks the retry schedule.
+func TestBackoffDoublesUpToMaximum(t *testing.T) {
+	dispatcher := &Dispatcher{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if actual := dispatcher.backoff(i + 1); actual != delay {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, delay, actual)
+		}
+	}
+}
+
+// TestDispatcherDeliversToSubscriptionsConcurrently makes one receiver hang
+// until the other has its delivery, which only happens when they are not
+// attempted one after the other.
+func TestDispatcherDeliversToSubscriptionsConcurrently(t *testing.T) {
+	testDatabase, initError := db.InitDB("file::memory:?cache=shared&_fk=1")
+	if initError != nil {
+		t.Fatalf("Failed to initialize in-memory database: %v", initError)
+	}
+	defer testDatabase.Close()
+	repository := &service.SwiftRepository{DB: testDatabase}
+
+	fastReceived := make(chan struct{})
+	fast := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(fastReceived)
+	}))
+	defer fast.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, _ *http.Request) {
+		select {
+		case <-fastReceived:
+		case <-time.After(2 * time.Second):
+			responseWriter.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	defer slow.Close()
+
+	for _, receiverURL := range []string{slow.URL, fast.URL} {
+		repository.CreateWebhookSubscription(models.WebhookSubscription{
+			URL: receiverURL, Secret: "test-secret", EventTypes: []string{models.ChangeCreated},
+		})
+	}
+	dispatcher := &Dispatcher{Store: repository, Client: &http.Client{Timeout: 5 * time.Second}}
+	ctx := context.Background()
+	dispatcher.RunOnce(ctx)
+	repository.CreateSwiftCode(models.SwiftCode{CountryISO2: "ZZ", SwiftCode: "ZZBANKXXX", IsHeadquarter: true})
+	if runError := dispatcher.RunOnce(ctx); runError != nil {
+		t.Fatalf("Unexpected error delivering: %v", runError)
+	}
+
+	subscriptions, _ := repository.ListWebhookSubscriptions()
+	for _, subscription := range subscriptions {
+		deliveries, _ := repository.ListWebhookDeliveries(subscription.ID, 10)
+		if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDelivered {
+			t.Errorf("Expected %s to have its delivery, got %+v", subscription.URL, deliveries)
+		}
+	}
+}
+
+// TestDefaultClientRefusesNonPublicTargets delivers to a receiver on loopback
+// with the default client and expects the attempt to be refused before any
+// request reaches it.
+func TestDefaultClientRefusesNonPublicTargets(t *testing.T) {
+	reached := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		reached = true
+	}))
+	defer receiver.Close()
+
+	dispatcher := &Dispatcher{}
+	delivery := models.WebhookDelivery{EventType: models.ChangeCreated, Payload: []byte(`{}`)}
+	dispatcher.attempt(context.Background(), models.WebhookSubscription{URL: receiver.URL, Secret: "s"}, &delivery)
+	if reached || !strings.Contains(delivery.LastError, ErrForbiddenTarget.Error()) {
+		t.Errorf("Expected the loopback receiver to be refused, got reached=%v error %q", reached, delivery.LastError)
+	}
+}
+
+// TestCheckTarget refuses internal addresses given literally or by name.
+func TestCheckTarget(t *testing.T) {
+	refused := []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://100.100.100.200/",
+		"http://[::1]/hook",
+		"http://[fd00:ec2::254]/",
+		"http://0.0.0.0/",
+	}
+	for _, target := range refused {
+		if err := CheckTarget(context.Background(), target); !errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("Expected %s to be refused, got %v", target, err)
+		}
+	}
+	if err := CheckTarget(context.Background(), "https://93.184.215.14/hook"); err != nil {
+		t.Errorf("Expected a public address to be accepted, got %v", err)
+	}
+}